@@ -0,0 +1,475 @@
+package gitbase
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/src-d/gitbase/internal/minhash"
+	"github.com/src-d/go-mysql-server/sql"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+type blobSignatureBucketsTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+	index   sql.IndexLookup
+}
+
+// BlobSignatureBucketsSchema is the schema for the blob signature buckets
+// table.
+var BlobSignatureBucketsSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: BlobSignatureBucketsTableName},
+	{Name: "blob_hash", Type: sql.VarChar(40), Nullable: false, Source: BlobSignatureBucketsTableName},
+	{Name: "bucket", Type: sql.Text, Nullable: false, Source: BlobSignatureBucketsTableName},
+}
+
+func newBlobSignatureBucketsTable(pool *RepositoryPool) *blobSignatureBucketsTable {
+	return &blobSignatureBucketsTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*blobSignatureBucketsTable)(nil)
+
+func (blobSignatureBucketsTable) isGitbaseTable() {}
+
+func (t blobSignatureBucketsTable) String() string {
+	return printTable(
+		BlobSignatureBucketsTableName,
+		BlobSignatureBucketsSchema,
+		nil,
+		t.filters,
+		t.index,
+	)
+}
+
+func (blobSignatureBucketsTable) Name() string {
+	return BlobSignatureBucketsTableName
+}
+
+func (blobSignatureBucketsTable) Schema() sql.Schema {
+	return BlobSignatureBucketsSchema
+}
+
+func (t *blobSignatureBucketsTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *blobSignatureBucketsTable) WithIndexLookup(idx sql.IndexLookup) sql.Table {
+	nt := *t
+	nt.index = idx
+	return &nt
+}
+
+func (t *blobSignatureBucketsTable) IndexLookup() sql.IndexLookup { return t.index }
+func (t *blobSignatureBucketsTable) Filters() []sql.Expression    { return t.filters }
+
+func (t *blobSignatureBucketsTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.BlobSignatureBucketsTable")
+	iter, err := rowIterWithSelectors(
+		ctx, BlobSignatureBucketsSchema, BlobSignatureBucketsTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var hashes []string
+			hashes, err = selectors.textValues("blob_hash")
+			if err != nil {
+				return nil, err
+			}
+
+			if t.index != nil {
+				var indexValues sql.IndexValueIter
+				indexValues, err = t.index.Values(p)
+				if err != nil {
+					return nil, err
+				}
+
+				s, err := getSession(ctx)
+				if err != nil {
+					return nil, err
+				}
+
+				return &blobSignatureBucketsIndexIter{
+					index:   indexValues,
+					decoder: newObjectDecoder(s.Pool),
+					hashes:  stringsToHashes(hashes),
+				}, nil
+			}
+
+			return &blobSignatureBucketsRowIter{
+				repo:          repo,
+				hashes:        stringsToHashes(hashes),
+				skipGitErrors: shouldSkipErrors(ctx),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (blobSignatureBucketsTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(BlobSignatureBucketsTableName, BlobSignatureBucketsSchema, filters)
+}
+
+func (blobSignatureBucketsTable) handledColumns() []string { return []string{"blob_hash"} }
+
+// IndexKeyValues implements the sql.IndexableTable interface.
+func (t *blobSignatureBucketsTable) IndexKeyValues(
+	ctx *sql.Context,
+	colNames []string,
+) (sql.PartitionIndexKeyValueIter, error) {
+	return newPartitionedIndexKeyValueIter(
+		ctx,
+		newBlobSignatureBucketsTable(t.pool),
+		colNames,
+		newBlobSignatureBucketsKeyValueIter,
+	)
+}
+
+// blobBuckets returns the locality-sensitive hashing buckets of the
+// signature of a blob, the same ones returned by
+// signature_buckets(content_signature(blob_content)). Binary blobs and
+// blobs bigger than the maximum size of blob contents have no buckets.
+func blobBuckets(blob *object.Blob) ([]string, error) {
+	if blob.Size > int64(blobsMaxSize) {
+		return nil, nil
+	}
+
+	bin, err := isBinary(blob)
+	if err != nil || bin {
+		return nil, err
+	}
+
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return minhash.New(minhash.Shingles(string(content))).Buckets(), nil
+}
+
+type blobSignatureBucketsRowIter struct {
+	repo          *Repository
+	iter          *object.BlobIter
+	hashes        []plumbing.Hash
+	pos           int
+	skipGitErrors bool
+
+	hash    string
+	buckets []string
+}
+
+func (i *blobSignatureBucketsRowIter) Next() (sql.Row, error) {
+	for len(i.buckets) == 0 {
+		blob, err := i.nextBlob()
+		if err != nil {
+			return nil, err
+		}
+
+		if i.buckets, err = blobBuckets(blob); err != nil {
+			if i.skipGitErrors {
+				continue
+			}
+			return nil, err
+		}
+		i.hash = blob.Hash.String()
+	}
+
+	bucket := i.buckets[0]
+	i.buckets = i.buckets[1:]
+	return sql.NewRow(i.repo.ID(), i.hash, bucket), nil
+}
+
+func (i *blobSignatureBucketsRowIter) nextBlob() (*object.Blob, error) {
+	if len(i.hashes) > 0 {
+		for {
+			if i.pos >= len(i.hashes) {
+				return nil, io.EOF
+			}
+
+			blob, err := i.repo.BlobObject(i.hashes[i.pos])
+			i.pos++
+			if err != nil {
+				if err == plumbing.ErrObjectNotFound || i.skipGitErrors {
+					continue
+				}
+				return nil, err
+			}
+
+			return blob, nil
+		}
+	}
+
+	if i.iter == nil {
+		var err error
+		if i.iter, err = i.repo.BlobObjects(); err != nil {
+			if i.skipGitErrors {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+	}
+
+	for {
+		blob, err := i.iter.Next()
+		if err != nil && err != io.EOF && i.skipGitErrors {
+			continue
+		}
+		return blob, err
+	}
+}
+
+func (i *blobSignatureBucketsRowIter) Close() error {
+	if i.iter != nil {
+		i.iter.Close()
+	}
+
+	i.repo.Close()
+
+	return nil
+}
+
+type blobSignatureBucketIndexKey struct {
+	Repository string
+	Packfile   string
+	Offset     int64
+	Hash       string
+	Band       int64
+}
+
+func (k *blobSignatureBucketIndexKey) encode() ([]byte, error) {
+	var buf bytes.Buffer
+	writeString(&buf, k.Repository)
+	if err := writeHash(&buf, k.Packfile); err != nil {
+		return nil, err
+	}
+
+	if err := writeHash(&buf, k.Hash); err != nil {
+		return nil, err
+	}
+
+	writeInt64(&buf, k.Offset)
+	writeInt64(&buf, k.Band)
+	return buf.Bytes(), nil
+}
+
+func (k *blobSignatureBucketIndexKey) decode(data []byte) error {
+	var buf = bytes.NewBuffer(data)
+	var err error
+
+	if k.Repository, err = readString(buf); err != nil {
+		return err
+	}
+
+	if k.Packfile, err = readHash(buf); err != nil {
+		return err
+	}
+
+	if k.Hash, err = readHash(buf); err != nil {
+		return err
+	}
+
+	if k.Offset, err = readInt64(buf); err != nil {
+		return err
+	}
+
+	if k.Band, err = readInt64(buf); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type blobSignatureBucketsKeyValueIter struct {
+	repo    *Repository
+	blobs   *object.BlobIter
+	idx     *repositoryIndex
+	columns []string
+
+	blob     *object.Blob
+	offset   int64
+	packfile plumbing.Hash
+	buckets  []string
+	band     int
+}
+
+func newBlobSignatureBucketsKeyValueIter(
+	_ *RepositoryPool,
+	repo *Repository,
+	columns []string,
+) (sql.IndexKeyValueIter, error) {
+	blobs, err := repo.BlobObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := newRepositoryIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blobSignatureBucketsKeyValueIter{
+		repo:    repo,
+		columns: columns,
+		idx:     idx,
+		blobs:   blobs,
+	}, nil
+}
+
+func (i *blobSignatureBucketsKeyValueIter) Next() ([]interface{}, []byte, error) {
+	for i.band >= len(i.buckets) {
+		var err error
+		i.blob, err = i.blobs.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if i.buckets, err = blobBuckets(i.blob); err != nil {
+			return nil, nil, err
+		}
+		i.band = 0
+
+		if len(i.buckets) == 0 {
+			continue
+		}
+
+		if i.offset, i.packfile, err = i.idx.find(i.blob.Hash); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	key, err := encodeIndexKey(&blobSignatureBucketIndexKey{
+		Repository: i.repo.ID(),
+		Packfile:   i.packfile.String(),
+		Offset:     i.offset,
+		Hash:       i.blob.Hash.String(),
+		Band:       int64(i.band),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	row := sql.NewRow(i.repo.ID(), i.blob.Hash.String(), i.buckets[i.band])
+	i.band++
+
+	values, err := rowIndexValues(row, i.columns, BlobSignatureBucketsSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return values, key, nil
+}
+
+func (i *blobSignatureBucketsKeyValueIter) Close() error {
+	if i.blobs != nil {
+		i.blobs.Close()
+	}
+
+	if i.idx != nil {
+		i.idx.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
+
+type blobSignatureBucketsIndexIter struct {
+	index   sql.IndexValueIter
+	decoder *objectDecoder
+	hashes  []plumbing.Hash
+
+	// signatures are computed once for all the bands of a blob, as the
+	// values of the same blob are usually next to each other.
+	hash    plumbing.Hash
+	buckets []string
+}
+
+func (i *blobSignatureBucketsIndexIter) Next() (sql.Row, error) {
+	for {
+		var err error
+		var data []byte
+		defer closeIndexOnError(&err, i.index)
+
+		data, err = i.index.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var key blobSignatureBucketIndexKey
+		if err = decodeIndexKey(data, &key); err != nil {
+			return nil, err
+		}
+
+		hash := plumbing.NewHash(key.Hash)
+		if len(i.hashes) > 0 && !hashContains(i.hashes, hash) {
+			continue
+		}
+
+		if hash != i.hash {
+			var obj object.Object
+			obj, err = i.decoder.decode(
+				key.Repository,
+				plumbing.NewHash(key.Packfile),
+				key.Offset,
+				hash,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			blob, ok := obj.(*object.Blob)
+			if !ok {
+				err = ErrInvalidObjectType.New(obj, "*object.Blob")
+				return nil, err
+			}
+
+			if i.buckets, err = blobBuckets(blob); err != nil {
+				return nil, err
+			}
+			i.hash = hash
+		}
+
+		if key.Band >= int64(len(i.buckets)) {
+			continue
+		}
+
+		return sql.NewRow(key.Repository, key.Hash, i.buckets[key.Band]), nil
+	}
+}
+
+func (i *blobSignatureBucketsIndexIter) Close() error {
+	if i.decoder != nil {
+		if err := i.decoder.Close(); err != nil {
+			_ = i.index.Close()
+			return err
+		}
+	}
+
+	return i.index.Close()
+}
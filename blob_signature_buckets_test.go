@@ -0,0 +1,148 @@
+package gitbase
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/src-d/gitbase/internal/minhash"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobSignatureBucketsTable(t *testing.T) {
+	require := require.New(t)
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	blobs, err := tableToRows(
+		ctx,
+		newBlobsTable(poolFromCtx(t, ctx)).WithProjection([]string{"blob_content"}),
+	)
+	require.NoError(err)
+
+	var expected []sql.Row
+	for _, row := range blobs {
+		sig := minhash.New(minhash.Shingles(string(row[3].([]byte))))
+		for _, bucket := range sig.Buckets() {
+			expected = append(expected, sql.NewRow(row[0], row[1], bucket))
+		}
+	}
+
+	table := getTable(t, BlobSignatureBucketsTableName, ctx)
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 9*minhash.Bands)
+	require.ElementsMatch(expected, rows)
+
+	schema := table.Schema()
+	for idx, row := range rows {
+		err := schema.CheckRow(row)
+		require.NoError(err, "row %d doesn't conform to schema", idx)
+	}
+}
+
+func TestBlobSignatureBucketsPushdown(t *testing.T) {
+	require := require.New(t)
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := newBlobSignatureBucketsTable(poolFromCtx(t, ctx))
+
+	t2 := table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, BlobSignatureBucketsTableName, "blob_hash", false),
+			expression.NewLiteral("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88", sql.Text),
+		),
+	})
+
+	rows, err := tableToRows(ctx, t2)
+	require.NoError(err)
+	require.Len(rows, minhash.Bands)
+
+	// binary blobs have no buckets
+	t3 := table.WithFilters([]sql.Expression{
+		expression.NewEquals(
+			expression.NewGetFieldWithTable(1, sql.Text, BlobSignatureBucketsTableName, "blob_hash", false),
+			expression.NewLiteral("d5c0f4ab811897cadf03aec358ae60d21f91c50d", sql.Text),
+		),
+	})
+
+	rows, err = tableToRows(ctx, t3)
+	require.NoError(err)
+	require.Len(rows, 0)
+}
+
+func TestBlobSignatureBucketsIndexKeyValueIter(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	table := new(blobSignatureBucketsTable)
+	iter, err := table.IndexKeyValues(ctx, []string{"blob_hash", "bucket"})
+	require.NoError(err)
+
+	_, kvIter, err := iter.Next()
+	require.NoError(err)
+
+	var n int
+	for {
+		values, data, err := kvIter.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+		require.Len(values, 2)
+
+		var key blobSignatureBucketIndexKey
+		require.NoError(decodeIndexKey(data, &key))
+		require.Equal(path, key.Repository)
+		require.Equal(values[0], key.Hash)
+		require.Equal(int64(n%minhash.Bands), key.Band)
+		require.Equal(fmt.Sprintf("%02d-", key.Band), values[1].(string)[:3])
+		n++
+	}
+
+	require.NoError(kvIter.Close())
+	require.NoError(iter.Close())
+	require.Equal(9*minhash.Bands, n)
+}
+
+func TestEncodeBlobSignatureBucketIndexKey(t *testing.T) {
+	require := require.New(t)
+
+	k := blobSignatureBucketIndexKey{
+		Repository: "repo1",
+		Packfile:   "323a4b6b5de684f9966953a043bc800154e5dbfa",
+		Offset:     1591,
+		Hash:       "32858aad3c383ed1ff0a0f9bdf231d54a00c9e88",
+		Band:       7,
+	}
+
+	data, err := k.encode()
+	require.NoError(err)
+
+	var k2 blobSignatureBucketIndexKey
+	require.NoError(k2.decode(data))
+	require.Equal(k, k2)
+}
+
+func TestBlobSignatureBucketsIndex(t *testing.T) {
+	testTableIndex(
+		t,
+		new(blobSignatureBucketsTable),
+		[]sql.Expression{expression.NewEquals(
+			expression.NewGetField(1, sql.Text, "blob_hash", false),
+			expression.NewLiteral("32858aad3c383ed1ff0a0f9bdf231d54a00c9e88", sql.Text),
+		)},
+	)
+}
+
+func TestBlobSignatureBucketsIndexIterClosed(t *testing.T) {
+	testTableIndexIterClosed(t, new(blobSignatureBucketsTable))
+}
+
+func TestBlobSignatureBucketsIterClosed(t *testing.T) {
+	testTableIterClosed(t, new(blobSignatureBucketsTable))
+}
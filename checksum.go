@@ -25,8 +25,6 @@ func (c *checksumable) Checksum() (string, error) {
 
 	var checksums checksums
 	for {
-		hash.Reset()
-
 		repo, err := iter.Next()
 		if err == io.EOF {
 			break
@@ -35,27 +33,14 @@ func (c *checksumable) Checksum() (string, error) {
 			return "", err
 		}
 
-		bytes, err := readChecksum(repo)
-		if err != nil {
-			return "", err
-		}
-
-		if _, err = hash.Write(bytes); err != nil {
-			return "", err
-		}
-
-		bytes, err = readRefs(repo)
+		sum, err := repositoryChecksum(repo)
 		if err != nil {
 			return "", err
 		}
 
-		if _, err = hash.Write(bytes); err != nil {
-			return "", err
-		}
-
 		c := checksum{
 			name: repo.ID(),
-			hash: hash.Sum(nil),
+			hash: sum,
 		}
 
 		checksums = append(checksums, c)
@@ -73,6 +58,48 @@ func (c *checksumable) Checksum() (string, error) {
 	return base64.StdEncoding.EncodeToString(hash.Sum(nil)), nil
 }
 
+// RepositoryChecksum returns a checksum of the packfiles and references of
+// the repository with the given id, which changes when the repository is
+// updated.
+func (p *RepositoryPool) RepositoryChecksum(id string) (string, error) {
+	repo, err := p.GetRepo(id)
+	if err != nil {
+		return "", err
+	}
+	defer repo.Close()
+
+	sum, err := repositoryChecksum(repo)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+func repositoryChecksum(repo *Repository) ([]byte, error) {
+	hash := sha1.New()
+
+	bytes, err := readChecksum(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = hash.Write(bytes); err != nil {
+		return nil, err
+	}
+
+	bytes, err = readRefs(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = hash.Write(bytes); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
 func readChecksum(r *Repository) ([]byte, error) {
 	fs, err := r.FS()
 	if err != nil {
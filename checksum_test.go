@@ -101,3 +101,35 @@ func TestChecksumStable(t *testing.T) {
 		require.Equal(checksumMulti, checksum)
 	}
 }
+
+func TestRepositoryChecksum(t *testing.T) {
+	require := require.New(t)
+
+	defer func() {
+		require.NoError(fixtures.Clean())
+	}()
+
+	lib, pool, err := newMultiPool()
+	require.NoError(err)
+
+	worktrees := fixtures.ByTag("worktree")
+	for i, f := range worktrees {
+		path := f.Worktree().Root()
+		require.NoError(lib.AddPlain(fmt.Sprintf("repo_%d", i), path, nil))
+	}
+
+	first, err := pool.RepositoryChecksum("repo_0")
+	require.NoError(err)
+	require.NotEmpty(first)
+
+	again, err := pool.RepositoryChecksum("repo_0")
+	require.NoError(err)
+	require.Equal(first, again)
+
+	second, err := pool.RepositoryChecksum("repo_1")
+	require.NoError(err)
+	require.NotEqual(first, second)
+
+	_, err = pool.RepositoryChecksum("nope")
+	require.True(ErrPoolRepoNotFound.Is(err))
+}
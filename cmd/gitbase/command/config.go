@@ -0,0 +1,462 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/src-d/gitbase/internal/function"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	yaml "gopkg.in/yaml.v2"
+	"vitess.io/vitess/go/mysql"
+)
+
+// serverConfig is the configuration file of the server command. The keys of
+// the options that are also flags are the long names of the flags. Options
+// that are not given are left untouched.
+type serverConfig struct {
+	// Database options.
+	Name          *string            `yaml:"db"`
+	Directories   []*directoryConfig `yaml:"directories"`
+	Format        *string            `yaml:"format"`
+	Bucket        *int               `yaml:"bucket"`
+	Bare          *bool              `yaml:"bare"`
+	NonBare       *bool              `yaml:"non-bare"`
+	NonRooted     *bool              `yaml:"non-rooted"`
+	IndexDir      *string            `yaml:"index"`
+	CacheSize     *int               `yaml:"cache"`
+	Parallelism   *int               `yaml:"parallelism"`
+	DisableSquash *bool              `yaml:"no-squash"`
+	SkipGitErrors *bool              `yaml:"skip-git-errors"`
+	Verbose       *bool              `yaml:"verbose"`
+	LogLevel      *string            `yaml:"log-level"`
+
+	// Server options.
+	Host            *string `yaml:"host"`
+	Port            *int    `yaml:"port"`
+	User            *string `yaml:"user"`
+	Password        *string `yaml:"password"`
+	UserFile        *string `yaml:"user-file"`
+	ConnTimeout     *int    `yaml:"timeout"`
+	TraceEnabled    *bool   `yaml:"trace"`
+	MetricsEnabled  *bool   `yaml:"metrics"`
+	MetricsPort     *int    `yaml:"metrics-port"`
+	HTTPEnabled     *bool   `yaml:"http"`
+	HTTPPort        *int    `yaml:"http-port"`
+	PostgresEnabled *bool   `yaml:"postgres"`
+	PostgresPort    *int    `yaml:"postgres-port"`
+	ReadOnly        *bool   `yaml:"readonly"`
+	RescanInterval  *string `yaml:"rescan-interval"`
+	TLSCert         *string `yaml:"tls-cert"`
+	TLSKey          *string `yaml:"tls-key"`
+	TLSCA           *string `yaml:"tls-ca"`
+	RequireSecure   *bool   `yaml:"require-secure-transport"`
+	Socket          *string `yaml:"socket"`
+	SocketMode      *string `yaml:"socket-mode"`
+	NoTCP           *bool   `yaml:"no-tcp"`
+
+	Users  []*userConfig  `yaml:"users"`
+	Caches map[string]int `yaml:"caches"`
+	Bblfsh *bblfshConfig  `yaml:"bblfsh"`
+}
+
+// directoryConfig is a directory of the configuration file. The options
+// that are not given take the value of the flags with the same name.
+type directoryConfig struct {
+	Path   string            `yaml:"path"`
+	Format string            `yaml:"format"`
+	Bare   string            `yaml:"bare"`
+	Bucket *int              `yaml:"bucket"`
+	Rooted *bool             `yaml:"rooted"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// userConfig is a user of the configuration file, with the same fields as
+// the entries of the JSON file given with --user-file.
+type userConfig struct {
+	Name        string   `yaml:"name"`
+	Password    string   `yaml:"password"`
+	Permissions []string `yaml:"permissions"`
+}
+
+type bblfshConfig struct {
+	Endpoint    string `yaml:"endpoint"`
+	MaxBlobSize *int   `yaml:"max-blob-size"`
+}
+
+// cacheSizeEnv maps the keys of the caches section of the configuration
+// file to the environment variables with the sizes of the caches.
+var cacheSizeEnv = map[string]string{
+	"language":       "GITBASE_LANGUAGE_CACHE_SIZE",
+	"attributes":     "GITBASE_ATTRIBUTES_CACHE_SIZE",
+	"commit-loc":     "GITBASE_COMMIT_LOC_CACHE_SIZE",
+	"code-owners":    "GITBASE_CODEOWNERS_CACHE_SIZE",
+	"ignore":         "GITBASE_IGNORE_CACHE_SIZE",
+	"patch-id":       "GITBASE_PATCH_ID_CACHE_SIZE",
+	"tree-languages": "GITBASE_TREE_LANGUAGES_CACHE_SIZE",
+	"uast":           "GITBASE_UAST_CACHE_SIZE",
+}
+
+const (
+	bblfshEndpointEnv    = "BBLFSH_ENDPOINT"
+	bblfshMaxBlobSizeEnv = "GITBASE_MAX_UAST_BLOB_SIZE"
+	skipGitErrorsEnv     = "GITBASE_SKIP_GIT_ERRORS"
+)
+
+// yamlTypeReg matches the Go types in the errors of the YAML decoder, which
+// are meaningless for the users writing the configuration file.
+var yamlTypeReg = regexp.MustCompile(` in type \S+`)
+
+// loadConfig reads and validates the configuration file in the given path.
+func loadConfig(path string) (*serverConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %s", err)
+	}
+
+	var cfg serverConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s",
+			path, yamlTypeReg.ReplaceAllString(err.Error(), ""))
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", path, err)
+	}
+
+	return &cfg, nil
+}
+
+func (cfg *serverConfig) validate() error {
+	if cfg.Format != nil && !isLibraryFormat(*cfg.Format) {
+		return fmt.Errorf("format: %q is not valid, it can only be git or siva", *cfg.Format)
+	}
+
+	if cfg.Bare != nil && cfg.NonBare != nil && *cfg.Bare && *cfg.NonBare {
+		return fmt.Errorf("bare and non-bare cannot be both enabled")
+	}
+
+	if cfg.LogLevel != nil {
+		switch *cfg.LogLevel {
+		case "info", "debug", "warning", "error", "fatal":
+		default:
+			return fmt.Errorf("log-level: %q is not valid, it can only be "+
+				"info, debug, warning, error or fatal", *cfg.LogLevel)
+		}
+	}
+
+	positive := []struct {
+		name  string
+		value *int
+	}{
+		{"bucket", cfg.Bucket},
+		{"cache", cfg.CacheSize},
+		{"parallelism", cfg.Parallelism},
+		{"port", cfg.Port},
+		{"timeout", cfg.ConnTimeout},
+		{"metrics-port", cfg.MetricsPort},
+		{"http-port", cfg.HTTPPort},
+		{"postgres-port", cfg.PostgresPort},
+	}
+	for _, o := range positive {
+		if o.value != nil && *o.value < 0 {
+			return fmt.Errorf("%s: %d is not valid, it cannot be negative", o.name, *o.value)
+		}
+	}
+
+	if cfg.RescanInterval != nil {
+		d, err := time.ParseDuration(*cfg.RescanInterval)
+		if err != nil || d < 0 {
+			return fmt.Errorf("rescan-interval: %q is not a valid duration", *cfg.RescanInterval)
+		}
+	}
+
+	for i, d := range cfg.Directories {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("directories[%d]: %s", i, err)
+		}
+	}
+
+	if len(cfg.Users) > 0 {
+		if cfg.UserFile != nil && *cfg.UserFile != "" {
+			return fmt.Errorf("users and user-file cannot be used together")
+		}
+
+		if cfg.ReadOnly != nil && *cfg.ReadOnly {
+			return fmt.Errorf("users and readonly cannot be used together")
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, u := range cfg.Users {
+		if u == nil || u.Name == "" {
+			return fmt.Errorf("users[%d]: name is required", i)
+		}
+
+		if names[u.Name] {
+			return fmt.Errorf("users[%d]: user %q is duplicated", i, u.Name)
+		}
+		names[u.Name] = true
+
+		for _, p := range u.Permissions {
+			if _, ok := auth.PermissionNames[strings.ToLower(p)]; !ok {
+				return fmt.Errorf("users[%d]: permission %q is not valid, it can only be read or write", i, p)
+			}
+		}
+	}
+
+	for name, size := range cfg.Caches {
+		if _, ok := cacheSizeEnv[name]; !ok {
+			return fmt.Errorf("caches: unknown cache %q, it can only be one of %s",
+				name, strings.Join(cacheNames(), ", "))
+		}
+
+		if size <= 0 {
+			return fmt.Errorf("caches: size of %s cache must be positive", name)
+		}
+	}
+
+	return nil
+}
+
+func (d *directoryConfig) validate() error {
+	if d == nil || d.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+
+	if d.Format != "" && !isLibraryFormat(d.Format) {
+		return fmt.Errorf("format: %q is not valid, it can only be git or siva", d.Format)
+	}
+
+	switch d.Bare {
+	case "", "true", "false", "auto":
+	default:
+		return fmt.Errorf("bare: %q is not valid, it can only be true, false or auto", d.Bare)
+	}
+
+	if d.Bucket != nil && *d.Bucket < 0 {
+		return fmt.Errorf("bucket: %d is not valid, it cannot be negative", *d.Bucket)
+	}
+
+	return nil
+}
+
+func isLibraryFormat(format string) bool {
+	return format == "git" || format == "siva"
+}
+
+func cacheNames() []string {
+	var names []string
+	for name := range cacheSizeEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyConfig sets the options of the configuration file that were not given
+// as flags or environment variables. Options given in the command line or
+// the environment always take precedence over the configuration file.
+func (c *Server) applyConfig(cfg *serverConfig) error {
+	setString(c.isSet, "db", &c.Name, cfg.Name)
+	setString(c.isSet, "format", &c.Format, cfg.Format)
+	setInt(c.isSet, "bucket", &c.Bucket, cfg.Bucket)
+	setBool(c.isSet, "bare", &c.Bare, cfg.Bare)
+	setBool(c.isSet, "non-bare", &c.NonBare, cfg.NonBare)
+	setBool(c.isSet, "non-rooted", &c.NonRooted, cfg.NonRooted)
+	setString(c.isSet, "index", &c.IndexDir, cfg.IndexDir)
+	if cfg.CacheSize != nil && !c.isSet("cache") {
+		c.CacheSize = cache.FileSize(*cfg.CacheSize)
+	}
+	if cfg.Parallelism != nil && !c.isSet("parallelism") {
+		c.Parallelism = uint(*cfg.Parallelism)
+	}
+	setBool(c.isSet, "no-squash", &c.DisableSquash, cfg.DisableSquash)
+	setBool(c.isSet, "v", &c.Verbose, cfg.Verbose)
+	setString(c.isSet, "log-level", &c.LogLevel, cfg.LogLevel)
+	if _, ok := os.LookupEnv(skipGitErrorsEnv); !ok && cfg.SkipGitErrors != nil {
+		c.SkipGitErrors = *cfg.SkipGitErrors
+	}
+
+	setString(c.isSet, "host", &c.Host, cfg.Host)
+	setInt(c.isSet, "port", &c.Port, cfg.Port)
+	setString(c.isSet, "user", &c.User, cfg.User)
+	setString(c.isSet, "password", &c.Password, cfg.Password)
+	setString(c.isSet, "user-file", &c.UserFile, cfg.UserFile)
+	setInt(c.isSet, "timeout", &c.ConnTimeout, cfg.ConnTimeout)
+	setBool(c.isSet, "trace", &c.TraceEnabled, cfg.TraceEnabled)
+	setBool(c.isSet, "metrics", &c.MetricsEnabled, cfg.MetricsEnabled)
+	setInt(c.isSet, "metrics-port", &c.MetricsPort, cfg.MetricsPort)
+	setBool(c.isSet, "http", &c.HTTPEnabled, cfg.HTTPEnabled)
+	setInt(c.isSet, "http-port", &c.HTTPPort, cfg.HTTPPort)
+	setBool(c.isSet, "postgres", &c.PostgresEnabled, cfg.PostgresEnabled)
+	setInt(c.isSet, "postgres-port", &c.PostgresPort, cfg.PostgresPort)
+	setBool(c.isSet, "readonly", &c.ReadOnly, cfg.ReadOnly)
+	setString(c.isSet, "tls-cert", &c.TLSCert, cfg.TLSCert)
+	setString(c.isSet, "tls-key", &c.TLSKey, cfg.TLSKey)
+	setString(c.isSet, "tls-ca", &c.TLSCA, cfg.TLSCA)
+	setBool(c.isSet, "require-secure-transport", &c.RequireSecure, cfg.RequireSecure)
+	setString(c.isSet, "socket", &c.Socket, cfg.Socket)
+	setString(c.isSet, "socket-mode", &c.SocketMode, cfg.SocketMode)
+	setBool(c.isSet, "no-tcp", &c.NoTCP, cfg.NoTCP)
+
+	if cfg.RescanInterval != nil && !c.isSet("rescan-interval") {
+		c.RescanInterval, _ = time.ParseDuration(*cfg.RescanInterval)
+	}
+
+	if !c.isSet("directories") {
+		c.configDirectories = cfg.Directories
+	}
+
+	if !c.isSet("user-file") && !c.isSet("user") && !c.isSet("password") {
+		c.users = cfg.Users
+	}
+
+	env := make(map[string]string)
+	for name, size := range cfg.Caches {
+		env[cacheSizeEnv[name]] = strconv.Itoa(size)
+	}
+
+	if cfg.Bblfsh != nil {
+		if cfg.Bblfsh.Endpoint != "" {
+			env[bblfshEndpointEnv] = cfg.Bblfsh.Endpoint
+		}
+
+		if cfg.Bblfsh.MaxBlobSize != nil {
+			env[bblfshMaxBlobSizeEnv] = strconv.Itoa(*cfg.Bblfsh.MaxBlobSize)
+		}
+	}
+
+	for key, value := range env {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+
+	return function.ResetCaches()
+}
+
+// isSet returns whether the option with the given long name was given in
+// the command line or in its environment variable.
+func (c *Server) isSet(name string) bool {
+	if c.command == nil || name == "" {
+		return false
+	}
+
+	var option *flags.Option
+	if len(name) == 1 {
+		option = c.command.FindOptionByShortName(rune(name[0]))
+	} else {
+		option = c.command.FindOptionByLongName(name)
+	}
+
+	if option == nil {
+		return false
+	}
+
+	if option.EnvDefaultKey != "" {
+		if _, ok := os.LookupEnv(option.EnvDefaultKey); ok {
+			return true
+		}
+	}
+
+	return option.IsSet() && !option.IsSetDefault()
+}
+
+func setString(isSet func(string) bool, name string, dst *string, v *string) {
+	if v != nil && !isSet(name) {
+		*dst = *v
+	}
+}
+
+func setInt(isSet func(string) bool, name string, dst *int, v *int) {
+	if v != nil && !isSet(name) {
+		*dst = *v
+	}
+}
+
+func setBool(isSet func(string) bool, name string, dst *bool, v *bool) {
+	if v != nil && !isSet(name) {
+		*dst = *v
+	}
+}
+
+// nativePasswordReg matches the passwords already hashed with
+// mysql_native_password, which are kept as they are.
+var nativePasswordReg = regexp.MustCompile(`^\*[0-9A-F]{40}$`)
+
+// configUsers is the authentication of the users of the configuration
+// file. Passwords are hashed with mysql_native_password as auth.Native
+// does with the users of a user file, but they are never written to disk.
+type configUsers map[string]configUser
+
+type configUser struct {
+	password    string
+	permissions auth.Permission
+}
+
+var _ auth.Auth = configUsers(nil)
+
+// configAuth returns the authentication of the users of the configuration
+// file, which are already validated.
+func configAuth(users []*userConfig) auth.Auth {
+	result := make(configUsers, len(users))
+	for _, u := range users {
+		password := u.Password
+		if !nativePasswordReg.MatchString(password) {
+			password = auth.NativePassword(password)
+		}
+
+		permissions := auth.DefaultPermissions
+		if len(u.Permissions) > 0 {
+			permissions = 0
+			for _, p := range u.Permissions {
+				permissions |= auth.PermissionNames[strings.ToLower(p)]
+			}
+		}
+
+		result[u.Name] = configUser{password, permissions}
+	}
+
+	return result
+}
+
+// Mysql implements the auth.Auth interface.
+func (u configUsers) Mysql() mysql.AuthServer {
+	server := mysql.NewAuthServerStatic()
+	for name, user := range u {
+		server.Entries[name] = []*mysql.AuthServerStaticEntry{{
+			MysqlNativePassword: user.password,
+			Password:            user.password,
+		}}
+	}
+
+	return server
+}
+
+// Allowed implements the auth.Auth interface.
+func (u configUsers) Allowed(ctx *sql.Context, permission auth.Permission) error {
+	user, ok := u[ctx.Client().User]
+	if !ok {
+		return auth.ErrNotAuthorized.Wrap(auth.ErrNoPermission.New(permission))
+	}
+
+	if missing := ^user.permissions & permission; missing != 0 {
+		return auth.ErrNotAuthorized.Wrap(auth.ErrNoPermission.New(missing))
+	}
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/mysql"
+)
+
+const testConfig = `
+db: repos
+host: 0.0.0.0
+port: 3307
+http: true
+http-port: 9090
+log-level: debug
+rescan-interval: 30s
+socket-mode: 0600
+directories:
+  - path: /repos/siva
+    format: siva
+    bucket: 0
+    rooted: false
+    labels:
+      team: core
+  - path: /repos/git
+    bare: true
+users:
+  - name: admin
+    password: secret
+    permissions: [read, write]
+  - name: reader
+caches:
+  language: 50
+bblfsh:
+  endpoint: bblfsh:9432
+  max-blob-size: 1024
+`
+
+func writeConfig(t *testing.T, content string) (string, func()) {
+	f, err := ioutil.TempFile("", "gitbase-config")
+	require.NoError(t, err)
+
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	return f.Name(), func() { os.Remove(f.Name()) }
+}
+
+func TestLoadConfig(t *testing.T) {
+	require := require.New(t)
+
+	path, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	cfg, err := loadConfig(path)
+	require.NoError(err)
+
+	require.Equal("repos", *cfg.Name)
+	require.Equal(3307, *cfg.Port)
+	require.True(*cfg.HTTPEnabled)
+	require.Nil(cfg.Password)
+	require.Equal("30s", *cfg.RescanInterval)
+	require.Equal("0600", *cfg.SocketMode)
+
+	bucket, rooted := 0, false
+	require.Equal([]*directoryConfig{
+		{
+			Path:   "/repos/siva",
+			Format: "siva",
+			Bucket: &bucket,
+			Rooted: &rooted,
+			Labels: map[string]string{"team": "core"},
+		},
+		{Path: "/repos/git", Bare: "true"},
+	}, cfg.Directories)
+
+	require.Equal([]*userConfig{
+		{Name: "admin", Password: "secret", Permissions: []string{"read", "write"}},
+		{Name: "reader"},
+	}, cfg.Users)
+
+	require.Equal(map[string]int{"language": 50}, cfg.Caches)
+	require.Equal("bblfsh:9432", cfg.Bblfsh.Endpoint)
+	require.Equal(1024, *cfg.Bblfsh.MaxBlobSize)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"unknown option", "prot: 3306", "line 1: field prot not found"},
+		{"invalid type", "port: foo", "cannot unmarshal"},
+		{"invalid format", "format: svn", `format: "svn" is not valid`},
+		{"invalid log level", "log-level: trace", `log-level: "trace" is not valid`},
+		{"negative port", "port: -1", "port: -1 is not valid"},
+		{"invalid rescan interval", "rescan-interval: often", `rescan-interval: "often" is not a valid duration`},
+		{"bare and non bare", "bare: true\nnon-bare: true", "bare and non-bare"},
+		{"directory without path", "directories:\n  - format: git", "directories[0]: path is required"},
+		{"invalid directory format", "directories:\n  - path: foo\n    format: svn", `directories[0]: format: "svn" is not valid`},
+		{"invalid directory bare", "directories:\n  - path: foo\n    bare: maybe", `directories[0]: bare: "maybe" is not valid`},
+		{"user without name", "users:\n  - password: foo", "users[0]: name is required"},
+		{"duplicated user", "users:\n  - name: foo\n  - name: foo", `users[1]: user "foo" is duplicated`},
+		{"invalid permission", "users:\n  - name: foo\n    permissions: [admin]", `users[0]: permission "admin" is not valid`},
+		{"users and user file", "user-file: users.json\nusers:\n  - name: foo", "users and user-file"},
+		{"users and readonly", "readonly: true\nusers:\n  - name: foo", "users and readonly"},
+		{"unknown cache", "caches:\n  objects: 10", `caches: unknown cache "objects"`},
+		{"invalid cache size", "caches:\n  uast: 0", "size of uast cache must be positive"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			path, cleanup := writeConfig(t, tt.content)
+			defer cleanup()
+
+			_, err := loadConfig(path)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+			require.NotContains(t, err.Error(), "serverConfig")
+		})
+	}
+
+	_, err := loadConfig("/does/not/exist.yml")
+	require.Error(t, err)
+}
+
+func TestApplyConfig(t *testing.T) {
+	require := require.New(t)
+
+	envs := []string{
+		"GITBASE_HTTP_PORT",
+		"GITBASE_LANGUAGE_CACHE_SIZE",
+		"BBLFSH_ENDPOINT",
+		"GITBASE_MAX_UAST_BLOB_SIZE",
+	}
+	defer func() {
+		for _, env := range envs {
+			os.Unsetenv(env)
+		}
+	}()
+
+	require.NoError(os.Setenv("GITBASE_HTTP_PORT", "8181"))
+	require.NoError(os.Setenv("BBLFSH_ENDPOINT", "localhost:9432"))
+
+	srv := new(Server)
+	parser := flags.NewParser(srv, flags.Default)
+	parser.CommandHandler = func(flags.Commander, []string) error { return nil }
+	srv.SetCommand(parser.Command)
+
+	_, err := parser.ParseArgs([]string{"--port", "3308", "--log-level", "info"})
+	require.NoError(err)
+
+	path, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	cfg, err := loadConfig(path)
+	require.NoError(err)
+	require.NoError(srv.applyConfig(cfg))
+
+	// given in the command line
+	require.Equal(3308, srv.Port)
+	require.Equal("info", srv.LogLevel)
+	// given in the environment
+	require.Equal(8181, srv.HTTPPort)
+	require.Equal("localhost:9432", os.Getenv("BBLFSH_ENDPOINT"))
+	// given in the config file
+	require.Equal("repos", srv.Name)
+	require.Equal("0.0.0.0", srv.Host)
+	require.True(srv.HTTPEnabled)
+	require.Equal(30*time.Second, srv.RescanInterval)
+	require.Len(srv.configDirectories, 2)
+	require.Len(srv.users, 2)
+	require.Equal("50", os.Getenv("GITBASE_LANGUAGE_CACHE_SIZE"))
+	require.Equal("1024", os.Getenv("GITBASE_MAX_UAST_BLOB_SIZE"))
+	// defaults
+	require.Equal("root", srv.User)
+	require.Equal(2112, srv.MetricsPort)
+}
+
+func TestConfigAuth(t *testing.T) {
+	require := require.New(t)
+
+	hashed := auth.NativePassword("hashed")
+	a := configAuth([]*userConfig{
+		{Name: "admin", Password: "secret", Permissions: []string{"read", "Write"}},
+		{Name: "reader"},
+		{Name: "hashed", Password: hashed},
+	})
+
+	server, ok := a.Mysql().(*mysql.AuthServerStatic)
+	require.True(ok)
+	require.Len(server.Entries, 3)
+	require.Equal(auth.NativePassword("secret"), server.Entries["admin"][0].MysqlNativePassword)
+	require.Equal("", server.Entries["reader"][0].MysqlNativePassword)
+	require.Equal(hashed, server.Entries["hashed"][0].MysqlNativePassword)
+
+	salt, err := mysql.NewSalt()
+	require.NoError(err)
+	_, err = a.Mysql().ValidateHash(
+		salt, "admin", mysql.ScramblePassword(salt, []byte("secret")), nil,
+	)
+	require.NoError(err)
+
+	newCtx := func(user string) *sql.Context {
+		return sql.NewContext(context.TODO(), sql.WithSession(
+			sql.NewSession("localhost", "127.0.0.1", user, 1),
+		))
+	}
+
+	require.NoError(a.Allowed(newCtx("admin"), auth.ReadPerm|auth.WritePerm))
+	require.NoError(a.Allowed(newCtx("reader"), auth.ReadPerm))
+	require.True(auth.ErrNotAuthorized.Is(a.Allowed(newCtx("reader"), auth.WritePerm)))
+	require.True(auth.ErrNotAuthorized.Is(a.Allowed(newCtx("unknown"), auth.ReadPerm)))
+}
+
+func TestConfigDirectoriesLabels(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	bucket := 0
+	db := &Database{
+		Name:     "gitbase",
+		Format:   "git",
+		Bucket:   2,
+		IndexDir: filepath.Join(tmpDir, "index"),
+		configDirectories: []*directoryConfig{
+			{
+				Path:   "../../../_testdata",
+				Format: "siva",
+				Bucket: &bucket,
+				Labels: map[string]string{"team": "core"},
+			},
+		},
+		userAuth: new(auth.None),
+	}
+	require.NoError(db.buildDatabase())
+
+	ctx := sql.NewContext(context.Background(), sql.WithSession(db.newSession()))
+	_, iter, err := db.engine.Query(ctx, `
+		SELECT repository_label(repository_id, 'team') AS team, COUNT(*)
+		FROM repositories
+		GROUP BY team`)
+	require.NoError(err)
+
+	rows, err := sql.RowIterToRows(iter)
+	require.NoError(err)
+	require.Equal([]sql.Row{{"core", int64(5)}}, rows)
+}
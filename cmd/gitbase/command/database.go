@@ -0,0 +1,331 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/gitbase/internal/function"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-borges"
+	"github.com/src-d/go-borges/libraries"
+	"github.com/src-d/go-borges/oldsiva"
+	"github.com/src-d/go-borges/plain"
+	"github.com/src-d/go-borges/siva"
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/index/pilosa"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+)
+
+// Database holds the options shared by the commands of gitbase cli tool
+// that need a database engine with the repositories of some directories.
+type Database struct {
+	engine   *sqle.Engine
+	pool     *gitbase.RepositoryPool
+	userAuth auth.Auth
+	// optionalIndex is set by the commands that run a few queries and exit,
+	// which can work without indexes when the index directory can't be
+	// created, as with the default one of a regular user.
+	optionalIndex bool
+
+	rootLibrary  *libraries.Libraries
+	plainLibrary *plain.Library
+	sharedCache  cache.Object
+
+	// configDirectories are the directories of the configuration file,
+	// loaded after the ones given with --directories.
+	configDirectories []*directoryConfig
+
+	// sources are the directories and the libraries or locations their
+	// repositories are loaded with, and repositories holds the directory
+	// of each repository found in the last scan.
+	sources        []repositorySource
+	repositoriesMu sync.RWMutex
+	repositories   map[string]string
+
+	Name          string         `long:"db" default:"gitbase" description:"Database name"`
+	Version       string         // Version of the application.
+	Directories   []string       `short:"d" long:"directories" description:"Path where standard git repositories are located, multiple directories can be defined."`
+	Format        string         `long:"format" default:"git" choice:"git" choice:"siva" description:"Library format"`
+	Bucket        int            `long:"bucket" default:"2" description:"Bucketing level to use with siva libraries"`
+	Bare          bool           `long:"bare" description:"Sets the library to use bare git repositories, used only with git format libraries"`
+	NonBare       bool           `long:"non-bare" description:"Sets the library to use non bare git repositories, used only with git format libraries"`
+	NonRooted     bool           `long:"non-rooted" description:"Disables treating siva files as rooted repositories"`
+	IndexDir      string         `short:"i" long:"index" default:"/var/lib/gitbase/index" description:"Directory where the gitbase indexes information will be persisted." env:"GITBASE_INDEX_DIR"`
+	CacheSize     cache.FileSize `long:"cache" default:"512" description:"Object cache size in megabytes" env:"GITBASE_CACHESIZE_MB"`
+	Parallelism   uint           `long:"parallelism" description:"Maximum number of parallel threads per table. By default, it's the number of CPU cores. 0 means default, 1 means disabled."`
+	DisableSquash bool           `long:"no-squash" description:"Disables the table squashing."`
+	SkipGitErrors bool           // SkipGitErrors disables failing when Git errors are found.
+	Verbose       bool           `short:"v" description:"Activates the verbose mode (equivalent to debug logging level), overwriting any passed logging level"`
+	LogLevel      string         `long:"log-level" env:"GITBASE_LOG_LEVEL" choice:"info" choice:"debug" choice:"warning" choice:"error" choice:"fatal" default:"info" description:"logging level; ignored if using -v verbose flag"`
+}
+
+// setupLogging sets the logging level given by the options and checks the
+// options that can't be used together.
+func (c *Database) setupLogging() error {
+	if c.Verbose {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+
+	if c.Bare && c.NonBare {
+		return fmt.Errorf("cannot use both --bare and --non-bare")
+	}
+
+	// info is the default log level
+	if c.LogLevel != "info" {
+		if c.Verbose {
+			logrus.Infof(
+				"ignoring passed '%s' log-level, using requesed '-v' verbose flag instead",
+				c.LogLevel,
+			)
+		} else {
+			level, err := logrus.ParseLevel(c.LogLevel)
+			if err != nil {
+				return fmt.Errorf("cannot parse log level: %s", err.Error())
+			}
+			logrus.SetLevel(level)
+		}
+	}
+
+	return nil
+}
+
+// sessionOptions returns the options of the sessions created for the
+// database.
+func (c *Database) sessionOptions() []gitbase.SessionOption {
+	return []gitbase.SessionOption{gitbase.WithSkipGitErrors(c.SkipGitErrors)}
+}
+
+// newSession creates a new gitbase session for the repositories of the
+// database.
+func (c *Database) newSession() *gitbase.Session {
+	return gitbase.NewSession(c.pool, c.sessionOptions()...)
+}
+
+func (c *Database) buildDatabase() error {
+	if c.engine == nil {
+		c.engine = NewDatabaseEngine(
+			c.userAuth,
+			c.Version,
+			int(c.Parallelism),
+			!c.DisableSquash,
+		)
+	}
+
+	c.sharedCache = cache.NewObjectLRU(c.CacheSize * cache.MiByte)
+
+	c.rootLibrary = libraries.New(nil)
+	c.pool = gitbase.NewRepositoryPool(c.sharedCache, c.rootLibrary)
+
+	if err := c.addDirectories(); err != nil {
+		return err
+	}
+
+	c.engine.AddDatabase(gitbase.NewDatabase(c.Name, c.pool))
+	c.engine.AddDatabase(sql.NewInformationSchemaDatabase(c.engine.Catalog))
+	c.engine.Catalog.SetCurrentDatabase(c.Name)
+	logrus.WithField("db", c.Name).Debug("registered database to catalog")
+
+	c.engine.Catalog.MustRegister(function.Functions...)
+	logrus.Debug("registered all available functions in catalog")
+
+	if err := c.registerDrivers(); err != nil {
+		return err
+	}
+
+	if !c.DisableSquash {
+		logrus.Info("squash tables rule is enabled")
+	} else {
+		logrus.Warn("squash tables rule is disabled")
+	}
+
+	return c.engine.Init()
+}
+
+func (c *Database) registerDrivers() error {
+	if err := os.MkdirAll(c.IndexDir, 0755); err != nil {
+		if !c.optionalIndex {
+			return err
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"dir": c.IndexDir,
+			"err": err,
+		}).Warn("unable to create index storage, indexes are disabled")
+		return nil
+	}
+
+	logrus.Debug("created index storage")
+
+	c.engine.Catalog.RegisterIndexDriver(
+		pilosa.NewDriver(filepath.Join(c.IndexDir, pilosa.DriverID)),
+	)
+	logrus.Debug("registered pilosa index driver")
+
+	return nil
+}
+
+func (c *Database) addDirectories() error {
+	if len(c.Directories) == 0 && len(c.configDirectories) == 0 {
+		logrus.Error("at least one folder should be provided.")
+	}
+
+	defaultBare := bareAuto
+	switch {
+	case c.Bare:
+		defaultBare = bareOn
+	case c.NonBare:
+		defaultBare = bareOff
+	}
+
+	for _, d := range c.Directories {
+		dir := directory{
+			Path:   d,
+			Format: c.Format,
+			Bare:   defaultBare,
+			Bucket: c.Bucket,
+			Rooted: !c.NonRooted,
+		}
+
+		dir, err := parseDirectory(dir)
+		if err != nil {
+			return err
+		}
+
+		err = c.addDirectory(dir)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, d := range c.configDirectories {
+		dir := directory{
+			Path:   d.Path,
+			Format: c.Format,
+			Bare:   defaultBare,
+			Bucket: c.Bucket,
+			Rooted: !c.NonRooted,
+			Labels: d.Labels,
+		}
+
+		if d.Format != "" {
+			dir.Format = d.Format
+		}
+
+		switch d.Bare {
+		case "true":
+			dir.Bare = bareOn
+		case "false":
+			dir.Bare = bareOff
+		case "auto":
+			dir.Bare = bareAuto
+		}
+
+		if d.Bucket != nil {
+			dir.Bucket = *d.Bucket
+		}
+
+		if d.Rooted != nil {
+			dir.Rooted = *d.Rooted
+		}
+
+		if err := c.addDirectory(dir); err != nil {
+			return err
+		}
+	}
+
+	repos, err := c.scanRepositories()
+	if err != nil {
+		return err
+	}
+
+	for id := range repos {
+		logrus.WithField("id", id).Debug("repository added")
+	}
+
+	c.repositoriesMu.Lock()
+	c.repositories = repos
+	c.repositoriesMu.Unlock()
+	RepositoriesGauge.Set(float64(len(repos)))
+	return nil
+}
+
+func (c *Database) addDirectory(d directory) error {
+	if d.Format == "siva" {
+		var lib borges.Library
+		var err error
+
+		if d.Rooted {
+			sivaOpts := &siva.LibraryOptions{
+				Transactional: true,
+				RootedRepo:    d.Rooted,
+				Cache:         c.sharedCache,
+				Bucket:        d.Bucket,
+				Performance:   true,
+				RegistryCache: 100000,
+			}
+
+			lib, err = siva.NewLibrary(d.Path, osfs.New(d.Path), sivaOpts)
+			if err != nil {
+				return err
+			}
+		} else {
+			sivaOpts := &oldsiva.LibraryOptions{
+				Cache:         c.sharedCache,
+				Bucket:        d.Bucket,
+				RegistryCache: 100000,
+			}
+
+			lib, err = oldsiva.NewLibrary(d.Path, osfs.New(d.Path), sivaOpts)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = c.rootLibrary.Add(lib)
+		if err != nil {
+			return err
+		}
+
+		c.sources = append(c.sources, repositorySource{d, lib})
+		return nil
+	}
+
+	bare, err := discoverBare(d)
+	if err != nil {
+		return err
+	}
+
+	plainOpts := &plain.LocationOptions{
+		Cache:       c.sharedCache,
+		Performance: true,
+		Bare:        bare,
+	}
+
+	if c.plainLibrary == nil {
+		c.plainLibrary = plain.NewLibrary(borges.LibraryID("plain"))
+		err := c.rootLibrary.Add(c.plainLibrary)
+		if err != nil {
+			return err
+		}
+	}
+
+	loc, err := plain.NewLocation(
+		borges.LocationID(d.Path),
+		osfs.New(d.Path),
+		plainOpts)
+	if err != nil {
+		return err
+	}
+
+	c.plainLibrary.AddLocation(loc)
+	c.sources = append(c.sources, repositorySource{d, loc})
+
+	return nil
+}
@@ -0,0 +1,440 @@
+package command
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/src-d/gitbase"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+const (
+	ExportDescription = "Exports a table or the results of a query to files"
+	ExportHelp        = ExportDescription + "\n\n" +
+		"Tables are exported to a directory with the name of the table inside\n" +
+		"the output directory, with a file for each repository. The files\n" +
+		"are written in parallel and, when the export is run again, the\n" +
+		"files of the repositories that did not change are not written\n" +
+		"again. The results of a query are exported to a single file named\n" +
+		"query in the output directory. The format of the files is given\n" +
+		"with --output-format, because --format is the format of the\n" +
+		"repositories to load. The export stops at the first error."
+
+	// checksumsFile is the file of an export directory with the checksums
+	// of the repositories of the exported files. It starts with an
+	// underscore so it's ignored by the tools that read the directory.
+	checksumsFile = "_checksums.json"
+
+	// queryFile is the name of the file with the results of a query.
+	queryFile = "query"
+)
+
+// Export represents the `export` command of gitbase cli tool.
+type Export struct {
+	Database
+
+	Query        string `long:"query" description:"Query whose results are exported"`
+	Table        string `long:"table" description:"Table to export, with a file for each repository"`
+	OutputFormat string `long:"output-format" default:"parquet" choice:"parquet" choice:"csv" choice:"jsonl" description:"Format of the exported files"`
+	Out          string `long:"out" required:"yes" description:"Directory where the files are written"`
+}
+
+// Execute exports a table or the results of a query, it honors the
+// go-flags.Commander interface.
+func (c *Export) Execute(args []string) error {
+	if err := c.setupLogging(); err != nil {
+		return err
+	}
+
+	if (c.Query == "") == (c.Table == "") {
+		return fmt.Errorf("either --query or --table must be given")
+	}
+
+	c.userAuth = new(auth.None)
+	c.optionalIndex = true
+	if err := c.buildDatabase(); err != nil {
+		return err
+	}
+
+	if c.Table != "" {
+		return c.exportTable()
+	}
+
+	return c.exportQuery()
+}
+
+// exportTable writes a file for each partition of the table, that is, for
+// each repository.
+func (c *Export) exportTable() error {
+	table, err := c.engine.Catalog.Table(c.Name, c.Table)
+	if err != nil {
+		return err
+	}
+
+	ctx := sql.NewContext(context.Background(), sql.WithSession(c.newSession()))
+	partitions, err := tablePartitions(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(c.Out, table.Name())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	old, err := readChecksums(dir)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu        sync.Mutex
+		checksums = make(map[string]string)
+		skipped   int
+		firstErr  error
+		wg        sync.WaitGroup
+		ch        = make(chan sql.Partition)
+		// failed is closed when a partition fails to stop exporting the
+		// rest of them.
+		failed = make(chan struct{})
+	)
+
+	parallelism := int(c.Parallelism)
+	if parallelism == 0 {
+		parallelism = runtime.NumCPU()
+	}
+
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range ch {
+				select {
+				case <-failed:
+					continue
+				default:
+				}
+
+				name := partitionFileName(p.Key()) + "." + c.OutputFormat
+				checksum := c.partitionChecksum(p)
+
+				mu.Lock()
+				skip := checksum != "" && old[name] == checksum && fileExists(filepath.Join(dir, name))
+				mu.Unlock()
+
+				var err error
+				if !skip {
+					err = c.exportPartition(ctx, table, p, filepath.Join(dir, name))
+				}
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					close(failed)
+				} else if err == nil {
+					checksums[name] = checksum
+					if skip {
+						skipped++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, p := range partitions {
+		select {
+		case ch <- p:
+		case <-failed:
+			break feed
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	// files of repositories that are not in the library anymore are removed,
+	// unless the export failed and they may just not have been written.
+	var removed int
+	if firstErr == nil {
+		for name := range old {
+			if _, ok := checksums[name]; ok {
+				continue
+			}
+
+			err := os.Remove(filepath.Join(dir, name))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			removed++
+		}
+	} else {
+		for name, checksum := range old {
+			if _, ok := checksums[name]; !ok {
+				checksums[name] = checksum
+			}
+		}
+	}
+
+	if err := writeChecksums(dir, checksums); err != nil {
+		return err
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"table":   table.Name(),
+		"dir":     dir,
+		"written": len(partitions) - skipped,
+		"skipped": skipped,
+		"removed": removed,
+	}).Info("table exported")
+
+	return nil
+}
+
+func (c *Export) exportPartition(
+	ctx *sql.Context,
+	table sql.Table,
+	p sql.Partition,
+	path string,
+) error {
+	rows, err := table.PartitionRows(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithField("partition", string(p.Key())).Debug("exporting partition")
+	return c.writeFile(path, table.Schema(), rows)
+}
+
+// partitionChecksum returns the checksum of the repository of a partition,
+// or an empty string if it can't be computed, so the partition is always
+// exported.
+func (c *Export) partitionChecksum(p sql.Partition) string {
+	rp, ok := p.(gitbase.RepositoryPartition)
+	if !ok {
+		return ""
+	}
+
+	checksum, err := c.pool.RepositoryChecksum(string(rp))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"partition": string(rp),
+			"error":     err,
+		}).Warn("unable to compute checksum of repository")
+		return ""
+	}
+
+	return checksum
+}
+
+// exportQuery writes the results of the query to a single file. The file
+// is not written again if neither the query nor the repositories changed.
+func (c *Export) exportQuery() error {
+	if err := os.MkdirAll(c.Out, 0755); err != nil {
+		return err
+	}
+
+	old, err := readChecksums(c.Out)
+	if err != nil {
+		return err
+	}
+
+	name := queryFile + "." + c.OutputFormat
+	path := filepath.Join(c.Out, name)
+	checksum := c.queryChecksum()
+	if checksum != "" && old[name] == checksum && fileExists(path) {
+		logrus.WithField("file", path).Info("query results did not change, skipping export")
+		return nil
+	}
+
+	ctx := sql.NewContext(context.Background(),
+		sql.WithSession(c.newSession()),
+		sql.WithQuery(c.Query),
+	)
+
+	schema, iter, err := c.engine.Query(ctx, c.Query)
+	if err != nil {
+		return err
+	}
+
+	if err := c.writeFile(path, schema, iter); err != nil {
+		return err
+	}
+
+	old[name] = checksum
+	if err := writeChecksums(c.Out, old); err != nil {
+		return err
+	}
+
+	logrus.WithField("file", path).Info("query results exported")
+	return nil
+}
+
+// queryChecksum returns a checksum of the query and all the repositories,
+// or an empty string if it can't be computed.
+func (c *Export) queryChecksum() string {
+	iter, err := c.pool.RepoIter()
+	if err != nil {
+		return ""
+	}
+	defer iter.Close()
+
+	var ids []string
+	for {
+		repo, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return ""
+		}
+
+		ids = append(ids, repo.ID())
+		_ = repo.Close()
+	}
+
+	sort.Strings(ids)
+	hash := sha1.New()
+	_, _ = io.WriteString(hash, c.Query)
+	for _, id := range ids {
+		checksum, err := c.pool.RepositoryChecksum(id)
+		if err != nil {
+			return ""
+		}
+
+		_, _ = io.WriteString(hash, "\x00"+id+"\x00"+checksum)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// writeFile writes the rows to a file. They are written to a temporary file
+// first, so the file is only replaced once all the rows are written.
+func (c *Export) writeFile(path string, schema sql.Schema, iter sql.RowIter) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		_ = iter.Close()
+		return err
+	}
+
+	rw, err := newRowWriter(c.OutputFormat, f)
+	if err == nil {
+		_, err = writeRows(rw, schema, iter)
+	} else {
+		_ = iter.Close()
+	}
+
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func tablePartitions(ctx *sql.Context, table sql.Table) ([]sql.Partition, error) {
+	iter, err := table.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	var partitions []sql.Partition
+	for {
+		p, err := iter.Next()
+		if err == io.EOF {
+			return partitions, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		partitions = append(partitions, p)
+	}
+}
+
+// partitionFileName returns the name of the file of a partition, without
+// extension. Keys that can't be used as file names are sanitized and a
+// hash of the key is added to keep the names unique.
+func partitionFileName(key []byte) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, string(key))
+
+	if name != string(key) || name == "" || name[0] == '.' || name[0] == '_' {
+		sum := sha1.Sum(key)
+		name = strings.TrimLeft(name, "._") + "-" + hex.EncodeToString(sum[:4])
+	}
+
+	return name
+}
+
+func readChecksums(dir string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	b, err := ioutil.ReadFile(filepath.Join(dir, checksumsFile))
+	if os.IsNotExist(err) {
+		return checksums, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &checksums); err != nil {
+		return nil, fmt.Errorf("invalid checksums file in %s: %s", dir, err)
+	}
+
+	return checksums, nil
+}
+
+func writeChecksums(dir string, checksums map[string]string) error {
+	b, err := json.MarshalIndent(checksums, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, "."+checksumsFile+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, checksumsFile))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,190 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExport(out string) *Export {
+	return &Export{
+		Database: Database{
+			CacheSize:   512,
+			Format:      "siva",
+			Bucket:      0,
+			LogLevel:    "info",
+			Directories: []string{"../../../_testdata"},
+			IndexDir:    filepath.Join(out, "_index"),
+		},
+		OutputFormat: CSVFormat,
+		Out:          out,
+	}
+}
+
+func TestExportTable(t *testing.T) {
+	require := require.New(t)
+
+	out, err := ioutil.TempDir("", "gitbase-export")
+	require.NoError(err)
+	defer os.RemoveAll(out)
+
+	cmd := newTestExport(out)
+	cmd.Table = "refs"
+	require.NoError(cmd.Execute(nil))
+
+	dir := filepath.Join(out, "refs")
+	require.Equal([]string{
+		"015da2f4-6d89-7ec8-5ac9-a38329ea875b.csv",
+		"015dcc49-9049-b00c-ba72-b6f5fa98cbe7.csv",
+		"015dcc49-90e6-34f2-ac03-df879ee269f3.csv",
+		"015dcc4d-0bdf-6aff-4aac-ffe68c752eb3.csv",
+		"015dcc4d-2622-bdac-12a5-ec441e3f3508.csv",
+		checksumsFile,
+	}, dirNames(t, dir))
+
+	file := filepath.Join(dir, "015da2f4-6d89-7ec8-5ac9-a38329ea875b.csv")
+	b, err := ioutil.ReadFile(file)
+	require.NoError(err)
+	require.Equal(
+		"repository_id,ref_name,commit_hash\n"+
+			"015da2f4-6d89-7ec8-5ac9-a38329ea875b,HEAD,dbfab055c70379219cbcf422f05316fdf4e1aed3\n"+
+			"015da2f4-6d89-7ec8-5ac9-a38329ea875b,refs/heads/master,dbfab055c70379219cbcf422f05316fdf4e1aed3\n",
+		string(b),
+	)
+
+	// unchanged repositories are not exported again, missing files and
+	// files of unknown repositories are
+	require.NoError(ioutil.WriteFile(file, []byte("unchanged"), 0644))
+	missing := filepath.Join(dir, "015dcc4d-2622-bdac-12a5-ec441e3f3508.csv")
+	require.NoError(os.Remove(missing))
+
+	checksums, err := readChecksums(dir)
+	require.NoError(err)
+	checksums["stale.csv"] = "foo"
+	require.NoError(writeChecksums(dir, checksums))
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "stale.csv"), nil, 0644))
+
+	cmd = newTestExport(out)
+	cmd.Table = "refs"
+	require.NoError(cmd.Execute(nil))
+
+	b, err = ioutil.ReadFile(file)
+	require.NoError(err)
+	require.Equal("unchanged", string(b))
+	require.FileExists(missing)
+	require.Len(dirNames(t, dir), 6)
+
+	checksums, err = readChecksums(dir)
+	require.NoError(err)
+	require.Len(checksums, 5)
+	require.NotContains(checksums, "stale.csv")
+}
+
+func TestExportTableError(t *testing.T) {
+	require := require.New(t)
+
+	out, err := ioutil.TempDir("", "gitbase-export")
+	require.NoError(err)
+	defer os.RemoveAll(out)
+
+	cmd := newTestExport(out)
+	cmd.Table = "refs"
+	cmd.Parallelism = 1
+	cmd.userAuth = new(auth.None)
+	require.NoError(cmd.buildDatabase())
+
+	table, err := cmd.engine.Catalog.Table(cmd.Name, cmd.Table)
+	require.NoError(err)
+	ctx := sql.NewContext(context.Background(), sql.WithSession(cmd.newSession()))
+	partitions, err := tablePartitions(ctx, table)
+	require.NoError(err)
+	require.Len(partitions, 5)
+
+	// the file of the first partition can't be written, so the export
+	// must stop before writing the rest of them.
+	dir := filepath.Join(out, "refs")
+	first := partitionFileName(partitions[0].Key()) + ".csv"
+	require.NoError(os.MkdirAll(filepath.Join(dir, first), 0755))
+
+	require.Error(cmd.exportTable())
+	require.Equal([]string{first, checksumsFile}, dirNames(t, dir))
+}
+
+func TestExportQuery(t *testing.T) {
+	require := require.New(t)
+
+	out, err := ioutil.TempDir("", "gitbase-export")
+	require.NoError(err)
+	defer os.RemoveAll(out)
+
+	export := func(query string) {
+		cmd := newTestExport(out)
+		cmd.OutputFormat = JSONLFormat
+		cmd.Query = query
+		require.NoError(cmd.Execute(nil))
+	}
+
+	export("SELECT COUNT(*) AS repos FROM repositories")
+
+	file := filepath.Join(out, "query.jsonl")
+	b, err := ioutil.ReadFile(file)
+	require.NoError(err)
+
+	var row map[string]interface{}
+	require.NoError(json.Unmarshal(b, &row))
+	require.Equal(map[string]interface{}{"repos": float64(5)}, row)
+
+	require.NoError(ioutil.WriteFile(file, []byte("unchanged"), 0644))
+	export("SELECT COUNT(*) AS repos FROM repositories")
+	b, err = ioutil.ReadFile(file)
+	require.NoError(err)
+	require.Equal("unchanged", string(b))
+
+	export("SELECT COUNT(*) AS refs FROM refs")
+	b, err = ioutil.ReadFile(file)
+	require.NoError(err)
+	require.NotEqual("unchanged", string(b))
+}
+
+func TestExportInvalidArgs(t *testing.T) {
+	cmd := newTestExport("")
+	require.Error(t, cmd.Execute(nil))
+
+	cmd = newTestExport("")
+	cmd.Query = "SELECT 1"
+	cmd.Table = "refs"
+	require.Error(t, cmd.Execute(nil))
+}
+
+func TestPartitionFileName(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("foo-bar.git", partitionFileName([]byte("foo-bar.git")))
+
+	name := partitionFileName([]byte("github.com/foo/bar"))
+	require.Regexp(`^github.com_foo_bar-[0-9a-f]{8}$`, name)
+	require.NotEqual(name, partitionFileName([]byte("github.com/foo_bar")))
+
+	require.Regexp(`^foo-[0-9a-f]{8}$`, partitionFileName([]byte(".foo")))
+	require.Regexp(`^-[0-9a-f]{8}$`, partitionFileName(nil))
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
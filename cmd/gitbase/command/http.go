@@ -0,0 +1,301 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/src-d/gitbase"
+
+	"github.com/sirupsen/logrus"
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"vitess.io/vitess/go/mysql"
+)
+
+const (
+	// maxQuerySize is the maximum size in bytes of the body of a query
+	// request.
+	maxQuerySize = 1 << 20
+
+	// httpPidOffset is added to the pids of the queries received through
+	// HTTP, so they don't collide with the ones of MySQL connections.
+	httpPidOffset = 1 << 32
+
+	errorTrailer = "X-Gitbase-Error"
+	typesHeader  = "X-Gitbase-Column-Types"
+
+	jsonlContentType = "application/x-ndjson"
+	csvContentType   = "text/csv"
+)
+
+// queryHandler serves the HTTP API to run queries. The queries are sent as
+// the body of POST requests to /query, and their rows are streamed back as
+// JSON lines or CSV. Users are authenticated with HTTP basic
+// authentication against the same auth.Auth used by the MySQL server.
+type queryHandler struct {
+	engine      *sqle.Engine
+	pool        *gitbase.RepositoryPool
+	auth        auth.Auth
+	authServer  mysql.AuthServer
+	addr        string
+	sessionOpts []gitbase.SessionOption
+
+	pid    uint64
+	connID uint32
+	// open is the number of queries being run.
+	open int32
+}
+
+func newQueryHandler(
+	engine *sqle.Engine,
+	pool *gitbase.RepositoryPool,
+	userAuth auth.Auth,
+	addr string,
+	opts ...gitbase.SessionOption,
+) *queryHandler {
+	return &queryHandler{
+		engine:      engine,
+		pool:        pool,
+		auth:        userAuth,
+		authServer:  userAuth.Mysql(),
+		addr:        addr,
+		sessionOpts: opts,
+	}
+}
+
+// newHTTPServer returns a server listening on the given host and port with
+// the query API.
+func newHTTPServer(host string, port int, h *queryHandler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/query", h)
+
+	return &http.Server{
+		Addr:    net.JoinHostPort(host, strconv.Itoa(port)),
+		Handler: mux,
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *queryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeHTTPError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	user, ok := h.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="gitbase"`)
+		writeHTTPError(w, http.StatusUnauthorized, fmt.Errorf("access denied for user %q", user))
+		return
+	}
+
+	// every request has its own session, which is open until the response
+	// is written.
+	atomic.AddInt32(&h.open, 1)
+	defer atomic.AddInt32(&h.open, -1)
+
+	format, err := responseFormat(r)
+	if err != nil {
+		writeHTTPError(w, http.StatusNotAcceptable, err)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxQuerySize))
+	if err != nil {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("cannot read query: %s", err))
+		return
+	}
+
+	query := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(string(body)), ";"))
+	if query == "" {
+		writeHTTPError(w, http.StatusBadRequest, fmt.Errorf("no query given"))
+		return
+	}
+
+	out := &flushWriter{w}
+	rw, err := newRowWriter(format, out)
+	if err != nil {
+		writeHTTPError(w, http.StatusNotAcceptable, err)
+		return
+	}
+
+	// the context of the request is canceled when the client disconnects,
+	// which cancels the query too.
+	ctx := sql.NewContext(r.Context(),
+		sql.WithSession(h.newSession(r, user)),
+		sql.WithPid(httpPidOffset+atomic.AddUint64(&h.pid, 1)),
+		sql.WithQuery(query),
+	)
+
+	start := time.Now()
+	schema, iter, err := h.engine.Query(ctx, query)
+	defer func() {
+		if a, ok := h.auth.(*auth.Audit); ok {
+			a.Query(ctx, time.Since(start), err)
+		}
+	}()
+
+	if err != nil {
+		status := http.StatusBadRequest
+		if auth.ErrNotAuthorized.Is(err) {
+			status = http.StatusForbidden
+		}
+
+		writeHTTPError(w, status, err)
+		return
+	}
+
+	types := make([]string, len(schema))
+	for i, col := range schema {
+		types[i] = col.Type.String()
+	}
+
+	w.Header().Set("Trailer", errorTrailer)
+	w.Header().Set(typesHeader, strings.Join(types, ","))
+	if format == JSONLFormat {
+		w.Header().Set("Content-Type", jsonlContentType)
+	} else {
+		w.Header().Set("Content-Type", csvContentType+"; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+
+	if format == JSONLFormat {
+		if err = writeColumns(out, schema); err != nil {
+			logrus.WithField("error", err).Debug("unable to write query response")
+			// the iterator must be closed to remove the query from the
+			// process list.
+			_ = iter.Close()
+			return
+		}
+	}
+
+	if _, err = writeRows(rw, schema, iter); err != nil {
+		w.Header().Set(errorTrailer, err.Error())
+		if format == JSONLFormat {
+			_ = json.NewEncoder(out).Encode(httpError{err.Error()})
+		}
+	}
+}
+
+// Sessions returns the number of sessions of the queries being run.
+func (h *queryHandler) Sessions() int {
+	return int(atomic.LoadInt32(&h.open))
+}
+
+// authenticate checks the credentials of the basic authentication of the
+// request and returns the name of the user.
+func (h *queryHandler) authenticate(r *http.Request) (string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	salt, err := mysql.NewSalt()
+	if err != nil {
+		return user, false
+	}
+
+	addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		return user, false
+	}
+
+	scrambled := mysql.ScramblePassword(salt, []byte(password))
+	_, err = h.authServer.ValidateHash(salt, user, scrambled, addr)
+	return user, err == nil
+}
+
+// newSession creates a session for a request with the same options as the
+// sessions of the MySQL server.
+func (h *queryHandler) newSession(r *http.Request, user string) sql.Session {
+	id := atomic.AddUint32(&h.connID, 1)
+	opts := append(h.sessionOpts[:len(h.sessionOpts):len(h.sessionOpts)],
+		gitbase.WithBaseSession(sql.NewSession(h.addr, r.RemoteAddr, user, id)),
+	)
+
+	return gitbase.NewSession(h.pool, opts...)
+}
+
+// responseFormat returns the format of the rows requested with the format
+// query parameter or, if it's not given, the Accept header. By default,
+// rows are returned as JSON lines.
+func responseFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case JSONLFormat, CSVFormat:
+			return format, nil
+		default:
+			return "", fmt.Errorf("unsupported format %q, expected jsonl or csv", format)
+		}
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case csvContentType:
+			return CSVFormat, nil
+		case jsonlContentType, "application/json", "*/*":
+			return JSONLFormat, nil
+		}
+	}
+
+	return JSONLFormat, nil
+}
+
+type column struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// writeColumns writes the line with the columns that precedes the rows of
+// a JSON lines response.
+func writeColumns(w *flushWriter, schema sql.Schema) error {
+	columns := make([]column, len(schema))
+	for i, col := range schema {
+		columns[i] = column{col.Name, col.Type.String(), col.Nullable}
+	}
+
+	return json.NewEncoder(w).Encode(struct {
+		Columns []column `json:"columns"`
+	}{columns})
+}
+
+type httpError struct {
+	Error string `json:"error"`
+}
+
+func writeHTTPError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(httpError{err.Error()})
+}
+
+// flushWriter flushes every write to the client, so the rows are streamed
+// as soon as they are written.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+
+	return n, err
+}
@@ -0,0 +1,203 @@
+package command
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryHandler(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	db := &Database{
+		Name:        "gitbase",
+		CacheSize:   512,
+		Format:      "siva",
+		Bucket:      0,
+		LogLevel:    "info",
+		Directories: []string{"../../../_testdata"},
+		IndexDir:    tmpDir,
+		userAuth:    auth.NewNativeSingle("user", "pass", auth.ReadPerm),
+	}
+	require.NoError(t, db.buildDatabase())
+
+	h := newQueryHandler(db.engine, db.pool, db.userAuth, "localhost:3306", db.sessionOptions()...)
+	srv := httptest.NewServer(newHTTPServer("localhost", 0, h).Handler)
+	defer srv.Close()
+
+	const refsQuery = "SELECT ref_name, commit_hash FROM refs " +
+		"WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b' " +
+		"ORDER BY ref_name;"
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		user     string
+		password string
+		accept   string
+		query    string
+		status   int
+		types    string
+		expected string
+	}{
+		{
+			name:   "jsonl",
+			method: http.MethodPost, path: "/query", user: "user", password: "pass",
+			query:  refsQuery,
+			status: http.StatusOK,
+			types:  "TEXT,VARCHAR(40)",
+			expected: `{"columns":[{"name":"ref_name","type":"TEXT","nullable":false},{"name":"commit_hash","type":"VARCHAR(40)","nullable":false}]}` + "\n" +
+				`{"ref_name":"HEAD","commit_hash":"dbfab055c70379219cbcf422f05316fdf4e1aed3"}` + "\n" +
+				`{"ref_name":"refs/heads/master","commit_hash":"dbfab055c70379219cbcf422f05316fdf4e1aed3"}` + "\n",
+		},
+		{
+			name:   "csv with accept header",
+			method: http.MethodPost, path: "/query", user: "user", password: "pass",
+			accept: "text/csv",
+			query:  refsQuery,
+			status: http.StatusOK,
+			types:  "TEXT,VARCHAR(40)",
+			expected: "ref_name,commit_hash\n" +
+				"HEAD,dbfab055c70379219cbcf422f05316fdf4e1aed3\n" +
+				"refs/heads/master,dbfab055c70379219cbcf422f05316fdf4e1aed3\n",
+		},
+		{
+			name:   "csv with format parameter",
+			method: http.MethodPost, path: "/query?format=csv", user: "user", password: "pass",
+			accept:   "application/json",
+			query:    "SELECT COUNT(*) AS repos FROM repositories",
+			status:   http.StatusOK,
+			types:    "INT64",
+			expected: "repos\n5\n",
+		},
+		{
+			name:   "unknown format",
+			method: http.MethodPost, path: "/query?format=xml", user: "user", password: "pass",
+			query:    refsQuery,
+			status:   http.StatusNotAcceptable,
+			expected: `{"error":"unsupported format \"xml\", expected jsonl or csv"}` + "\n",
+		},
+		{
+			name:   "wrong password",
+			method: http.MethodPost, path: "/query", user: "user", password: "nope",
+			query:    refsQuery,
+			status:   http.StatusUnauthorized,
+			expected: `{"error":"access denied for user \"user\""}` + "\n",
+		},
+		{
+			name:   "no credentials",
+			method: http.MethodPost, path: "/query",
+			query:    refsQuery,
+			status:   http.StatusUnauthorized,
+			expected: `{"error":"access denied for user \"\""}` + "\n",
+		},
+		{
+			name:   "get",
+			method: http.MethodGet, path: "/query", user: "user", password: "pass",
+			status:   http.StatusMethodNotAllowed,
+			expected: `{"error":"method GET not allowed"}` + "\n",
+		},
+		{
+			name:   "empty query",
+			method: http.MethodPost, path: "/query", user: "user", password: "pass",
+			query:    " ; ",
+			status:   http.StatusBadRequest,
+			expected: `{"error":"no query given"}` + "\n",
+		},
+		{
+			name:   "invalid query",
+			method: http.MethodPost, path: "/query", user: "user", password: "pass",
+			query:    "SELECT * FROM nope",
+			status:   http.StatusBadRequest,
+			expected: `{"error":"table not found: nope"}` + "\n",
+		},
+		{
+			name:   "not allowed",
+			method: http.MethodPost, path: "/query", user: "user", password: "pass",
+			query:    "DROP INDEX foo ON refs",
+			status:   http.StatusForbidden,
+			expected: `{"error":"not authorized: user does not have permission: write"}` + "\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.query))
+			require.NoError(err)
+
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(err)
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(err)
+
+			require.Equal(tt.status, resp.StatusCode)
+			require.Equal(tt.expected, string(body))
+			require.Equal(tt.types, resp.Header.Get(typesHeader))
+			require.Empty(resp.Trailer.Get(errorTrailer))
+		})
+	}
+
+	t.Run("sessions", func(t *testing.T) {
+		require := require.New(t)
+		require.Equal(0, h.Sessions())
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader("SELECT SLEEP(0.5)"))
+			req.SetBasicAuth("user", "pass")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+
+		var open bool
+		for i := 0; i < 40 && !open; i++ {
+			open = h.Sessions() == 1
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.True(open)
+
+		<-done
+		require.Equal(0, h.Sessions())
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(refsQuery))
+		req.SetBasicAuth("user", "pass")
+
+		h.ServeHTTP(&failingResponseWriter{httptest.NewRecorder()}, req)
+		require.Empty(t, db.engine.Catalog.ProcessList.Processes())
+	})
+}
+
+// failingResponseWriter is a response writer that fails to write the body,
+// as when the client disconnects.
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (*failingResponseWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,105 @@
+package command
+
+import (
+	"crypto/tls"
+	"sync/atomic"
+
+	"github.com/opentracing/opentracing-go"
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/server"
+	"vitess.io/vitess/go/mysql"
+)
+
+// mysqlServerOptions are the options of the MySQL listeners that
+// server.Config doesn't have.
+type mysqlServerOptions struct {
+	// tlsConfig enables TLS connections if it's not nil.
+	tlsConfig *tls.Config
+	// requireSecure rejects the clients that don't use TLS.
+	requireSecure bool
+	// conns gives IDs to the connections and counts the open ones. The
+	// listeners sharing it give unique IDs to all their connections.
+	conns *connections
+}
+
+// newMySQLServer creates the MySQL server the same way server.NewServer
+// does, with the given options.
+func newMySQLServer(
+	cfg server.Config,
+	e *sqle.Engine,
+	sb server.SessionBuilder,
+	opts mysqlServerOptions,
+) (*server.Server, error) {
+	if cfg.Tracer == nil {
+		cfg.Tracer = opentracing.NoopTracer{}
+	}
+
+	if cfg.ConnReadTimeout < 0 {
+		cfg.ConnReadTimeout = 0
+	}
+
+	if cfg.ConnWriteTimeout < 0 {
+		cfg.ConnWriteTimeout = 0
+	}
+
+	var handler mysql.Handler = server.NewHandler(
+		e,
+		server.NewSessionManager(sb, cfg.Tracer, cfg.Address),
+	)
+	if opts.requireSecure {
+		handler = &secureTransportHandler{handler}
+	}
+
+	if opts.conns != nil {
+		handler = &connectionsHandler{handler, opts.conns}
+	}
+
+	l, err := mysql.NewListener(
+		cfg.Protocol,
+		cfg.Address,
+		cfg.Auth.Mysql(),
+		handler,
+		cfg.ConnReadTimeout,
+		cfg.ConnWriteTimeout,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	l.TLSConfig = opts.tlsConfig
+	l.RequireSecureTransport = opts.requireSecure
+
+	return &server.Server{Listener: l}, nil
+}
+
+// connections holds the last ID given to a connection and the number of
+// open connections of the MySQL listeners.
+type connections struct {
+	lastID uint32
+	open   int32
+}
+
+// Open returns the number of open connections.
+func (c *connections) Open() int {
+	return int(atomic.LoadInt32(&c.open))
+}
+
+// connectionsHandler counts the open connections and replaces the IDs the
+// listener gives to them, which start from 1 in every listener, with the
+// ones of a counter shared by all the listeners. The engine identifies the
+// sessions, processes and table locks of the connections by these IDs.
+type connectionsHandler struct {
+	mysql.Handler
+	conns *connections
+}
+
+func (h *connectionsHandler) NewConnection(c *mysql.Conn) {
+	c.ConnectionID = atomic.AddUint32(&h.conns.lastID, 1)
+	atomic.AddInt32(&h.conns.open, 1)
+	h.Handler.NewConnection(c)
+}
+
+func (h *connectionsHandler) ConnectionClosed(c *mysql.Conn) {
+	atomic.AddInt32(&h.conns.open, -1)
+	h.Handler.ConnectionClosed(c)
+}
@@ -0,0 +1,540 @@
+package command
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/src-d/gitbase/internal/parquet"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"vitess.io/vitess/go/sqltypes"
+)
+
+// Output formats of the rows of a query.
+const (
+	TableFormat    = "table"
+	CSVFormat      = "csv"
+	TSVFormat      = "tsv"
+	JSONLFormat    = "jsonl"
+	MarkdownFormat = "markdown"
+	VerticalFormat = "vertical"
+	ParquetFormat  = "parquet"
+)
+
+// rowWriter writes the rows of a query with some output format.
+type rowWriter interface {
+	// WriteHeader writes the header of the rows with the given schema. It is
+	// called once before any row is written.
+	WriteHeader(schema sql.Schema) error
+	// WriteRow writes a row.
+	WriteRow(row sql.Row) error
+	// Flush writes any buffered data.
+	Flush() error
+}
+
+// newRowWriter returns a rowWriter writing to w in the given format.
+func newRowWriter(format string, w io.Writer) (rowWriter, error) {
+	switch format {
+	case TableFormat:
+		return &tableWriter{w: w}, nil
+	case CSVFormat:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case TSVFormat:
+		return &tsvWriter{w: bufio.NewWriter(w)}, nil
+	case JSONLFormat:
+		return &jsonlWriter{w: bufio.NewWriter(w)}, nil
+	case MarkdownFormat:
+		return &markdownWriter{w: bufio.NewWriter(w)}, nil
+	case VerticalFormat:
+		return &verticalWriter{w: bufio.NewWriter(w)}, nil
+	case ParquetFormat:
+		return &parquetWriter{w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// writeRows writes all the rows of the iterator with the rowWriter and
+// returns the number of rows written. The iterator is always closed.
+func writeRows(rw rowWriter, schema sql.Schema, iter sql.RowIter) (n int, err error) {
+	defer func() {
+		if cerr := iter.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if err := rw.WriteHeader(schema); err != nil {
+		return 0, err
+	}
+
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return n, err
+		}
+
+		if err := rw.WriteRow(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rw.Flush()
+}
+
+// sqlValues converts the values of a row to their SQL representation, the
+// same values a MySQL client would receive.
+func sqlValues(schema sql.Schema, row sql.Row) ([]sqltypes.Value, error) {
+	values := make([]sqltypes.Value, len(row))
+	for i, v := range row {
+		var err error
+		values[i], err = schema[i].Type.SQL(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// textValues converts the values of a row to text, using null for the NULL
+// values.
+func textValues(schema sql.Schema, row sql.Row, null string) ([]string, error) {
+	values, err := sqlValues(schema, row)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, len(values))
+	for i, v := range values {
+		if v.IsNull() {
+			result[i] = null
+		} else {
+			result[i] = v.ToString()
+		}
+	}
+
+	return result, nil
+}
+
+func columnNames(schema sql.Schema) []string {
+	names := make([]string, len(schema))
+	for i, col := range schema {
+		names[i] = col.Name
+	}
+
+	return names
+}
+
+// tableWriter writes the rows as an aligned table, like the MySQL client
+// does. Since the width of the columns depends on all the rows, they are
+// kept in memory until Flush is called.
+type tableWriter struct {
+	w      io.Writer
+	schema sql.Schema
+	rows   [][]string
+	widths []int
+}
+
+func (t *tableWriter) WriteHeader(schema sql.Schema) error {
+	t.schema = schema
+	t.add(columnNames(schema))
+	return nil
+}
+
+func (t *tableWriter) WriteRow(row sql.Row) error {
+	values, err := textValues(t.schema, row, "NULL")
+	if err != nil {
+		return err
+	}
+
+	t.add(values)
+	return nil
+}
+
+func (t *tableWriter) add(values []string) {
+	if t.widths == nil {
+		t.widths = make([]int, len(values))
+	}
+
+	for i, v := range values {
+		if n := utf8.RuneCountInString(v); n > t.widths[i] {
+			t.widths[i] = n
+		}
+	}
+
+	t.rows = append(t.rows, values)
+}
+
+func (t *tableWriter) Flush() error {
+	if len(t.rows) == 0 {
+		return nil
+	}
+
+	w := bufio.NewWriter(t.w)
+	t.writeSeparator(w)
+	for i, values := range t.rows {
+		w.WriteString("|")
+		for j, v := range values {
+			padding := t.widths[j] - utf8.RuneCountInString(v)
+			w.WriteString(" " + v + strings.Repeat(" ", padding) + " |")
+		}
+		w.WriteString("\n")
+
+		if i == 0 {
+			t.writeSeparator(w)
+		}
+	}
+	t.writeSeparator(w)
+
+	t.rows = nil
+	return w.Flush()
+}
+
+func (t *tableWriter) writeSeparator(w *bufio.Writer) {
+	w.WriteString("+")
+	for _, width := range t.widths {
+		w.WriteString(strings.Repeat("-", width+2) + "+")
+	}
+	w.WriteString("\n")
+}
+
+// csvWriter writes the rows as CSV with a header line. NULL values are
+// written as empty fields.
+type csvWriter struct {
+	w      *csv.Writer
+	schema sql.Schema
+}
+
+func (c *csvWriter) WriteHeader(schema sql.Schema) error {
+	c.schema = schema
+	return c.w.Write(columnNames(schema))
+}
+
+func (c *csvWriter) WriteRow(row sql.Row) error {
+	values, err := textValues(c.schema, row, "")
+	if err != nil {
+		return err
+	}
+
+	return c.w.Write(values)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// tsvEscaper escapes the values of TSV rows the same way the batch mode of
+// the MySQL client does.
+var tsvEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x00", `\0`,
+)
+
+// tsvWriter writes the rows as tab separated values with a header line.
+// NULL values are written as NULL.
+type tsvWriter struct {
+	w      *bufio.Writer
+	schema sql.Schema
+}
+
+func (t *tsvWriter) WriteHeader(schema sql.Schema) error {
+	t.schema = schema
+	return t.write(columnNames(schema))
+}
+
+func (t *tsvWriter) WriteRow(row sql.Row) error {
+	values, err := textValues(t.schema, row, "NULL")
+	if err != nil {
+		return err
+	}
+
+	return t.write(values)
+}
+
+func (t *tsvWriter) write(values []string) error {
+	for i, v := range values {
+		values[i] = tsvEscaper.Replace(v)
+	}
+
+	_, err := t.w.WriteString(strings.Join(values, "\t") + "\n")
+	return err
+}
+
+func (t *tsvWriter) Flush() error {
+	return t.w.Flush()
+}
+
+// jsonlWriter writes every row as a JSON object in its own line, with the
+// columns in the same order as the schema. Numbers and JSON values are kept
+// as they are and the rest of values are written as strings.
+type jsonlWriter struct {
+	w      *bufio.Writer
+	schema sql.Schema
+	keys   [][]byte
+}
+
+func (j *jsonlWriter) WriteHeader(schema sql.Schema) error {
+	j.schema = schema
+	j.keys = make([][]byte, len(schema))
+	for i, col := range schema {
+		key, err := json.Marshal(col.Name)
+		if err != nil {
+			return err
+		}
+
+		j.keys[i] = key
+	}
+
+	return nil
+}
+
+func (j *jsonlWriter) WriteRow(row sql.Row) error {
+	values, err := sqlValues(j.schema, row)
+	if err != nil {
+		return err
+	}
+
+	j.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+
+		j.w.Write(j.keys[i])
+		j.w.WriteByte(':')
+
+		val, err := jsonValue(v)
+		if err != nil {
+			return err
+		}
+		j.w.Write(val)
+	}
+
+	_, err = j.w.WriteString("}\n")
+	return err
+}
+
+func jsonValue(v sqltypes.Value) ([]byte, error) {
+	switch {
+	case v.IsNull():
+		return []byte("null"), nil
+	case v.IsIntegral(), v.IsFloat(), v.Type() == sqltypes.TypeJSON:
+		return v.ToBytes(), nil
+	default:
+		return json.Marshal(v.ToString())
+	}
+}
+
+func (j *jsonlWriter) Flush() error {
+	return j.w.Flush()
+}
+
+// markdownEscaper escapes the values of markdown table cells, which can't
+// contain pipes nor new lines.
+var markdownEscaper = strings.NewReplacer(
+	"|", `\|`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+)
+
+// markdownWriter writes the rows as a markdown table.
+type markdownWriter struct {
+	w      *bufio.Writer
+	schema sql.Schema
+}
+
+func (m *markdownWriter) WriteHeader(schema sql.Schema) error {
+	m.schema = schema
+	if err := m.write(columnNames(schema)); err != nil {
+		return err
+	}
+
+	separator := make([]string, len(schema))
+	for i := range separator {
+		separator[i] = "---"
+	}
+
+	return m.write(separator)
+}
+
+func (m *markdownWriter) WriteRow(row sql.Row) error {
+	values, err := textValues(m.schema, row, "NULL")
+	if err != nil {
+		return err
+	}
+
+	return m.write(values)
+}
+
+func (m *markdownWriter) write(values []string) error {
+	for i, v := range values {
+		values[i] = markdownEscaper.Replace(v)
+	}
+
+	_, err := m.w.WriteString("| " + strings.Join(values, " | ") + " |\n")
+	return err
+}
+
+func (m *markdownWriter) Flush() error {
+	return m.w.Flush()
+}
+
+// verticalWriter writes every row with a line for each column, as the
+// MySQL client does for the queries ended with \G.
+type verticalWriter struct {
+	w      *bufio.Writer
+	schema sql.Schema
+	names  []string
+	n      int
+}
+
+func (v *verticalWriter) WriteHeader(schema sql.Schema) error {
+	v.schema = schema
+	v.names = columnNames(schema)
+
+	var width int
+	for _, name := range v.names {
+		if n := utf8.RuneCountInString(name); n > width {
+			width = n
+		}
+	}
+
+	for i, name := range v.names {
+		padding := width - utf8.RuneCountInString(name)
+		v.names[i] = strings.Repeat(" ", padding) + name
+	}
+
+	return nil
+}
+
+func (v *verticalWriter) WriteRow(row sql.Row) error {
+	values, err := textValues(v.schema, row, "NULL")
+	if err != nil {
+		return err
+	}
+
+	v.n++
+	fmt.Fprintf(v.w, "*************************** %d. row ***************************\n", v.n)
+	for i, value := range values {
+		if _, err := v.w.WriteString(v.names[i] + ": " + value + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *verticalWriter) Flush() error {
+	return v.w.Flush()
+}
+
+// parquetWriter writes the rows as a parquet file, with the gitbase types
+// mapped to parquet logical types.
+type parquetWriter struct {
+	w      *bufio.Writer
+	pw     *parquet.Writer
+	schema sql.Schema
+	kinds  []parquet.Kind
+}
+
+func (p *parquetWriter) WriteHeader(schema sql.Schema) error {
+	p.schema = schema
+	p.kinds = make([]parquet.Kind, len(schema))
+	columns := make([]parquet.Column, len(schema))
+	for i, col := range schema {
+		p.kinds[i] = parquetKind(col.Type)
+		columns[i] = parquet.Column{Name: col.Name, Kind: p.kinds[i]}
+	}
+
+	var err error
+	p.pw, err = parquet.NewWriter(p.w, columns)
+	return err
+}
+
+func (p *parquetWriter) WriteRow(row sql.Row) error {
+	values := make([]interface{}, len(row))
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+
+		typ := p.schema[i].Type
+		if p.kinds[i] == parquet.JSON {
+			val, err := typ.SQL(v)
+			if err != nil {
+				return err
+			}
+
+			values[i] = val.Raw()
+			continue
+		}
+
+		val, err := typ.Convert(v)
+		if err != nil {
+			return err
+		}
+		values[i] = val
+	}
+
+	return p.pw.Write(values)
+}
+
+func (p *parquetWriter) Flush() error {
+	if err := p.pw.Close(); err != nil {
+		return err
+	}
+
+	return p.w.Flush()
+}
+
+// parquetKind returns the kind of the parquet column of a gitbase type.
+func parquetKind(t sql.Type) parquet.Kind {
+	switch t.Type() {
+	case sqltypes.Bit:
+		return parquet.Boolean
+	case sqltypes.Int8:
+		return parquet.Int8
+	case sqltypes.Uint8:
+		return parquet.Uint8
+	case sqltypes.Int16:
+		return parquet.Int16
+	case sqltypes.Uint16:
+		return parquet.Uint16
+	case sqltypes.Int24, sqltypes.Int32:
+		return parquet.Int32
+	case sqltypes.Uint24, sqltypes.Uint32:
+		return parquet.Uint32
+	case sqltypes.Int64:
+		return parquet.Int64
+	case sqltypes.Uint64:
+		return parquet.Uint64
+	case sqltypes.Float32:
+		return parquet.Float
+	case sqltypes.Float64:
+		return parquet.Double
+	case sqltypes.Timestamp, sqltypes.Datetime:
+		return parquet.Timestamp
+	case sqltypes.Date:
+		return parquet.Date
+	case sqltypes.Blob:
+		return parquet.Bytes
+	case sqltypes.TypeJSON:
+		return parquet.JSON
+	default:
+		return parquet.String
+	}
+}
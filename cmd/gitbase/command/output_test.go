@@ -0,0 +1,129 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/src-d/gitbase/internal/parquet"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRowWriters(t *testing.T) {
+	schema := sql.Schema{
+		{Name: "name", Type: sql.Text},
+		{Name: "count", Type: sql.Int64},
+		{Name: "stats", Type: sql.JSON},
+		{Name: "empty", Type: sql.Text},
+	}
+
+	rows := []sql.Row{
+		sql.NewRow("foo", int64(1), map[string]interface{}{"a": 1}, nil),
+		sql.NewRow("bar,\tbaz|qux\n", int64(22), []interface{}{"b"}, "x"),
+	}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			TableFormat,
+			"+---------------+-------+---------+-------+\n" +
+				"| name          | count | stats   | empty |\n" +
+				"+---------------+-------+---------+-------+\n" +
+				"| foo           | 1     | {\"a\":1} | NULL  |\n" +
+				"| bar,\tbaz|qux\n | 22    | [\"b\"]   | x     |\n" +
+				"+---------------+-------+---------+-------+\n",
+		},
+		{
+			CSVFormat,
+			"name,count,stats,empty\n" +
+				"foo,1,\"{\"\"a\"\":1}\",\n" +
+				"\"bar,\tbaz|qux\n\",22,\"[\"\"b\"\"]\",x\n",
+		},
+		{
+			TSVFormat,
+			"name\tcount\tstats\tempty\n" +
+				"foo\t1\t{\"a\":1}\tNULL\n" +
+				"bar,\\tbaz|qux\\n\t22\t[\"b\"]\tx\n",
+		},
+		{
+			JSONLFormat,
+			`{"name":"foo","count":1,"stats":{"a":1},"empty":null}` + "\n" +
+				`{"name":"bar,\tbaz|qux\n","count":22,"stats":["b"],"empty":"x"}` + "\n",
+		},
+		{
+			MarkdownFormat,
+			"| name | count | stats | empty |\n" +
+				"| --- | --- | --- | --- |\n" +
+				"| foo | 1 | {\"a\":1} | NULL |\n" +
+				"| bar,\tbaz\\|qux<br> | 22 | [\"b\"] | x |\n",
+		},
+		{
+			VerticalFormat,
+			"*************************** 1. row ***************************\n" +
+				" name: foo\n" +
+				"count: 1\n" +
+				"stats: {\"a\":1}\n" +
+				"empty: NULL\n" +
+				"*************************** 2. row ***************************\n" +
+				" name: bar,\tbaz|qux\n\n" +
+				"count: 22\n" +
+				"stats: [\"b\"]\n" +
+				"empty: x\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.format, func(t *testing.T) {
+			require := require.New(t)
+
+			var buf bytes.Buffer
+			rw, err := newRowWriter(tt.format, &buf)
+			require.NoError(err)
+
+			n, err := writeRows(rw, schema, sql.RowsToRowIter(rows...))
+			require.NoError(err)
+			require.Equal(2, n)
+			require.Equal(tt.expected, buf.String())
+		})
+	}
+}
+
+func TestRowWriterUnknownFormat(t *testing.T) {
+	_, err := newRowWriter("xml", new(bytes.Buffer))
+	require.Error(t, err)
+}
+
+func TestParquetRowWriter(t *testing.T) {
+	require := require.New(t)
+
+	schema := sql.Schema{
+		{Name: "name", Type: sql.Text},
+		{Name: "count", Type: sql.Int32},
+		{Name: "stats", Type: sql.JSON},
+		{Name: "when", Type: sql.Timestamp},
+	}
+
+	var buf bytes.Buffer
+	rw, err := newRowWriter(ParquetFormat, &buf)
+	require.NoError(err)
+
+	n, err := writeRows(rw, schema, sql.RowsToRowIter(
+		sql.NewRow("foo", int64(1), map[string]interface{}{"a": 1}, "2019-01-02 03:04:05"),
+		sql.NewRow(nil, nil, nil, nil),
+	))
+	require.NoError(err)
+	require.Equal(2, n)
+
+	data := buf.Bytes()
+	require.Equal("PAR1", string(data[:4]))
+	require.Equal("PAR1", string(data[len(data)-4:]))
+	require.Contains(buf.String(), `{"a":1}`)
+
+	require.Equal(
+		[]parquet.Kind{parquet.String, parquet.Int32, parquet.JSON, parquet.Timestamp},
+		rw.(*parquetWriter).kinds,
+	)
+}
@@ -0,0 +1,102 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+const (
+	QueryDescription = "Runs a query and prints its results"
+	QueryHelp        = QueryDescription + "\n\n" +
+		"The query is given as argument or, if there are no arguments, read\n" +
+		"from the standard input. The rows are printed to the standard output\n" +
+		"as an aligned table, CSV, TSV, JSON lines or a markdown table.\n" +
+		"Logs are printed to the standard error."
+)
+
+// Query represents the `query` command of gitbase cli tool.
+type Query struct {
+	Database
+
+	Output string `short:"o" long:"output" default:"table" choice:"table" choice:"csv" choice:"tsv" choice:"jsonl" choice:"markdown" description:"Output format of the rows"`
+	Args   struct {
+		Query []string `positional-arg-name:"query" description:"Query to run; if it's not given, it's read from the standard input"`
+	} `positional-args:"yes"`
+
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// Execute runs a query in a new gitbase database and prints its rows, it
+// honors the go-flags.Commander interface.
+func (c *Query) Execute(args []string) error {
+	if err := c.setupLogging(); err != nil {
+		return err
+	}
+
+	if c.stdin == nil {
+		c.stdin = os.Stdin
+	}
+
+	if c.stdout == nil {
+		c.stdout = os.Stdout
+	}
+
+	query, err := c.query(append(c.Args.Query, args...))
+	if err != nil {
+		return err
+	}
+
+	rw, err := newRowWriter(c.Output, c.stdout)
+	if err != nil {
+		return err
+	}
+
+	c.userAuth = new(auth.None)
+	c.optionalIndex = true
+	if err := c.buildDatabase(); err != nil {
+		return err
+	}
+
+	ctx := sql.NewContext(context.Background(),
+		sql.WithSession(c.newSession()),
+		sql.WithQuery(query),
+	)
+
+	schema, iter, err := c.engine.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	_, err = writeRows(rw, schema, iter)
+	return err
+}
+
+// query returns the query given as arguments or read from the standard
+// input if there are no arguments.
+func (c *Query) query(args []string) (string, error) {
+	query := strings.Join(args, " ")
+	if len(args) == 0 {
+		b, err := ioutil.ReadAll(c.stdin)
+		if err != nil {
+			return "", fmt.Errorf("cannot read query: %s", err)
+		}
+
+		query = string(b)
+	}
+
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return "", fmt.Errorf("no query given")
+	}
+
+	return query, nil
+}
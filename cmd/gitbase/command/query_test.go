@@ -0,0 +1,103 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+	}{
+		{
+			"argument",
+			[]string{"SELECT ref_name, commit_hash FROM refs", "WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b'"},
+			"",
+			"ref_name,commit_hash\n" +
+				"HEAD,dbfab055c70379219cbcf422f05316fdf4e1aed3\n" +
+				"refs/heads/master,dbfab055c70379219cbcf422f05316fdf4e1aed3\n",
+		},
+		{
+			"stdin",
+			nil,
+			"SELECT COUNT(*) AS repos\nFROM repositories;\n",
+			"repos\n5\n",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			tmpDir, err := ioutil.TempDir("", "gitbase")
+			require.NoError(err)
+			defer os.RemoveAll(tmpDir)
+
+			var stdout bytes.Buffer
+			cmd := &Query{
+				Database: Database{
+					CacheSize:   512,
+					Format:      "siva",
+					Bucket:      0,
+					LogLevel:    "info",
+					Directories: []string{"../../../_testdata"},
+					IndexDir:    tmpDir,
+				},
+				Output: CSVFormat,
+				stdin:  strings.NewReader(tt.stdin),
+				stdout: &stdout,
+			}
+
+			require.NoError(cmd.Execute(tt.args))
+			require.Equal(tt.expected, stdout.String())
+		})
+	}
+}
+
+func TestQueryEmpty(t *testing.T) {
+	cmd := &Query{
+		Output: CSVFormat,
+		stdin:  strings.NewReader(" ;\n"),
+		stdout: new(bytes.Buffer),
+	}
+
+	require.Error(t, cmd.Execute(nil))
+}
+
+func TestQueryWithoutIndexDir(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	// the index directory can't be created inside a file
+	file := filepath.Join(tmpDir, "file")
+	require.NoError(ioutil.WriteFile(file, nil, 0644))
+
+	var stdout bytes.Buffer
+	cmd := &Query{
+		Database: Database{
+			CacheSize:   512,
+			Format:      "siva",
+			Bucket:      0,
+			LogLevel:    "info",
+			Directories: []string{"../../../_testdata"},
+			IndexDir:    filepath.Join(file, "index"),
+		},
+		Output: CSVFormat,
+		stdout: &stdout,
+	}
+
+	require.NoError(cmd.Execute([]string{"SELECT COUNT(*) FROM repositories"}))
+	require.Equal("COUNT(*)\n5\n", stdout.String())
+}
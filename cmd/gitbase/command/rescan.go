@@ -0,0 +1,143 @@
+package command
+
+import (
+	"time"
+
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-borges"
+)
+
+var (
+	// RepositoriesGauge describes the number of repositories found in the
+	// last scan of the directories.
+	RepositoriesGauge = discard.NewGauge()
+
+	// RepositoriesAddedCounter describes a metric that accumulates the number
+	// of repositories added to the directories while the server runs.
+	RepositoriesAddedCounter = discard.NewCounter()
+
+	// RepositoriesRemovedCounter describes a metric that accumulates the
+	// number of repositories removed from the directories while the server
+	// runs.
+	RepositoriesRemovedCounter = discard.NewCounter()
+)
+
+// repositoryLister is implemented by the libraries and locations the
+// repositories of a directory are loaded with.
+type repositoryLister interface {
+	Repositories(borges.Mode) (borges.RepositoryIterator, error)
+}
+
+// repositorySource is a directory and the library or location its
+// repositories are loaded with.
+type repositorySource struct {
+	dir    directory
+	lister repositoryLister
+}
+
+// scanRepositories lists the repositories of all the directories and sets
+// their labels. It returns the path of the directory of each repository.
+func (c *Database) scanRepositories() (map[string]string, error) {
+	repos := make(map[string]string)
+	for _, s := range c.sources {
+		iter, err := s.lister.Repositories(borges.ReadOnlyMode)
+		if err != nil {
+			return nil, err
+		}
+
+		err = iter.ForEach(func(r borges.Repository) error {
+			id := r.ID().String()
+			repos[id] = s.dir.Path
+			c.pool.SetLabels(id, s.dir.Labels)
+			return r.Close()
+		})
+		iter.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return repos, nil
+}
+
+// rescanRepositories lists again the repositories of the directories and
+// logs the ones added and removed since the last scan. The libraries already
+// find the repositories added or removed when queries list them, so queries
+// running while the directories change are not affected by the scan.
+func (c *Database) rescanRepositories() error {
+	repos, err := c.scanRepositories()
+	if err != nil {
+		return err
+	}
+
+	var added, removed int
+	for id, path := range repos {
+		if _, ok := c.repositories[id]; !ok {
+			added++
+			logrus.WithFields(logrus.Fields{
+				"id":        id,
+				"directory": path,
+			}).Info("repository added")
+		}
+	}
+
+	for id, path := range c.repositories {
+		if _, ok := repos[id]; !ok {
+			removed++
+			c.pool.SetLabels(id, nil)
+			logrus.WithFields(logrus.Fields{
+				"id":        id,
+				"directory": path,
+			}).Info("repository removed")
+		}
+	}
+
+	c.repositoriesMu.Lock()
+	c.repositories = repos
+	c.repositoriesMu.Unlock()
+
+	RepositoriesGauge.Set(float64(len(repos)))
+	RepositoriesAddedCounter.Add(float64(added))
+	RepositoriesRemovedCounter.Add(float64(removed))
+
+	logrus.WithFields(logrus.Fields{
+		"repositories": len(repos),
+		"added":        added,
+		"removed":      removed,
+	}).Debug("directories rescanned")
+
+	return nil
+}
+
+// directoryRepositories returns the number of repositories found in each
+// directory in the last scan.
+func (c *Database) directoryRepositories() map[string]int {
+	c.repositoriesMu.RLock()
+	defer c.repositoriesMu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, path := range c.repositories {
+		counts[path]++
+	}
+
+	return counts
+}
+
+// watchRepositories rescans the directories every interval until the stop
+// channel is closed.
+func (c *Database) watchRepositories(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.rescanRepositories(); err != nil {
+				logrus.WithField("error", err).Error("unable to rescan directories")
+			}
+		}
+	}
+}
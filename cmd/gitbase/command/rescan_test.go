@@ -0,0 +1,90 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/stretchr/testify/require"
+	git "gopkg.in/src-d/go-git.v4"
+)
+
+func TestRescanRepositories(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	reposDir := filepath.Join(tmpDir, "repos")
+	_, err = git.PlainInit(filepath.Join(reposDir, "foo"), true)
+	require.NoError(err)
+
+	db := &Database{
+		Name:     "gitbase",
+		Format:   "git",
+		Bare:     true,
+		IndexDir: filepath.Join(tmpDir, "index"),
+		configDirectories: []*directoryConfig{
+			{Path: reposDir, Labels: map[string]string{"team": "core"}},
+		},
+		userAuth: new(auth.None),
+	}
+	require.NoError(db.buildDatabase())
+	require.Len(db.repositories, 1)
+
+	var foo string
+	for id := range db.repositories {
+		foo = id
+	}
+	require.Equal(map[string]string{"team": "core"}, db.pool.Labels(foo))
+
+	_, err = git.PlainInit(filepath.Join(reposDir, "bar"), true)
+	require.NoError(err)
+	require.NoError(os.RemoveAll(filepath.Join(reposDir, "foo")))
+
+	require.NoError(db.rescanRepositories())
+	require.Len(db.repositories, 1)
+	require.NotContains(db.repositories, foo)
+	require.Nil(db.pool.Labels(foo))
+
+	for id, path := range db.repositories {
+		require.Equal(reposDir, path)
+		require.Equal(map[string]string{"team": "core"}, db.pool.Labels(id))
+	}
+}
+
+func TestWatchRepositories(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	db := &Database{
+		Name:        "gitbase",
+		Format:      "git",
+		Bare:        true,
+		IndexDir:    filepath.Join(tmpDir, "index"),
+		Directories: []string{tmpDir},
+		userAuth:    new(auth.None),
+	}
+	require.NoError(db.buildDatabase())
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		db.watchRepositories(time.Millisecond, stop)
+		close(done)
+	}()
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow("watcher did not stop")
+	}
+}
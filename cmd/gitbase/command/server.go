@@ -2,11 +2,11 @@ package command
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -20,15 +20,13 @@ import (
 
 	"github.com/src-d/gitbase"
 	"github.com/src-d/gitbase/internal/function"
+	"github.com/src-d/gitbase/internal/pgwire"
 	"github.com/src-d/gitbase/internal/rule"
 
+	"github.com/jessevdk/go-flags"
 	"github.com/opentracing/opentracing-go"
 	"github.com/sirupsen/logrus"
-	"github.com/src-d/go-borges"
-	"github.com/src-d/go-borges/libraries"
-	"github.com/src-d/go-borges/oldsiva"
 	"github.com/src-d/go-borges/plain"
-	"github.com/src-d/go-borges/siva"
 	sqle "github.com/src-d/go-mysql-server"
 	"github.com/src-d/go-mysql-server/auth"
 	"github.com/src-d/go-mysql-server/server"
@@ -37,7 +35,6 @@ import (
 	"github.com/src-d/go-mysql-server/sql/index/pilosa"
 	"github.com/uber/jaeger-client-go/config"
 	"gopkg.in/src-d/go-billy.v4/osfs"
-	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"vitess.io/vitess/go/mysql"
 )
 
@@ -52,39 +49,49 @@ const (
 
 // Server represents the `server` command of gitbase cli tool.
 type Server struct {
-	engine   *sqle.Engine
-	pool     *gitbase.RepositoryPool
-	userAuth auth.Auth
-
-	rootLibrary  *libraries.Libraries
-	plainLibrary *plain.Library
-	sharedCache  cache.Object
-
-	Name           string         `long:"db" default:"gitbase" description:"Database name"`
-	Version        string         // Version of the application.
-	Directories    []string       `short:"d" long:"directories" description:"Path where standard git repositories are located, multiple directories can be defined."`
-	Format         string         `long:"format" default:"git" choice:"git" choice:"siva" description:"Library format"`
-	Bucket         int            `long:"bucket" default:"2" description:"Bucketing level to use with siva libraries"`
-	Bare           bool           `long:"bare" description:"Sets the library to use bare git repositories, used only with git format libraries"`
-	NonBare        bool           `long:"non-bare" description:"Sets the library to use non bare git repositories, used only with git format libraries"`
-	NonRooted      bool           `long:"non-rooted" description:"Disables treating siva files as rooted repositories"`
-	Host           string         `long:"host" default:"localhost" description:"Host where the server is going to listen"`
-	Port           int            `short:"p" long:"port" default:"3306" description:"Port where the server is going to listen"`
-	User           string         `short:"u" long:"user" default:"root" description:"User name used for connection"`
-	Password       string         `short:"P" long:"password" default:"" description:"Password used for connection"`
-	UserFile       string         `short:"U" long:"user-file" env:"GITBASE_USER_FILE" default:"" description:"JSON file with credentials list"`
-	ConnTimeout    int            `short:"t" long:"timeout" env:"GITBASE_CONNECTION_TIMEOUT" description:"Timeout in seconds used for connections"`
-	IndexDir       string         `short:"i" long:"index" default:"/var/lib/gitbase/index" description:"Directory where the gitbase indexes information will be persisted." env:"GITBASE_INDEX_DIR"`
-	CacheSize      cache.FileSize `long:"cache" default:"512" description:"Object cache size in megabytes" env:"GITBASE_CACHESIZE_MB"`
-	Parallelism    uint           `long:"parallelism" description:"Maximum number of parallel threads per table. By default, it's the number of CPU cores. 0 means default, 1 means disabled."`
-	DisableSquash  bool           `long:"no-squash" description:"Disables the table squashing."`
-	TraceEnabled   bool           `long:"trace" env:"GITBASE_TRACE" description:"Enables jaeger tracing"`
-	MetricsEnabled bool           `long:"metrics" env:"GITBASE_METRICS" description:"Enables prometheus metrics"`
-	MetricsPort    int            `long:"metrics-port" env:"GITBASE_METRICS_PORT" default:"2112" description:"Port where the server is going to expose prometheus metrics"`
-	ReadOnly       bool           `short:"r" long:"readonly" description:"Only allow read queries. This disables creating and deleting indexes as well. Cannot be used with --user-file." env:"GITBASE_READONLY"`
-	SkipGitErrors  bool           // SkipGitErrors disables failing when Git errors are found.
-	Verbose        bool           `short:"v" description:"Activates the verbose mode (equivalent to debug logging level), overwriting any passed logging level"`
-	LogLevel       string         `long:"log-level" env:"GITBASE_LOG_LEVEL" choice:"info" choice:"debug" choice:"warning" choice:"error" choice:"fatal" default:"info" description:"logging level; ignored if using -v verbose flag"`
+	Database
+
+	command *flags.Command
+	users   []*userConfig
+
+	// started is the time the server was started, and ready is set to 1
+	// when it's ready to accept queries.
+	started time.Time
+	ready   int32
+	conns   connections
+	pgSrv   *pgwire.Server
+	httpAPI *queryHandler
+
+	Host            string        `long:"host" default:"localhost" description:"Host where the server is going to listen"`
+	Port            int           `short:"p" long:"port" default:"3306" description:"Port where the server is going to listen"`
+	User            string        `short:"u" long:"user" default:"root" description:"User name used for connection"`
+	Password        string        `short:"P" long:"password" default:"" description:"Password used for connection"`
+	UserFile        string        `short:"U" long:"user-file" env:"GITBASE_USER_FILE" default:"" description:"JSON file with credentials list"`
+	ConnTimeout     int           `short:"t" long:"timeout" env:"GITBASE_CONNECTION_TIMEOUT" description:"Timeout in seconds used for connections"`
+	TraceEnabled    bool          `long:"trace" env:"GITBASE_TRACE" description:"Enables jaeger tracing"`
+	MetricsEnabled  bool          `long:"metrics" env:"GITBASE_METRICS" description:"Enables prometheus metrics"`
+	MetricsPort     int           `long:"metrics-port" env:"GITBASE_METRICS_PORT" default:"2112" description:"Port where the server is going to expose prometheus metrics"`
+	HTTPEnabled     bool          `long:"http" env:"GITBASE_HTTP" description:"Enables the HTTP API to run queries"`
+	HTTPPort        int           `long:"http-port" env:"GITBASE_HTTP_PORT" default:"8080" description:"Port where the server is going to expose the HTTP API"`
+	PostgresEnabled bool          `long:"postgres" env:"GITBASE_POSTGRES" description:"Enables the PostgreSQL wire protocol listener"`
+	PostgresPort    int           `long:"postgres-port" env:"GITBASE_POSTGRES_PORT" default:"5432" description:"Port where the server is going to listen for PostgreSQL clients"`
+	ReadOnly        bool          `short:"r" long:"readonly" description:"Only allow read queries. This disables creating and deleting indexes as well. Cannot be used with --user-file." env:"GITBASE_READONLY"`
+	Config          string        `short:"c" long:"config" env:"GITBASE_CONFIG" description:"YAML file with the configuration of the server. Flags and environment variables take precedence over it."`
+	RescanInterval  time.Duration `long:"rescan-interval" env:"GITBASE_RESCAN_INTERVAL" description:"Interval to rescan the directories, such as 1m, logging the repositories added and removed and updating their labels and metrics. Disabled by default."`
+	TLSCert         string        `long:"tls-cert" env:"GITBASE_TLS_CERT" description:"PEM file with the TLS certificate of the server. Enables TLS connections. Certificates are reloaded on SIGHUP."`
+	TLSKey          string        `long:"tls-key" env:"GITBASE_TLS_KEY" description:"PEM file with the private key of the TLS certificate"`
+	TLSCA           string        `long:"tls-ca" env:"GITBASE_TLS_CA" description:"PEM file with the certificate authorities used to verify the certificates of the clients. Clients must present a certificate signed by them."`
+	RequireSecure   bool          `long:"require-secure-transport" env:"GITBASE_REQUIRE_SECURE_TRANSPORT" description:"Rejects the clients that don't use TLS"`
+	Socket          string        `long:"socket" env:"GITBASE_SOCKET" description:"Path of a Unix socket where the server is also going to listen"`
+	SocketMode      string        `long:"socket-mode" env:"GITBASE_SOCKET_MODE" default:"0660" description:"Permissions of the Unix socket file"`
+	NoTCP           bool          `long:"no-tcp" env:"GITBASE_NO_TCP" description:"Disables the TCP listener, so the server only listens on the Unix socket"`
+}
+
+// SetCommand sets the command parsed from the command line, used to know
+// which options were given explicitly and must not be overridden by the
+// configuration file.
+func (c *Server) SetCommand(cmd *flags.Command) {
+	c.command = cmd
 }
 
 type jaegerLogrus struct {
@@ -128,31 +135,35 @@ func NewDatabaseEngine(
 // Execute starts a new gitbase server based on provided configuration, it
 // honors the go-flags.Commander interface.
 func (c *Server) Execute(args []string) error {
-	if c.Verbose {
-		logrus.SetLevel(logrus.DebugLevel)
+	c.started = time.Now()
+	if c.Config != "" {
+		cfg, err := loadConfig(c.Config)
+		if err != nil {
+			return err
+		}
+
+		if err := c.applyConfig(cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := c.setupLogging(); err != nil {
+		return err
 	}
 
-	if c.Bare && c.NonBare {
-		return fmt.Errorf("cannot use both --bare and --non-bare")
+	if err := c.checkTLS(); err != nil {
+		return err
 	}
 
-	// info is the default log level
-	if c.LogLevel != "info" {
-		if c.Verbose {
-			logrus.Infof(
-				"ignoring passed '%s' log-level, using requesed '-v' verbose flag instead",
-				c.LogLevel,
-			)
-		} else {
-			level, err := logrus.ParseLevel(c.LogLevel)
-			if err != nil {
-				return fmt.Errorf("cannot parse log level: %s", err.Error())
-			}
-			logrus.SetLevel(level)
-		}
+	if c.NoTCP && c.Socket == "" {
+		return fmt.Errorf("cannot use --no-tcp without --socket")
+	}
+
+	socketMode, err := parseSocketMode(c.SocketMode)
+	if err != nil {
+		return err
 	}
 
-	var err error
 	if c.UserFile != "" {
 		if c.ReadOnly {
 			return fmt.Errorf("cannot use both --user-file and --readonly")
@@ -162,6 +173,12 @@ func (c *Server) Execute(args []string) error {
 		if err != nil {
 			return err
 		}
+	} else if len(c.users) > 0 {
+		if c.ReadOnly {
+			return fmt.Errorf("cannot use both users of the config file and --readonly")
+		}
+
+		c.userAuth = configAuth(c.users)
 	} else {
 		permissions := auth.AllPermissions
 		if c.ReadOnly {
@@ -171,6 +188,23 @@ func (c *Server) Execute(args []string) error {
 	}
 
 	c.userAuth = auth.NewAudit(c.userAuth, auth.NewAuditLog(logrus.StandardLogger()))
+
+	// The metrics server is started before loading the repositories, which
+	// may take a while, so it can report the server is alive but not ready.
+	if c.MetricsEnabled {
+		metricsSrv := enableMetrics(c.Host, c.MetricsPort)
+		metricsSrv.Handler = c.newStatusHandler(metricsSrv.Handler)
+		defer func() {
+			if err := metricsSrv.Shutdown(context.Background()); err != nil {
+				logrus.Errorln(err)
+			}
+		}()
+		go func() {
+			logrus.Infof("metrics server started and listening on %s", metricsSrv.Addr)
+			logrus.Errorln(metricsSrv.ListenAndServe())
+		}()
+	}
+
 	if err := c.buildDatabase(); err != nil {
 		logrus.WithField("error", err).Fatal("unable to initialize database engine")
 		return err
@@ -209,211 +243,154 @@ func (c *Server) Execute(args []string) error {
 
 	hostString := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
 	timeout := time.Duration(c.ConnTimeout) * time.Second
-	s, err := server.NewServer(
-		server.Config{
-			Protocol:         "tcp",
-			Address:          hostString,
-			Auth:             c.userAuth,
-			Tracer:           tracer,
-			ConnReadTimeout:  timeout,
-			ConnWriteTimeout: timeout,
-		},
-		c.engine,
-		gitbase.NewSessionBuilder(c.pool,
-			gitbase.WithSkipGitErrors(c.SkipGitErrors),
-		),
-	)
-	if err != nil {
-		return err
-	}
-
-	if c.MetricsEnabled {
-		metricsSrv := enableMetrics(c.Host, c.MetricsPort)
-		defer func() {
-			if err := metricsSrv.Shutdown(context.Background()); err != nil {
-				logrus.Errorln(err)
-			}
-		}()
-		go func() {
-			logrus.Infof("metrics server started and listening on %s", metricsSrv.Addr)
-			logrus.Errorln(metricsSrv.ListenAndServe())
-		}()
-	}
-
-	logrus.Infof("server started and listening on %s:%d", c.Host, c.Port)
-	return s.Start()
-}
-
-func (c *Server) buildDatabase() error {
-	if c.engine == nil {
-		c.engine = NewDatabaseEngine(
-			c.userAuth,
-			c.Version,
-			int(c.Parallelism),
-			!c.DisableSquash,
-		)
-	}
-
-	c.sharedCache = cache.NewObjectLRU(c.CacheSize * cache.MiByte)
-
-	c.rootLibrary = libraries.New(nil)
-	c.pool = gitbase.NewRepositoryPool(c.sharedCache, c.rootLibrary)
-
-	if err := c.addDirectories(); err != nil {
-		return err
-	}
-
-	c.engine.AddDatabase(gitbase.NewDatabase(c.Name, c.pool))
-	c.engine.AddDatabase(sql.NewInformationSchemaDatabase(c.engine.Catalog))
-	c.engine.Catalog.SetCurrentDatabase(c.Name)
-	logrus.WithField("db", c.Name).Debug("registered database to catalog")
-
-	c.engine.Catalog.MustRegister(function.Functions...)
-	logrus.Debug("registered all available functions in catalog")
-
-	if err := c.registerDrivers(); err != nil {
-		return err
-	}
-
-	if !c.DisableSquash {
-		logrus.Info("squash tables rule is enabled")
-	} else {
-		logrus.Warn("squash tables rule is disabled")
-	}
-
-	return c.engine.Init()
-}
-
-func (c *Server) registerDrivers() error {
-	if err := os.MkdirAll(c.IndexDir, 0755); err != nil {
-		return err
-	}
-
-	logrus.Debug("created index storage")
+	var tlsConfig *tls.Config
+	if c.TLSCert != "" {
+		certs, err := newTLSCertificates(c.TLSCert, c.TLSKey, c.TLSCA)
+		if err != nil {
+			return err
+		}
 
-	c.engine.Catalog.RegisterIndexDriver(
-		pilosa.NewDriver(filepath.Join(c.IndexDir, pilosa.DriverID)),
-	)
-	logrus.Debug("registered pilosa index driver")
+		stop := make(chan struct{})
+		defer close(stop)
+		go certs.reloadOnSignal(stop)
 
-	return nil
-}
-
-func (c *Server) addDirectories() error {
-	if len(c.Directories) == 0 {
-		logrus.Error("at least one folder should be provided.")
+		tlsConfig = certs.config()
+		logrus.Info("TLS enabled")
 	}
 
-	defaultBare := bareAuto
-	switch {
-	case c.Bare:
-		defaultBare = bareOn
-	case c.NonBare:
-		defaultBare = bareOff
+	cfg := server.Config{
+		Auth:             c.userAuth,
+		Tracer:           tracer,
+		ConnReadTimeout:  timeout,
+		ConnWriteTimeout: timeout,
 	}
+	sb := gitbase.NewSessionBuilder(c.pool, c.sessionOptions()...)
 
-	for _, d := range c.Directories {
-		dir := directory{
-			Path:   d,
-			Format: c.Format,
-			Bare:   defaultBare,
-			Bucket: c.Bucket,
-			Rooted: !c.NonRooted,
-		}
-
-		dir, err := parseDirectory(dir)
+	var s *server.Server
+	if !c.NoTCP {
+		cfg.Protocol = "tcp"
+		cfg.Address = hostString
+		s, err = newMySQLServer(cfg, c.engine, sb, mysqlServerOptions{
+			tlsConfig:     tlsConfig,
+			requireSecure: c.RequireSecure,
+			conns:         &c.conns,
+		})
 		if err != nil {
 			return err
 		}
+	}
 
-		err = c.addDirectory(dir)
+	var socketSrv *server.Server
+	if c.Socket != "" {
+		// Connections through the socket don't leave the host, so they are
+		// secure even without TLS.
+		cfg.Address = c.Socket
+		socketSrv, err = newSocketServer(cfg, c.engine, sb, mysqlServerOptions{
+			tlsConfig: tlsConfig,
+			conns:     &c.conns,
+		}, socketMode)
 		if err != nil {
 			return err
 		}
 	}
 
-	repos, err := c.rootLibrary.Repositories(borges.ReadOnlyMode)
-	if err != nil {
-		return err
+	if c.RescanInterval > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go c.watchRepositories(c.RescanInterval, stop)
+		logrus.Infof("rescanning directories every %s", c.RescanInterval)
 	}
-	defer repos.Close()
-
-	return repos.ForEach(func(r borges.Repository) error {
-		id := r.ID().String()
-		logrus.WithField("id", id).Debug("repository added")
-		return r.Close()
-	})
-}
 
-func (c *Server) addDirectory(d directory) error {
-	if d.Format == "siva" {
-		var lib borges.Library
-		var err error
-
-		if d.Rooted {
-			sivaOpts := &siva.LibraryOptions{
-				Transactional: true,
-				RootedRepo:    d.Rooted,
-				Cache:         c.sharedCache,
-				Bucket:        d.Bucket,
-				Performance:   true,
-				RegistryCache: 100000,
+	if c.HTTPEnabled {
+		c.httpAPI = newQueryHandler(
+			c.engine,
+			c.pool,
+			c.userAuth,
+			hostString,
+			c.sessionOptions()...,
+		)
+		httpSrv := newHTTPServer(c.Host, c.HTTPPort, c.httpAPI)
+		defer func() {
+			if err := httpSrv.Shutdown(context.Background()); err != nil {
+				logrus.Errorln(err)
 			}
+		}()
+		go func() {
+			logrus.Infof("http server started and listening on %s", httpSrv.Addr)
+			logrus.Errorln(httpSrv.ListenAndServe())
+		}()
+	}
 
-			lib, err = siva.NewLibrary(d.Path, osfs.New(d.Path), sivaOpts)
-			if err != nil {
-				return err
-			}
-		} else {
-			sivaOpts := &oldsiva.LibraryOptions{
-				Cache:         c.sharedCache,
-				Bucket:        d.Bucket,
-				RegistryCache: 100000,
+	if c.PostgresEnabled {
+		pgSrv, err := pgwire.NewServer(
+			pgwire.Config{
+				Address:                net.JoinHostPort(c.Host, strconv.Itoa(c.PostgresPort)),
+				ConnReadTimeout:        timeout,
+				ConnWriteTimeout:       timeout,
+				TLSConfig:              tlsConfig,
+				RequireSecureTransport: c.RequireSecure,
+			},
+			c.engine,
+			func(user, client string, connID uint32) sql.Session {
+				opts := append(c.sessionOptions(), gitbase.WithBaseSession(
+					sql.NewSession(hostString, client, user, connID),
+				))
+				return gitbase.NewSession(c.pool, opts...)
+			},
+		)
+		if err != nil {
+			return err
+		}
+		c.pgSrv = pgSrv
+		defer func() {
+			if err := pgSrv.Close(); err != nil {
+				logrus.Errorln(err)
 			}
-
-			lib, err = oldsiva.NewLibrary(d.Path, osfs.New(d.Path), sivaOpts)
-			if err != nil {
-				return err
+		}()
+		go func() {
+			logrus.Infof("postgres server started and listening on %s", pgSrv.Addr())
+			if err := pgSrv.Start(); err != nil {
+				logrus.Errorln(err)
 			}
-		}
+		}()
+	}
 
-		err = c.rootLibrary.Add(lib)
-		if err != nil {
-			return err
+	c.setReady()
+	if socketSrv != nil {
+		if s == nil {
+			logrus.Infof("server started and listening on %s", c.Socket)
+			return socketSrv.Start()
 		}
 
-		return nil
+		defer socketSrv.Close()
+		go func() {
+			logrus.Infof("server listening on %s", c.Socket)
+			if err := socketSrv.Start(); err != nil {
+				logrus.Errorln(err)
+			}
+		}()
 	}
 
-	bare, err := discoverBare(d)
-	if err != nil {
-		return err
-	}
+	logrus.Infof("server started and listening on %s:%d", c.Host, c.Port)
+	return s.Start()
+}
 
-	plainOpts := &plain.LocationOptions{
-		Cache:       c.sharedCache,
-		Performance: true,
-		Bare:        bare,
+// checkTLS checks the TLS options that must be given together.
+func (c *Server) checkTLS() error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
 	}
 
-	if c.plainLibrary == nil {
-		c.plainLibrary = plain.NewLibrary(borges.LibraryID("plain"))
-		err := c.rootLibrary.Add(c.plainLibrary)
-		if err != nil {
-			return err
+	if c.TLSCert == "" {
+		if c.TLSCA != "" {
+			return fmt.Errorf("cannot use --tls-ca without --tls-cert")
 		}
-	}
 
-	loc, err := plain.NewLocation(
-		borges.LocationID(d.Path),
-		osfs.New(d.Path),
-		plainOpts)
-	if err != nil {
-		return err
+		if c.RequireSecure {
+			return fmt.Errorf("cannot use --require-secure-transport without --tls-cert")
+		}
 	}
 
-	c.plainLibrary.AddLocation(loc)
-
 	return nil
 }
 
@@ -431,6 +408,7 @@ type directory struct {
 	Bucket int
 	Rooted bool
 	Bare   bareOpt
+	Labels map[string]string
 }
 
 var (
@@ -603,6 +581,23 @@ func enableMetrics(host string, port int) *http.Server {
 		"duration",
 	})
 
+	// Repositories metrics
+	RepositoriesGauge = prometheus.NewGaugeFrom(promopts.GaugeOpts{
+		Namespace: "gitbase",
+		Subsystem: "repositories",
+		Name:      "total",
+	}, []string{})
+	RepositoriesAddedCounter = prometheus.NewCounterFrom(promopts.CounterOpts{
+		Namespace: "gitbase",
+		Subsystem: "repositories",
+		Name:      "added_counter",
+	}, []string{})
+	RepositoriesRemovedCounter = prometheus.NewCounterFrom(promopts.CounterOpts{
+		Namespace: "gitbase",
+		Subsystem: "repositories",
+		Name:      "removed_counter",
+	}, []string{})
+
 	//Uast metrics
 	function.UastHitCacheCounter = prometheus.NewCounterFrom(promopts.CounterOpts{
 		Namespace: "gitbase",
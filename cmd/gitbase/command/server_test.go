@@ -284,14 +284,14 @@ func TestCache(t *testing.T) {
 		require.NoError(os.RemoveAll(tmpDir))
 	}()
 
-	server := &Server{
+	server := &Server{Database: Database{
 		CacheSize:   512,
 		Format:      "siva",
 		Bucket:      0,
 		LogLevel:    "debug",
 		Directories: []string{"../../../_testdata"},
 		IndexDir:    tmpDir,
-	}
+	}}
 
 	err = server.buildDatabase()
 	require.NoError(err)
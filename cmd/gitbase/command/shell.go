@@ -0,0 +1,430 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	ShellDescription = "Starts an interactive shell to run queries"
+	ShellHelp        = ShellDescription + "\n\n" +
+		"Queries can span several lines and are run when they end with ';',\n" +
+		"or with '\\G' to print every row with a line for each column. The\n" +
+		"names of tables, columns and functions are completed with tab.\n" +
+		"Ctrl-C interrupts the running query. The history of queries is kept\n" +
+		"in memory and it's lost when the shell exits."
+
+	shellPrompt        = "gitbase> "
+	continuationPrompt = "      -> "
+)
+
+const shellUsage = `List of shell commands:
+help    (\h) Display this help.
+exit    (\q) Exit the shell. Same as quit.
+clear   (\c) Clear the current query, when added at the end of a line.
+
+Queries are run when a line ends with ';' or with '\G' to print every row
+with a line for each column. Use tab to complete the names of tables,
+columns and functions, and the arrow keys to go through the history, which
+is kept in memory and lost when the shell exits. Ctrl-C interrupts the
+running query.
+`
+
+// Shell represents the `shell` command of gitbase cli tool.
+type Shell struct {
+	Database
+
+	stdin  io.Reader
+	stdout io.Writer
+
+	session sql.Session
+	pid     uint64
+}
+
+// Execute starts a new interactive shell with a gitbase database, it
+// honors the go-flags.Commander interface.
+func (c *Shell) Execute(args []string) error {
+	if err := c.setupLogging(); err != nil {
+		return err
+	}
+
+	if c.stdin == nil {
+		c.stdin = os.Stdin
+	}
+
+	if c.stdout == nil {
+		c.stdout = os.Stdout
+	}
+
+	c.userAuth = new(auth.None)
+	c.optionalIndex = true
+	if err := c.buildDatabase(); err != nil {
+		return err
+	}
+
+	c.session = c.newSession()
+
+	lines, out, restore, err := c.newLineReader()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	return c.run(lines, out)
+}
+
+// lineReader reads the lines typed in the shell.
+type lineReader interface {
+	ReadLine() (string, error)
+	SetPrompt(prompt string)
+	// Suspend stops reading lines while a query runs, so the terminal
+	// sends an interrupt signal on Ctrl-C, and returns the function to
+	// call before reading lines again.
+	Suspend() (resume func())
+}
+
+// newLineReader returns the lineReader used to read the lines of the shell
+// and the writer where its output is written. If the standard input is a
+// terminal, it's set in raw mode to edit the lines with history and
+// completion, and the returned function restores it.
+func (c *Shell) newLineReader() (lineReader, io.Writer, func(), error) {
+	f, ok := c.stdin.(*os.File)
+	if !ok || !terminal.IsTerminal(int(f.Fd())) {
+		r := &plainLineReader{bufio.NewReader(c.stdin)}
+		return r, c.stdout, func() {}, nil
+	}
+
+	fd := int(f.Fd())
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	t := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{c.stdin, c.stdout}, shellPrompt)
+	if width, height, err := terminal.GetSize(fd); err == nil && width > 0 {
+		_ = t.SetSize(width, height)
+	}
+
+	completer, err := newCompleter(c.engine.Catalog, c.Name)
+	if err != nil {
+		_ = terminal.Restore(fd, state)
+		return nil, nil, nil, err
+	}
+
+	completer.list = func(candidates []string) {
+		fmt.Fprintln(t, strings.Join(candidates, "  "))
+	}
+	t.AutoCompleteCallback = completer.complete
+
+	// the terminal is in raw mode, so the logs must be written through it
+	// to have the lines properly ended.
+	logrus.SetOutput(t)
+
+	restore := func() {
+		logrus.SetOutput(os.Stderr)
+		_ = terminal.Restore(fd, state)
+	}
+
+	return &terminalLineReader{t, fd, state}, t, restore, nil
+}
+
+// terminalLineReader reads the lines of a terminal. Pasted lines are read
+// as if they were typed.
+type terminalLineReader struct {
+	*terminal.Terminal
+	fd    int
+	state *terminal.State
+}
+
+func (r *terminalLineReader) ReadLine() (string, error) {
+	line, err := r.Terminal.ReadLine()
+	if err == terminal.ErrPasteIndicator {
+		err = nil
+	}
+
+	return line, err
+}
+
+func (r *terminalLineReader) Suspend() func() {
+	_ = terminal.Restore(r.fd, r.state)
+	return func() {
+		_, _ = terminal.MakeRaw(r.fd)
+	}
+}
+
+// plainLineReader reads the lines of a reader that is not a terminal, such
+// as a pipe, so prompts are not written.
+type plainLineReader struct {
+	r *bufio.Reader
+}
+
+func (r *plainLineReader) ReadLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (*plainLineReader) SetPrompt(string) {}
+
+func (*plainLineReader) Suspend() func() { return func() {} }
+
+// run reads and runs queries until the input ends or the shell is exited.
+func (c *Shell) run(lines lineReader, out io.Writer) error {
+	fmt.Fprintf(out, "Welcome to the gitbase shell. Type 'help' or '\\h' for help.\n\n")
+
+	var buf []string
+	for {
+		if len(buf) == 0 {
+			lines.SetPrompt(shellPrompt)
+		} else {
+			lines.SetPrompt(continuationPrompt)
+		}
+
+		line, err := lines.ReadLine()
+		if err == io.EOF {
+			fmt.Fprintln(out, "Bye")
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(buf) == 0 {
+			command := strings.ToLower(strings.TrimSpace(line))
+			switch strings.TrimSuffix(command, ";") {
+			case "":
+				continue
+			case "exit", "quit", `\q`:
+				fmt.Fprintln(out, "Bye")
+				return nil
+			case "help", `\h`, `\?`:
+				fmt.Fprint(out, shellUsage)
+				continue
+			}
+		}
+
+		buf = append(buf, line)
+		query := strings.TrimSpace(strings.Join(buf, "\n"))
+
+		var vertical bool
+		switch {
+		case strings.HasSuffix(query, `\c`):
+			buf = nil
+			continue
+		case strings.HasSuffix(query, `\G`):
+			query = strings.TrimSuffix(query, `\G`)
+			vertical = true
+		case strings.HasSuffix(query, ";"):
+			query = strings.TrimSuffix(query, ";")
+		default:
+			continue
+		}
+
+		buf = nil
+		if strings.TrimSpace(query) == "" {
+			fmt.Fprintln(out, "ERROR: no query specified")
+			continue
+		}
+
+		resume := lines.Suspend()
+		err = c.runQuery(out, query, vertical)
+		resume()
+		if err != nil {
+			fmt.Fprintf(out, "ERROR: %s\n", err)
+		}
+	}
+}
+
+// runQuery runs a query and writes its rows, followed by the number of
+// rows and the time it took. The query is canceled if the shell receives
+// an interrupt signal while it runs.
+func (c *Shell) runQuery(out io.Writer, query string, vertical bool) error {
+	cctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-cctx.Done():
+		}
+	}()
+
+	c.pid++
+	ctx := sql.NewContext(cctx,
+		sql.WithSession(c.session),
+		sql.WithPid(c.pid),
+		sql.WithQuery(query),
+	)
+
+	format := TableFormat
+	if vertical {
+		format = VerticalFormat
+	}
+
+	rw, err := newRowWriter(format, out)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	schema, iter, err := c.engine.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	n, err := writeRows(rw, schema, iter)
+	if err != nil {
+		if cctx.Err() != nil {
+			return fmt.Errorf("query interrupted")
+		}
+
+		return err
+	}
+
+	elapsed := time.Since(start).Seconds()
+	switch {
+	case len(schema) == 0:
+		fmt.Fprintf(out, "Query OK (%.2f sec)\n\n", elapsed)
+	case n == 0:
+		fmt.Fprintf(out, "Empty set (%.2f sec)\n\n", elapsed)
+	case n == 1:
+		fmt.Fprintf(out, "1 row in set (%.2f sec)\n\n", elapsed)
+	default:
+		fmt.Fprintf(out, "%d rows in set (%.2f sec)\n\n", n, elapsed)
+	}
+
+	return nil
+}
+
+// completer completes the names of the tables, columns and functions of a
+// database.
+type completer struct {
+	// names are the sorted names of all tables, columns and functions.
+	names []string
+	// columns are the sorted names of the columns of every table.
+	columns map[string][]string
+	// list is called to show the candidates when there is more than one
+	// and none of them can be completed any further.
+	list func(candidates []string)
+}
+
+func newCompleter(catalog *sql.Catalog, db string) (*completer, error) {
+	database, err := catalog.Database(db)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &completer{columns: make(map[string][]string)}
+	names := make(map[string]struct{})
+	for name, table := range database.Tables() {
+		names[name] = struct{}{}
+		for _, col := range table.Schema() {
+			names[col.Name] = struct{}{}
+			c.columns[name] = append(c.columns[name], col.Name)
+		}
+		sort.Strings(c.columns[name])
+	}
+
+	for name := range catalog.FunctionRegistry {
+		names[name] = struct{}{}
+	}
+
+	for name := range names {
+		c.names = append(c.names, name)
+	}
+	sort.Strings(c.names)
+
+	return c, nil
+}
+
+// complete completes the word before pos in the line when tab is pressed.
+// A word prefixed by a table name and a dot is completed with the columns
+// of the table. It honors the terminal.Terminal AutoCompleteCallback.
+func (c *completer) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	start := pos
+	for start > 0 && isNameChar(line[start-1]) {
+		start--
+	}
+
+	word := line[start:pos]
+	names := c.names
+	if i := strings.LastIndexByte(word, '.'); i >= 0 {
+		names = c.columns[strings.ToLower(word[:i])]
+		start += i + 1
+		word = word[i+1:]
+	}
+
+	var candidates []string
+	prefix := strings.ToLower(word)
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return line, pos, true
+	}
+
+	completion := commonPrefix(candidates)
+	if len(candidates) > 1 && len(completion) == len(word) {
+		if c.list != nil {
+			c.list(candidates)
+		}
+		return line, pos, true
+	}
+
+	if len(candidates) == 1 && (pos == len(line) || line[pos] != ' ') {
+		completion += " "
+	}
+
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+func isNameChar(b byte) bool {
+	return b == '_' || b == '.' ||
+		(b >= 'a' && b <= 'z') ||
+		(b >= 'A' && b <= 'Z') ||
+		(b >= '0' && b <= '9')
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		i := 0
+		for i < len(prefix) && i < len(w) && prefix[i] == w[i] {
+			i++
+		}
+		prefix = prefix[:i]
+	}
+
+	return prefix
+}
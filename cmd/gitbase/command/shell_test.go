@@ -0,0 +1,167 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func setupShell(t *testing.T, stdin string) (*Shell, *bytes.Buffer, func()) {
+	t.Helper()
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+
+	var stdout bytes.Buffer
+	shell := &Shell{
+		Database: Database{
+			Name:        "gitbase",
+			CacheSize:   512,
+			Format:      "siva",
+			Bucket:      0,
+			LogLevel:    "info",
+			Directories: []string{"../../../_testdata"},
+			IndexDir:    tmpDir,
+		},
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+	}
+
+	return shell, &stdout, func() {
+		require.NoError(os.RemoveAll(tmpDir))
+	}
+}
+
+func TestShell(t *testing.T) {
+	require := require.New(t)
+
+	input := "help\n" +
+		"SELECT ref_name\n" +
+		"FROM refs\n" +
+		"WHERE repository_id = '015da2f4-6d89-7ec8-5ac9-a38329ea875b';\n" +
+		"SELECT 1 AS one, 'foo' AS foo\\G\n" +
+		"SELECT * FROM nope;\n" +
+		"SELECT 1 \\c\n" +
+		"exit\n" +
+		"SELECT 2;\n"
+
+	shell, stdout, cleanup := setupShell(t, input)
+	defer cleanup()
+
+	require.NoError(shell.Execute(nil))
+
+	output := regexp.MustCompile(`\(\d+\.\d+ sec\)`).
+		ReplaceAllString(stdout.String(), "(0.00 sec)")
+
+	expected := "Welcome to the gitbase shell. Type 'help' or '\\h' for help.\n\n" +
+		shellUsage +
+		"+-------------------+\n" +
+		"| ref_name          |\n" +
+		"+-------------------+\n" +
+		"| HEAD              |\n" +
+		"| refs/heads/master |\n" +
+		"+-------------------+\n" +
+		"2 rows in set (0.00 sec)\n\n" +
+		"*************************** 1. row ***************************\n" +
+		"one: 1\n" +
+		"foo: foo\n" +
+		"1 row in set (0.00 sec)\n\n" +
+		"ERROR: table not found: nope\n" +
+		"Bye\n"
+
+	require.Equal(expected, output)
+}
+
+func TestShellInterrupt(t *testing.T) {
+	require := require.New(t)
+
+	shell, stdout, cleanup := setupShell(t, "")
+	defer cleanup()
+
+	// keep the test from being killed if the signal arrives when the
+	// query is not running.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	shell.userAuth = new(auth.None)
+	require.NoError(shell.buildDatabase())
+	shell.session = shell.newSession()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+
+	start := time.Now()
+	err := shell.runQuery(stdout, "SELECT SLEEP(0.2) FROM refs", false)
+	require.EqualError(err, "query interrupted")
+	require.True(time.Since(start) < 2*time.Second)
+
+	require.NoError(shell.runQuery(stdout, "SELECT 1", false))
+	require.Contains(stdout.String(), "1 row in set")
+}
+
+func TestCompleter(t *testing.T) {
+	shell, _, cleanup := setupShell(t, "")
+	defer cleanup()
+
+	shell.userAuth = new(auth.None)
+	require.NoError(t, shell.buildDatabase())
+
+	c, err := newCompleter(shell.engine.Catalog, shell.Name)
+	require.NoError(t, err)
+
+	var listed []string
+	c.list = func(candidates []string) {
+		listed = candidates
+	}
+
+	testCases := []struct {
+		line         string
+		pos          int
+		expectedLine string
+		expectedPos  int
+		listed       []string
+	}{
+		{"SELECT * FROM ref_c", 19, "SELECT * FROM ref_commits ", 26, nil},
+		{"SELECT commit_file_st FROM commits", 21, "SELECT commit_file_stats FROM commits", 24, nil},
+		{"SELECT * FROM remote_fe", 23, "SELECT * FROM remote_fetch_", 27, nil},
+		{"SELECT * FROM remote_fetch_", 27, "SELECT * FROM remote_fetch_", 27, []string{"remote_fetch_refspec", "remote_fetch_url"}},
+		{"SELECT refs.ref_ FROM refs", 16, "SELECT refs.ref_name FROM refs", 20, nil},
+		{"SELECT REFS.Com", 15, "SELECT REFS.commit_hash ", 24, nil},
+		{"SELECT xyz", 10, "SELECT xyz", 10, nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.line, func(t *testing.T) {
+			require := require.New(t)
+			listed = nil
+
+			line, pos, ok := c.complete(tt.line, tt.pos, '\t')
+			require.True(ok)
+			require.Equal(tt.expectedLine, line)
+			require.Equal(tt.expectedPos, pos)
+			require.Equal(tt.listed, listed)
+		})
+	}
+
+	_, _, ok := c.complete("SELECT", 6, 'a')
+	require.False(t, ok)
+}
+
+func TestCommonPrefix(t *testing.T) {
+	require.Equal(t, "ref", commonPrefix([]string{"refs", "ref_name", "ref_commits"}))
+	require.Equal(t, "", commonPrefix([]string{"refs", "blobs"}))
+	require.Equal(t, "refs", commonPrefix([]string{"refs"}))
+}
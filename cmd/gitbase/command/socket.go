@@ -0,0 +1,86 @@
+package command
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/server"
+)
+
+// parseSocketMode parses the octal permissions of the socket file.
+func parseSocketMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid socket mode %q, it must be an octal number such as 0660", mode)
+	}
+
+	return os.FileMode(m), nil
+}
+
+// withUmask runs fn with the given file mode creation mask, so the files
+// it creates have the right permissions from the start. The mask is
+// process wide, so it must only be used while no other files are being
+// created.
+func withUmask(mask int, fn func() error) error {
+	old := syscall.Umask(mask)
+	defer syscall.Umask(old)
+	return fn()
+}
+
+// removeStaleSocket removes the socket file at path if no server is
+// listening on it, such as the one left by a server that was killed.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is already in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// newSocketServer creates a MySQL server listening on the Unix socket at
+// cfg.Address. The socket file is created with the given permissions, so
+// it can't be used by anyone else even right after it's created.
+func newSocketServer(
+	cfg server.Config,
+	e *sqle.Engine,
+	sb server.SessionBuilder,
+	opts mysqlServerOptions,
+	mode os.FileMode,
+) (*server.Server, error) {
+	cfg.Protocol = "unix"
+	if err := removeStaleSocket(cfg.Address); err != nil {
+		return nil, err
+	}
+
+	var s *server.Server
+	err := withUmask(int(^mode&0777), func() error {
+		var err error
+		s, err = newMySQLServer(cfg, e, sb, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,129 @@
+package command
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSocketMode(t *testing.T) {
+	require := require.New(t)
+
+	mode, err := parseSocketMode("0660")
+	require.NoError(err)
+	require.Equal(os.FileMode(0660), mode)
+
+	mode, err = parseSocketMode("777")
+	require.NoError(err)
+	require.Equal(os.FileMode(0777), mode)
+
+	for _, m := range []string{"", "foo", "0999", "01777", "-1"} {
+		_, err = parseSocketMode(m)
+		require.Error(err, m)
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "gitbase.sock")
+	require.NoError(removeStaleSocket(path))
+
+	l, err := net.Listen("unix", path)
+	require.NoError(err)
+	require.Error(removeStaleSocket(path))
+
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(l.Close())
+	require.NoError(removeStaleSocket(path))
+	_, err = os.Stat(path)
+	require.True(os.IsNotExist(err))
+
+	file := filepath.Join(tmpDir, "file")
+	require.NoError(ioutil.WriteFile(file, nil, 0644))
+	require.Error(removeStaleSocket(file))
+}
+
+func TestSocketServer(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	db := &Database{
+		Name:        "gitbase",
+		CacheSize:   512,
+		Format:      "siva",
+		Bucket:      0,
+		Directories: []string{"../../../_testdata"},
+		IndexDir:    tmpDir,
+		userAuth:    auth.NewNativeSingle("user", "pass", auth.ReadPerm),
+	}
+	require.NoError(db.buildDatabase())
+
+	cfg := server.Config{Auth: db.userAuth}
+	sb := gitbase.NewSessionBuilder(db.pool, db.sessionOptions()...)
+	conns := new(connections)
+
+	cfg.Protocol = "tcp"
+	cfg.Address = "127.0.0.1:0"
+	tcpSrv, err := newMySQLServer(cfg, db.engine, sb, mysqlServerOptions{conns: conns})
+	require.NoError(err)
+	go tcpSrv.Start()
+	defer tcpSrv.Close()
+
+	path := filepath.Join(tmpDir, "gitbase.sock")
+	cfg.Address = path
+	umask := syscall.Umask(0022)
+	defer syscall.Umask(umask)
+	socketSrv, err := newSocketServer(cfg, db.engine, sb, mysqlServerOptions{conns: conns}, 0600)
+	require.NoError(err)
+	go socketSrv.Start()
+	defer socketSrv.Close()
+
+	// the umask used to create the socket is restored.
+	require.Equal(0022, syscall.Umask(0022))
+
+	fi, err := os.Stat(path)
+	require.NoError(err)
+	require.Equal(os.FileMode(0600), fi.Mode().Perm())
+
+	_, err = newSocketServer(cfg, db.engine, sb, mysqlServerOptions{}, 0600)
+	require.Error(err)
+
+	connect := func(addr string) *sql.DB {
+		conn, err := sql.Open("mysql", "user:pass@"+addr+"/gitbase")
+		require.NoError(err)
+		conn.SetMaxOpenConns(1)
+		return conn
+	}
+
+	tcpConn := connect("tcp(" + tcpSrv.Listener.Addr().String() + ")")
+	defer tcpConn.Close()
+	socketConn := connect("unix(" + path + ")")
+	defer socketConn.Close()
+
+	var n int
+	require.NoError(socketConn.QueryRow("SELECT COUNT(*) FROM repositories").Scan(&n))
+	require.Equal(5, n)
+
+	var tcpID, socketID int
+	require.NoError(tcpConn.QueryRow("SELECT CONNECTION_ID()").Scan(&tcpID))
+	require.NoError(socketConn.QueryRow("SELECT CONNECTION_ID()").Scan(&socketID))
+	require.NotEqual(tcpID, socketID)
+	require.Equal(2, conns.Open())
+}
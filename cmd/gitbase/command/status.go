@@ -0,0 +1,146 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/src-d/gitbase/internal/function"
+
+	"github.com/src-d/go-borges"
+)
+
+// newStatusHandler returns the handler of the metrics server, which serves
+// the metrics and the health, readiness and status endpoints.
+func (c *Server) newStatusHandler(metrics http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	mux.HandleFunc("/healthz", c.handleHealth)
+	mux.HandleFunc("/readyz", c.handleReady)
+	mux.HandleFunc("/status", c.handleStatus)
+	return mux
+}
+
+// setReady marks the server as ready to accept queries.
+func (c *Server) setReady() {
+	atomic.StoreInt32(&c.ready, 1)
+}
+
+func (c *Server) isReady() bool {
+	return atomic.LoadInt32(&c.ready) == 1
+}
+
+// handleHealth reports that the server is alive, even while the
+// repositories are still being loaded.
+func (c *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReady reports whether the server is ready to accept queries, that
+// is, the database is built, the listeners are started and the repositories
+// of the library can be iterated.
+func (c *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if err := c.checkReady(); err != nil {
+		writeHTTPError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+func (c *Server) checkReady() error {
+	if !c.isReady() {
+		return fmt.Errorf("server is starting")
+	}
+
+	iter, err := c.rootLibrary.Repositories(borges.ReadOnlyMode)
+	if err != nil {
+		return fmt.Errorf("cannot list repositories: %s", err)
+	}
+	defer iter.Close()
+
+	repo, err := iter.Next()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot list repositories: %s", err)
+	}
+
+	return repo.Close()
+}
+
+type serverStatus struct {
+	Version       string                `json:"version"`
+	Ready         bool                  `json:"ready"`
+	Started       time.Time             `json:"started"`
+	UptimeSeconds int64                 `json:"uptime_seconds"`
+	Repositories  int                   `json:"repositories"`
+	Libraries     []libraryStatus       `json:"libraries"`
+	Caches        []function.CacheStats `json:"caches"`
+	Sessions      sessionsStatus        `json:"sessions"`
+	Queries       int                   `json:"queries"`
+}
+
+type libraryStatus struct {
+	Path         string            `json:"path"`
+	Format       string            `json:"format"`
+	Repositories int               `json:"repositories"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+type sessionsStatus struct {
+	MySQL    int `json:"mysql"`
+	Postgres int `json:"postgres"`
+	HTTP     int `json:"http"`
+}
+
+// handleStatus writes the version, the libraries and repositories, the
+// caches, the sessions and the uptime of the server as JSON. Until the
+// server is ready, the libraries, sessions and queries are not given.
+func (c *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := serverStatus{
+		Version:       c.Version,
+		Ready:         c.isReady(),
+		Started:       c.started,
+		UptimeSeconds: int64(time.Since(c.started) / time.Second),
+		Libraries:     []libraryStatus{},
+		Caches:        function.Caches(),
+	}
+
+	if status.Ready {
+		counts := c.directoryRepositories()
+		for _, n := range counts {
+			status.Repositories += n
+		}
+
+		for _, s := range c.sources {
+			status.Libraries = append(status.Libraries, libraryStatus{
+				Path:         s.dir.Path,
+				Format:       s.dir.Format,
+				Repositories: counts[s.dir.Path],
+				Labels:       s.dir.Labels,
+			})
+		}
+
+		status.Sessions.MySQL = c.conns.Open()
+		if c.pgSrv != nil {
+			status.Sessions.Postgres = c.pgSrv.Connections()
+		}
+
+		if c.httpAPI != nil {
+			status.Sessions.HTTP = c.httpAPI.Sessions()
+		}
+
+		status.Queries = len(c.engine.Catalog.ProcessList.Processes())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
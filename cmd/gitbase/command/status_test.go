@@ -0,0 +1,107 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusHandler(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	srv := &Server{
+		Database: Database{
+			Name:      "gitbase",
+			Version:   "v1.0.0",
+			CacheSize: 512,
+			Format:    "siva",
+			Bucket:    0,
+			IndexDir:  tmpDir,
+			configDirectories: []*directoryConfig{
+				{
+					Path:   "../../../_testdata",
+					Labels: map[string]string{"team": "core"},
+				},
+			},
+			userAuth: new(auth.None),
+		},
+		started: time.Now().Add(-time.Minute),
+	}
+
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("metrics"))
+	})
+	ts := httptest.NewServer(srv.newStatusHandler(metrics))
+	defer ts.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(ts.URL + path)
+		require.NoError(err)
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(err)
+		return resp.StatusCode, string(body)
+	}
+
+	status := func() serverStatus {
+		code, body := get("/status")
+		require.Equal(http.StatusOK, code)
+
+		var s serverStatus
+		require.NoError(json.Unmarshal([]byte(body), &s))
+		return s
+	}
+
+	code, body := get("/metrics")
+	require.Equal(http.StatusOK, code)
+	require.Equal("metrics", body)
+
+	// starting
+	code, _ = get("/healthz")
+	require.Equal(http.StatusOK, code)
+
+	code, body = get("/readyz")
+	require.Equal(http.StatusServiceUnavailable, code)
+	require.Contains(body, "server is starting")
+
+	s := status()
+	require.False(s.Ready)
+	require.Equal("v1.0.0", s.Version)
+	require.True(s.UptimeSeconds >= 60)
+	require.Empty(s.Libraries)
+	require.Len(s.Caches, 8)
+
+	// ready
+	require.NoError(srv.buildDatabase())
+	srv.setReady()
+
+	code, _ = get("/healthz")
+	require.Equal(http.StatusOK, code)
+
+	code, _ = get("/readyz")
+	require.Equal(http.StatusOK, code)
+
+	s = status()
+	require.True(s.Ready)
+	require.Equal(5, s.Repositories)
+	require.Equal([]libraryStatus{{
+		Path:         "../../../_testdata",
+		Format:       "siva",
+		Repositories: 5,
+		Labels:       map[string]string{"team": "core"},
+	}}, s.Libraries)
+	require.Equal(sessionsStatus{}, s.Sessions)
+	require.Equal(0, s.Queries)
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"vitess.io/vitess/go/mysql"
+	"vitess.io/vitess/go/sqltypes"
+)
+
+// erSecureTransportRequired is the MySQL error returned to the clients
+// that don't use TLS when it's required.
+const erSecureTransportRequired = 3159
+
+// tlsCertificates holds the certificate of the server and the certificate
+// authorities used to verify the certificates of the clients. They are read
+// from their files again with reload, and the connections started after it
+// use the new ones.
+type tlsCertificates struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.RWMutex
+	cert      tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// newTLSCertificates loads the certificate and key of the server and,
+// if caFile is not empty, the certificate authorities of the clients.
+func newTLSCertificates(certFile, keyFile, caFile string) (*tlsCertificates, error) {
+	c := &tlsCertificates{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// reload reads the certificate files again. The certificates in use are
+// kept if any of the files can't be loaded.
+func (c *tlsCertificates) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load TLS certificate: %s", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.caFile != "" {
+		data, err := ioutil.ReadFile(c.caFile)
+		if err != nil {
+			return fmt.Errorf("cannot load TLS CA: %s", err)
+		}
+
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("cannot load TLS CA: no certificates found in %s", c.caFile)
+		}
+	}
+
+	c.mu.Lock()
+	c.cert = cert
+	c.clientCAs = clientCAs
+	c.mu.Unlock()
+
+	return nil
+}
+
+// config returns the TLS configuration of the server. Each connection gets
+// the certificates loaded at the time of its handshake.
+func (c *tlsCertificates) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{c.cert},
+			}
+
+			if c.clientCAs != nil {
+				cfg.ClientCAs = c.clientCAs
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+
+			return cfg, nil
+		},
+	}
+}
+
+// reloadOnSignal reloads the certificates each time the process receives
+// a SIGHUP signal until the stop channel is closed.
+func (c *tlsCertificates) reloadOnSignal(stop <-chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signals:
+			if err := c.reload(); err != nil {
+				logrus.WithField("error", err).Error("unable to reload TLS certificates")
+				continue
+			}
+
+			logrus.Info("TLS certificates reloaded")
+		}
+	}
+}
+
+// secureTransportHandler rejects the queries of the connections that don't
+// use TLS. The listener already sends an error to these clients during the
+// handshake, but it doesn't close the connection.
+type secureTransportHandler struct {
+	mysql.Handler
+}
+
+func (h *secureTransportHandler) ComQuery(
+	c *mysql.Conn,
+	query string,
+	callback func(*sqltypes.Result) error,
+) error {
+	if c.Capabilities&mysql.CapabilityClientSSL == 0 {
+		return mysql.NewSQLError(
+			erSecureTransportRequired,
+			mysql.SSUnknownSQLState,
+			"Connections using insecure transport are prohibited while --require-secure-transport is enabled",
+		)
+	}
+
+	return h.Handler.ComQuery(c, query, callback)
+}
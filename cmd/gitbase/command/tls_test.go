@@ -0,0 +1,312 @@
+package command
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/server"
+	"github.com/stretchr/testify/require"
+	"vitess.io/vitess/go/mysql"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate for localhost signed by parent, or a
+// certificate authority if parent is nil.
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth,
+			x509.ExtKeyUsageClientAuth,
+		},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert, key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	require.NoError(t, ioutil.WriteFile(certFile, certPEM, 0600))
+
+	if keyFile != "" {
+		der, err := x509.MarshalECPrivateKey(c.key)
+		require.NoError(t, err)
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+		require.NoError(t, ioutil.WriteFile(keyFile, keyPEM, 0600))
+	}
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{c.cert.Raw},
+		PrivateKey:  c.key,
+	}
+}
+
+func servedCert(t *testing.T, certs *tlsCertificates) *x509.Certificate {
+	cfg, err := certs.config().GetConfigForClient(nil)
+	require.NoError(t, err)
+	require.Len(t, cfg.Certificates, 1)
+
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return cert
+}
+
+func TestTLSCertificatesReload(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	certFile := filepath.Join(tmpDir, "cert.pem")
+	keyFile := filepath.Join(tmpDir, "key.pem")
+	caFile := filepath.Join(tmpDir, "ca.pem")
+
+	ca := newTestCert(t, "ca", nil)
+	ca.write(t, caFile, "")
+	first := newTestCert(t, "first", ca)
+	first.write(t, certFile, keyFile)
+
+	certs, err := newTLSCertificates(certFile, keyFile, caFile)
+	require.NoError(err)
+	require.Equal("first", servedCert(t, certs).Subject.CommonName)
+
+	cfg, err := certs.config().GetConfigForClient(nil)
+	require.NoError(err)
+	require.Equal(tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+	require.NotNil(cfg.ClientCAs)
+
+	second := newTestCert(t, "second", ca)
+	second.write(t, certFile, keyFile)
+	require.NoError(certs.reload())
+	require.Equal("second", servedCert(t, certs).Subject.CommonName)
+
+	// invalid files keep the certificates in use
+	require.NoError(ioutil.WriteFile(certFile, []byte("foo"), 0600))
+	require.Error(certs.reload())
+	require.Equal("second", servedCert(t, certs).Subject.CommonName)
+
+	require.NoError(ioutil.WriteFile(caFile, []byte("foo"), 0600))
+	second.write(t, certFile, keyFile)
+	require.Error(certs.reload())
+
+	_, err = newTLSCertificates(filepath.Join(tmpDir, "missing.pem"), keyFile, "")
+	require.Error(err)
+}
+
+func TestTLSCertificatesReloadOnSignal(t *testing.T) {
+	require := require.New(t)
+
+	// Keep SIGHUP from terminating the tests if it's received before the
+	// certificates handle it.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	certFile := filepath.Join(tmpDir, "cert.pem")
+	keyFile := filepath.Join(tmpDir, "key.pem")
+
+	ca := newTestCert(t, "ca", nil)
+	newTestCert(t, "first", ca).write(t, certFile, keyFile)
+
+	certs, err := newTLSCertificates(certFile, keyFile, "")
+	require.NoError(err)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go certs.reloadOnSignal(stop)
+
+	newTestCert(t, "second", ca).write(t, certFile, keyFile)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for servedCert(t, certs).Subject.CommonName != "second" {
+		require.True(time.Now().Before(deadline), "certificates not reloaded")
+		require.NoError(syscall.Kill(os.Getpid(), syscall.SIGHUP))
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTLSServer(t *testing.T) {
+	require := require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "gitbase")
+	require.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	certFile := filepath.Join(tmpDir, "cert.pem")
+	keyFile := filepath.Join(tmpDir, "key.pem")
+	caFile := filepath.Join(tmpDir, "ca.pem")
+
+	ca := newTestCert(t, "ca", nil)
+	ca.write(t, caFile, "")
+	newTestCert(t, "server", ca).write(t, certFile, keyFile)
+	client := newTestCert(t, "client", ca)
+	other := newTestCert(t, "other", newTestCert(t, "other-ca", nil))
+
+	certs, err := newTLSCertificates(certFile, keyFile, caFile)
+	require.NoError(err)
+
+	db := &Database{
+		Name:        "gitbase",
+		CacheSize:   512,
+		Format:      "siva",
+		Bucket:      0,
+		Directories: []string{"../../../_testdata"},
+		IndexDir:    tmpDir,
+		userAuth:    auth.NewNativeSingle("user", "pass", auth.ReadPerm),
+	}
+	require.NoError(db.buildDatabase())
+
+	s, err := newMySQLServer(
+		server.Config{
+			Protocol: "tcp",
+			Address:  "127.0.0.1:0",
+			Auth:     db.userAuth,
+		},
+		db.engine,
+		gitbase.NewSessionBuilder(db.pool, db.sessionOptions()...),
+		mysqlServerOptions{tlsConfig: certs.config(), requireSecure: true},
+	)
+	require.NoError(err)
+	go s.Start()
+	defer s.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tlsConfigs := map[string]*tls.Config{
+		"gitbase-client": {
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: []tls.Certificate{client.tlsCertificate()},
+		},
+		"gitbase-nocert": {RootCAs: roots, ServerName: "localhost"},
+		"gitbase-other": {
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: []tls.Certificate{other.tlsCertificate()},
+		},
+	}
+	for name, cfg := range tlsConfigs {
+		require.NoError(driver.RegisterTLSConfig(name, cfg))
+		defer driver.DeregisterTLSConfig(name)
+	}
+
+	query := func(tlsName string) (int, error) {
+		conn, err := sql.Open("mysql", "user:pass@tcp("+
+			s.Listener.Addr().String()+")/gitbase?tls="+tlsName)
+		require.NoError(err)
+		defer conn.Close()
+
+		var n int
+		err = conn.QueryRow("SELECT COUNT(*) FROM repositories").Scan(&n)
+		return n, err
+	}
+
+	n, err := query("gitbase-client")
+	require.NoError(err)
+	require.Equal(5, n)
+
+	_, err = query("false")
+	require.Error(err)
+
+	_, err = query("gitbase-nocert")
+	require.Error(err)
+
+	_, err = query("gitbase-other")
+	require.Error(err)
+}
+
+func TestSecureTransportHandler(t *testing.T) {
+	require := require.New(t)
+
+	h := &secureTransportHandler{}
+	err := h.ComQuery(new(mysql.Conn), "SELECT 1", nil)
+	require.Error(err)
+
+	sqlErr, ok := err.(*mysql.SQLError)
+	require.True(ok)
+	require.Equal(erSecureTransportRequired, sqlErr.Number())
+}
+
+func TestCheckTLS(t *testing.T) {
+	testCases := []struct {
+		name string
+		srv  *Server
+		err  bool
+	}{
+		{"disabled", &Server{}, false},
+		{"cert and key", &Server{TLSCert: "cert", TLSKey: "key"}, false},
+		{"all", &Server{TLSCert: "cert", TLSKey: "key", TLSCA: "ca", RequireSecure: true}, false},
+		{"cert without key", &Server{TLSCert: "cert"}, true},
+		{"key without cert", &Server{TLSKey: "key"}, true},
+		{"ca without cert", &Server{TLSCA: "ca"}, true},
+		{"require without cert", &Server{RequireSecure: true}, true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.srv.checkTLS()
+			if tt.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
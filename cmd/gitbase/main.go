@@ -30,11 +30,39 @@ func main() {
 		return append(append(args, "-d"), args[0]), nil
 	}
 
-	_, err := parser.AddCommand("server", command.ServerDescription, command.ServerHelp,
-		&command.Server{
+	srv := &command.Server{Database: command.Database{
+		SkipGitErrors: os.Getenv("GITBASE_SKIP_GIT_ERRORS") != "",
+		Version:       version,
+	}}
+	cmd, err := parser.AddCommand("server", command.ServerDescription, command.ServerHelp, srv)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	srv.SetCommand(cmd)
+
+	_, err = parser.AddCommand("query", command.QueryDescription, command.QueryHelp,
+		&command.Query{Database: command.Database{
 			SkipGitErrors: os.Getenv("GITBASE_SKIP_GIT_ERRORS") != "",
 			Version:       version,
-		})
+		}})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	_, err = parser.AddCommand("shell", command.ShellDescription, command.ShellHelp,
+		&command.Shell{Database: command.Database{
+			SkipGitErrors: os.Getenv("GITBASE_SKIP_GIT_ERRORS") != "",
+			Version:       version,
+		}})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	_, err = parser.AddCommand("export", command.ExportDescription, command.ExportHelp,
+		&command.Export{Database: command.Database{
+			SkipGitErrors: os.Getenv("GITBASE_SKIP_GIT_ERRORS") != "",
+			Version:       version,
+		}})
 	if err != nil {
 		logrus.Fatal(err)
 	}
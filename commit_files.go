@@ -79,9 +79,26 @@ func (t *commitFilesTable) PartitionRows(
 	}
 
 	span, ctx := ctx.Span("gitbase.CommitFilesTable")
+
+	// ignore filters can only be evaluated while reading the commits, so
+	// they are applied to the rows when an index is used.
+	var ignored []ignoreFilter
+	filters := t.filters
+	if t.index == nil {
+		ignored, filters, err = classifyIgnoreFilters(
+			CommitFilesTableName,
+			"commit_hash",
+			filters,
+		)
+		if err != nil {
+			span.Finish()
+			return nil, errorWithRepo(repo, err)
+		}
+	}
+
 	iter, err := rowIterWithSelectors(
 		ctx, CommitFilesSchema, CommitFilesTableName,
-		t.filters,
+		filters,
 		t.handledColumns(),
 		func(selectors selectors) (sql.RowIter, error) {
 			var repos []string
@@ -118,6 +135,7 @@ func (t *commitFilesTable) PartitionRows(
 				index:         index,
 				commitHashes:  stringsToHashes(hashes),
 				paths:         paths,
+				ignore:        newIgnoreMatcher(ctx, repo, ignored),
 				skipGitErrors: shouldSkipErrors(ctx),
 			}, nil
 		},
@@ -185,6 +203,7 @@ type commitFilesRowIter struct {
 	// selectors for faster filtering
 	commitHashes []plumbing.Hash
 	paths        []string
+	ignore       *ignoreMatcher
 }
 
 func (i *commitFilesRowIter) Next() (sql.Row, error) {
@@ -265,6 +284,19 @@ func (i *commitFilesRowIter) next() (sql.Row, error) {
 				return nil, err
 			}
 
+			if err = i.ignore.setCommit(i.commit); err != nil {
+				if i.skipGitErrors {
+					logrus.WithFields(logrus.Fields{
+						"repo":   i.repo.ID(),
+						"err":    err,
+						"commit": i.commit.Hash.String(),
+					}).Error("can't get ignore rules for commit")
+					continue
+				}
+
+				return nil, err
+			}
+
 			i.files, err = i.commit.Files()
 			if err != nil {
 				if i.skipGitErrors {
@@ -301,6 +333,10 @@ func (i *commitFilesRowIter) next() (sql.Row, error) {
 			continue
 		}
 
+		if !i.ignore.match(f.Name) {
+			continue
+		}
+
 		return newCommitFilesRow(i.repo, i.commit, f), nil
 	}
 }
@@ -27,6 +27,10 @@ const (
 	CommitFilesTableName = "commit_files"
 	// FilesTableName is the name of the files table.
 	FilesTableName = "files"
+	// LeakedSecretsTableName is the name of the leaked secrets table.
+	LeakedSecretsTableName = "leaked_secrets"
+	// BlobSignatureBucketsTableName is the name of the blob signature buckets table.
+	BlobSignatureBucketsTableName = "blob_signature_buckets"
 )
 
 // Database holds all git repository tables
@@ -43,6 +47,8 @@ type Database struct {
 	commitBlobs  sql.Table
 	commitFiles  sql.Table
 	files        sql.Table
+	secrets      sql.Table
+	buckets      sql.Table
 }
 
 // NewDatabase creates a new Database structure and initializes its
@@ -61,6 +67,8 @@ func NewDatabase(name string, pool *RepositoryPool) sql.Database {
 		commitBlobs:  newCommitBlobsTable(pool),
 		commitFiles:  newCommitFilesTable(pool),
 		files:        newFilesTable(pool),
+		secrets:      newLeakedSecretsTable(pool),
+		buckets:      newBlobSignatureBucketsTable(pool),
 	}
 }
 
@@ -72,16 +80,18 @@ func (d *Database) Name() string {
 // Tables returns a map with all initialized tables
 func (d *Database) Tables() map[string]sql.Table {
 	return map[string]sql.Table{
-		CommitsTableName:      d.commits,
-		ReferencesTableName:   d.references,
-		BlobsTableName:        d.blobs,
-		TreeEntriesTableName:  d.treeEntries,
-		RepositoriesTableName: d.repositories,
-		RemotesTableName:      d.remotes,
-		RefCommitsTableName:   d.refCommits,
-		CommitTreesTableName:  d.commitTrees,
-		CommitBlobsTableName:  d.commitBlobs,
-		CommitFilesTableName:  d.commitFiles,
-		FilesTableName:        d.files,
+		CommitsTableName:              d.commits,
+		ReferencesTableName:           d.references,
+		BlobsTableName:                d.blobs,
+		TreeEntriesTableName:          d.treeEntries,
+		RepositoriesTableName:         d.repositories,
+		RemotesTableName:              d.remotes,
+		RefCommitsTableName:           d.refCommits,
+		CommitTreesTableName:          d.commitTrees,
+		CommitBlobsTableName:          d.commitBlobs,
+		CommitFilesTableName:          d.commitFiles,
+		FilesTableName:                d.files,
+		LeakedSecretsTableName:        d.secrets,
+		BlobSignatureBucketsTableName: d.buckets,
 	}
 }
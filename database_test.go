@@ -36,6 +36,8 @@ func TestDatabase_Tables(t *testing.T) {
 		CommitBlobsTableName,
 		FilesTableName,
 		CommitFilesTableName,
+		LeakedSecretsTableName,
+		BlobSignatureBucketsTableName,
 	}
 	sort.Strings(expected)
 
@@ -73,9 +73,22 @@ func (r *filesTable) PartitionRows(
 	}
 
 	span, ctx := ctx.Span("gitbase.FilesTable")
+
+	// ignore filters can only be evaluated while reading the trees, so
+	// they are applied to the rows when an index is used.
+	var ignored []ignoreFilter
+	filters := r.filters
+	if r.index == nil {
+		ignored, filters, err = classifyIgnoreFilters(FilesTableName, "", filters)
+		if err != nil {
+			span.Finish()
+			return nil, errorWithRepo(repo, err)
+		}
+	}
+
 	iter, err := rowIterWithSelectors(
 		ctx, FilesSchema, FilesTableName,
-		r.filters,
+		filters,
 		r.handledColumns(),
 		func(selectors selectors) (sql.RowIter, error) {
 			var repos []string
@@ -134,6 +147,7 @@ func (r *filesTable) PartitionRows(
 				treeHashes:    stringsToHashes(treeHashes),
 				blobHashes:    stringsToHashes(blobHashes),
 				filePaths:     filePaths,
+				ignore:        newIgnoreMatcher(ctx, repo, ignored),
 				readContent:   shouldReadContent(r.projection),
 				skipGitErrors: shouldSkipErrors(ctx),
 			}, nil
@@ -193,6 +207,7 @@ type filesRowIter struct {
 	filePaths  []string
 	blobHashes []plumbing.Hash
 	treeHashes []plumbing.Hash
+	ignore     *ignoreMatcher
 }
 
 func (i *filesRowIter) init() error {
@@ -223,7 +238,7 @@ func (i *filesRowIter) shouldVisitFile(file *object.File) bool {
 		return false
 	}
 
-	return true
+	return i.ignore.match(file.Name)
 }
 
 func (i *filesRowIter) Next() (sql.Row, error) {
@@ -255,6 +270,14 @@ func (i *filesRowIter) Next() (sql.Row, error) {
 				i.treeHash = commit.TreeHash
 				i.seen[commit.TreeHash] = struct{}{}
 
+				if err = i.ignore.setCommit(commit); err != nil {
+					if i.skipGitErrors {
+						continue
+					}
+
+					return nil, err
+				}
+
 				if i.files, err = commit.Files(); err != nil {
 					if i.skipGitErrors {
 						continue
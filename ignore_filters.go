@@ -0,0 +1,211 @@
+package gitbase
+
+import (
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// IgnoreFilter is a filter checking whether a file path matches the ignore
+// rules of a commit, such as the is_ignored function. Tables reading files
+// evaluate it with the commits they read, so ignored files are skipped
+// before their rows are built.
+type IgnoreFilter interface {
+	sql.Expression
+	// IgnoreArgs returns the repository, commit and path arguments of the
+	// filter.
+	IgnoreArgs() (repo, commit, path sql.Expression)
+	// IgnoreMatcher returns a function reporting whether a path is ignored
+	// by the rules of the given commit, or nil if the rules can't be read.
+	IgnoreMatcher(
+		ctx *sql.Context,
+		repo *Repository,
+		commit *object.Commit,
+	) (func(path string) bool, error)
+}
+
+// ignoreFilter is an IgnoreFilter handled by a table. If commit is empty,
+// the rules are the ones of the commit being read.
+type ignoreFilter struct {
+	filter  IgnoreFilter
+	commit  plumbing.Hash
+	negated bool
+}
+
+// classifyIgnoreFilters splits the given filters in the ignore filters
+// on the repository and file path of the given table and the rest of
+// them. The commit of the filter must be a literal or, if commitColumn is
+// not empty, that column of the table.
+func classifyIgnoreFilters(
+	table, commitColumn string,
+	filters []sql.Expression,
+) ([]ignoreFilter, []sql.Expression, error) {
+	var ignored []ignoreFilter
+	var rest []sql.Expression
+	for _, f := range filters {
+		filter, ok, err := newIgnoreFilter(table, commitColumn, f)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			ignored = append(ignored, filter)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+
+	return ignored, rest, nil
+}
+
+func newIgnoreFilter(
+	table, commitColumn string,
+	e sql.Expression,
+) (ignoreFilter, bool, error) {
+	var result ignoreFilter
+	if not, ok := e.(*expression.Not); ok {
+		e = not.Child
+		result.negated = true
+	}
+
+	f, ok := e.(IgnoreFilter)
+	if !ok {
+		return result, false, nil
+	}
+	result.filter = f
+
+	repo, commit, path := f.IgnoreArgs()
+	if !isTableField(repo, table, "repository_id") ||
+		!isTableField(path, table, "file_path") {
+		return result, false, nil
+	}
+
+	if commitColumn != "" && isTableField(commit, table, commitColumn) {
+		return result, true, nil
+	}
+
+	lit, ok := commit.(*expression.Literal)
+	if !ok {
+		return result, false, nil
+	}
+
+	v, err := lit.Eval(nil, nil)
+	if err != nil {
+		return result, false, err
+	}
+
+	if v == nil {
+		// is_ignored is NULL for every row, let it be evaluated as usual.
+		return result, false, nil
+	}
+
+	v, err = sql.Text.Convert(v)
+	if err != nil {
+		return result, false, err
+	}
+
+	result.commit = plumbing.NewHash(v.(string))
+	if result.commit.IsZero() {
+		return result, false, nil
+	}
+
+	return result, true, nil
+}
+
+func isTableField(e sql.Expression, table, name string) bool {
+	gf, ok := e.(*expression.GetField)
+	return ok && gf.Table() == table && gf.Name() == name
+}
+
+// ignoreMatcher evaluates the ignore filters of a table with the commits
+// it reads. Matchers of filters with a fixed commit are computed only
+// once.
+type ignoreMatcher struct {
+	ctx     *sql.Context
+	repo    *Repository
+	filters []ignoreFilter
+
+	commit   plumbing.Hash
+	matchers []func(string) bool
+	fixed    map[plumbing.Hash]func(string) bool
+}
+
+func newIgnoreMatcher(
+	ctx *sql.Context,
+	repo *Repository,
+	filters []ignoreFilter,
+) *ignoreMatcher {
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return &ignoreMatcher{
+		ctx:     ctx,
+		repo:    repo,
+		filters: filters,
+		fixed:   make(map[plumbing.Hash]func(string) bool),
+	}
+}
+
+// setCommit computes the matchers for the files of the given commit.
+func (m *ignoreMatcher) setCommit(commit *object.Commit) error {
+	if m == nil || m.matchers != nil && commit.Hash == m.commit {
+		return nil
+	}
+
+	m.commit = commit.Hash
+	m.matchers = make([]func(string) bool, len(m.filters))
+	for i, f := range m.filters {
+		var err error
+		if f.commit.IsZero() {
+			m.matchers[i], err = f.filter.IgnoreMatcher(m.ctx, m.repo, commit)
+		} else {
+			m.matchers[i], err = m.fixedMatcher(f)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *ignoreMatcher) fixedMatcher(f ignoreFilter) (func(string) bool, error) {
+	if matcher, ok := m.fixed[f.commit]; ok {
+		return matcher, nil
+	}
+
+	var matcher func(string) bool
+	commit, err := m.repo.CommitObject(f.commit)
+	if err == nil {
+		matcher, err = f.filter.IgnoreMatcher(m.ctx, m.repo, commit)
+	} else if err == plumbing.ErrObjectNotFound {
+		err = nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	m.fixed[f.commit] = matcher
+	return matcher, nil
+}
+
+// match reports whether the file in the given path passes all the
+// filters. As with NULL values, no file passes a filter whose rules
+// can't be read.
+func (m *ignoreMatcher) match(path string) bool {
+	if m == nil {
+		return true
+	}
+
+	for i, matcher := range m.matchers {
+		if matcher == nil || matcher(path) == m.filters[i].negated {
+			return false
+		}
+	}
+
+	return true
+}
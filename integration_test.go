@@ -23,8 +23,11 @@ import (
 	"github.com/src-d/go-mysql-server/sql"
 	"github.com/src-d/go-mysql-server/sql/analyzer"
 	"github.com/src-d/go-mysql-server/sql/index/pilosa"
+	"github.com/src-d/go-mysql-server/sql/parse"
+	"github.com/src-d/go-mysql-server/sql/plan"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-billy.v4/util"
 	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 )
 
@@ -203,6 +206,74 @@ func TestIntegration(t *testing.T) {
 				{"1669dce138d9b841a518c64b10914d88f5e488ea", "binary.jpg"},
 			},
 		},
+		{
+			`SELECT commit_hash, file_path
+			FROM commit_files
+			WHERE commit_hash = '1669dce138d9b841a518c64b10914d88f5e488ea'
+				AND NOT is_ignored(repository_id, commit_hash, file_path)`,
+			[]sql.Row{
+				{"1669dce138d9b841a518c64b10914d88f5e488ea", ".gitignore"},
+				{"1669dce138d9b841a518c64b10914d88f5e488ea", "CHANGELOG"},
+				{"1669dce138d9b841a518c64b10914d88f5e488ea", "LICENSE"},
+				{"1669dce138d9b841a518c64b10914d88f5e488ea", "binary.jpg"},
+			},
+		},
+		{
+			`SELECT f.file_path, is_ignored(cf.repository_id, cf.commit_hash, CONCAT(f.file_path, '.jar'))
+			FROM commit_files cf
+			NATURAL JOIN files f
+			WHERE cf.commit_hash = '1669dce138d9b841a518c64b10914d88f5e488ea'
+				AND is_ignored(cf.repository_id, cf.commit_hash, f.file_path) = false`,
+			[]sql.Row{
+				{".gitignore", true},
+				{"CHANGELOG", true},
+				{"LICENSE", true},
+				{"binary.jpg", true},
+			},
+		},
+		{
+			`SELECT patch_id, COUNT(*) AS commits
+			FROM (
+				SELECT patch_id(repository_id, commit_hash) AS patch_id
+				FROM commits
+			) t
+			GROUP BY patch_id
+			HAVING commits > 1`,
+			[]sql.Row{
+				{"654add56d9609afaaac7c05388967247d466a307", int64(3)},
+			},
+		},
+		{
+			`SELECT commit_hash, issue_refs(commit_message, 'pull request #(\\d+)')
+			FROM commits
+			WHERE ARRAY_LENGTH(issue_refs(commit_message)) > 0`,
+			[]sql.Row{
+				{"a5b8b09e2f8fcb0bb99d3ccb0958157b40890d69", []interface{}{"1"}},
+			},
+		},
+		{
+			`SELECT JSON_EXTRACT(commit_loc(repository_id, commit_hash), '$.PHP.Code')
+			FROM commits
+			WHERE commit_hash = '6ecf0ef2c2dffb796033e5a02219af86ec6584e5'`,
+			[]sql.Row{{float64(186)}},
+		},
+		{
+			`SELECT JSON_EXTRACT(tree_languages(repository_id, commit_hash), '$.Go.files')
+			FROM commits
+			WHERE commit_hash = '6ecf0ef2c2dffb796033e5a02219af86ec6584e5'`,
+			[]sql.Row{{float64(1)}},
+		},
+		{
+			`SELECT JSON_EXTRACT(identifiers('Go', blob_content), '$[0]'),
+				JSON_EXTRACT(tokens('Go', blob_content, 2), '$[0]')
+			FROM files
+			WHERE file_path = 'go/example.go'`,
+			[]sql.Row{
+				{"harvesterd", "package harvesterd"},
+				{"harvesterd", "package harvesterd"},
+				{"harvesterd", "package harvesterd"},
+			},
+		},
 		{
 			`SELECT commit_hash, file_path
 			FROM commit_files
@@ -955,6 +1026,7 @@ func TestIndexes(t *testing.T) {
 		`SELECT tree_entry_name, blob_hash FROM tree_entries WHERE tree_entry_name = 'LICENSE'`,
 		`SELECT blob_hash, blob_size FROM blobs WHERE blob_hash = 'd5c0f4ab811897cadf03aec358ae60d21f91c50d'`,
 		`SELECT file_path, blob_hash FROM files WHERE file_path = 'LICENSE'`,
+		`SELECT blob_hash, bucket FROM blob_signature_buckets WHERE bucket = '00-aa929784a2497125'`,
 		`SELECT b.* FROM tree_entries t
 		INNER JOIN blobs b ON t.blob_hash = b.blob_hash
 		WHERE t.tree_entry_name = 'LICENSE'`,
@@ -1003,6 +1075,98 @@ func TestIndexes(t *testing.T) {
 	}
 }
 
+func TestIsIgnoredPushdown(t *testing.T) {
+	engine, pool, cleanup := setup(t)
+	defer cleanup()
+
+	repo, err := pool.GetRepo("worktree")
+	require.NoError(t, err)
+	fs, err := repo.FS()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(fs, "info/exclude", []byte("*.go\n"), 0644))
+	require.NoError(t, repo.Close())
+
+	ctx := sql.NewContext(
+		context.TODO(),
+		sql.WithSession(gitbase.NewSession(pool)),
+	)
+
+	const commit = "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{
+			fmt.Sprintf(`SELECT file_path, blob_hash FROM files
+			WHERE is_ignored(repository_id, '%s', file_path)`, commit),
+			fmt.Sprintf(`SELECT file_path, blob_hash FROM files
+			WHERE is_ignored(repository_id, '%s', file_path) = true`, commit),
+		},
+		{
+			fmt.Sprintf(`SELECT file_path, blob_hash FROM files
+			WHERE NOT is_ignored(repository_id, '%s', file_path)`, commit),
+			fmt.Sprintf(`SELECT file_path, blob_hash FROM files
+			WHERE is_ignored(repository_id, '%s', file_path) = false`, commit),
+		},
+		{
+			`SELECT commit_hash, file_path FROM commit_files
+			WHERE is_ignored(repository_id, commit_hash, file_path)`,
+			`SELECT commit_hash, file_path FROM commit_files
+			WHERE is_ignored(repository_id, commit_hash, file_path) = true`,
+		},
+		{
+			`SELECT commit_hash, file_path FROM commit_files
+			WHERE NOT is_ignored(repository_id, commit_hash, file_path)`,
+			`SELECT commit_hash, file_path FROM commit_files
+			WHERE is_ignored(repository_id, commit_hash, file_path) = false`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require := require.New(t)
+
+			node, err := parse.Parse(ctx, tt.query)
+			require.NoError(err)
+			node, err = engine.Analyzer.Analyze(ctx, node)
+			require.NoError(err)
+
+			var handled bool
+			plan.Inspect(node, func(n sql.Node) bool {
+				switch n := n.(type) {
+				case *plan.Filter:
+					require.Fail("is_ignored filter was not pushed down")
+				case *plan.ResolvedTable:
+					var t = n.Table
+					if w, ok := t.(sql.TableWrapper); ok {
+						t = w.Underlying()
+					}
+
+					table, ok := t.(sql.FilteredTable)
+					require.True(ok)
+					require.Len(table.HandledFilters(table.Filters()), 1)
+					handled = true
+				}
+				return true
+			})
+			require.True(handled)
+
+			_, iter, err := engine.Query(ctx, tt.query)
+			require.NoError(err)
+			rows, err := sql.RowIterToRows(iter)
+			require.NoError(err)
+			require.NotEmpty(rows)
+
+			_, iter, err = engine.Query(ctx, tt.expected)
+			require.NoError(err)
+			expected, err := sql.RowIterToRows(iter)
+			require.NoError(err)
+
+			require.ElementsMatch(expected, rows)
+		})
+	}
+}
+
 type indexData struct {
 	id    string
 	table string
@@ -1066,6 +1230,11 @@ func createTestIndexes(t testing.TB, engine *sqle.Engine, ctx *sql.Context) func
 			table: gitbase.FilesTableName,
 			exprs: []string{"language(file_path, blob_content)"},
 		},
+		{
+			id:    "blob_signature_buckets_idx",
+			table: gitbase.BlobSignatureBucketsTableName,
+			exprs: []string{"bucket"},
+		},
 	}
 
 	for _, idx := range indexes {
@@ -0,0 +1,173 @@
+// Package codeowners parses CODEOWNERS files and finds the owners of the
+// files of a repository.
+package codeowners
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidPattern is returned when a pattern of a CODEOWNERS file can't
+// be parsed.
+var ErrInvalidPattern = errors.NewKind("invalid CODEOWNERS pattern %q in line %d: %s")
+
+// Paths are the paths where CODEOWNERS files are looked for, in order of
+// precedence.
+var Paths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule assigns owners to the files matching a pattern.
+type Rule struct {
+	Pattern string
+	Owners  []string
+
+	re *regexp.Regexp
+}
+
+// Match returns whether the given path matches the pattern of the rule.
+func (r *Rule) Match(path string) bool {
+	return r.re.MatchString(path)
+}
+
+// Ruleset is the list of rules of a CODEOWNERS file.
+type Ruleset []*Rule
+
+// Parse parses the content of a CODEOWNERS file. Blank lines, comments
+// and negated patterns, which are not supported, are ignored.
+func Parse(r io.Reader) (Ruleset, error) {
+	var rules Ruleset
+	scanner := bufio.NewScanner(r)
+	var line int
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(stripComment(scanner.Text()))
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+
+		pattern := strings.Replace(fields[0], `\#`, "#", -1)
+		re, err := regexp.Compile(patternToRegexp(pattern))
+		if err != nil {
+			return nil, ErrInvalidPattern.New(fields[0], line, err)
+		}
+
+		owners := fields[1:]
+		if owners == nil {
+			owners = []string{}
+		}
+
+		rules = append(rules, &Rule{Pattern: pattern, Owners: owners, re: re})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// Owners returns the owners of the file in the given path, which are the
+// ones of the last rule matching it. If no rule matches the path, or the
+// rule has no owners, the file is unowned and the result is empty.
+func (rs Ruleset) Owners(path string) []string {
+	if rule := rs.Match(path); rule != nil {
+		return rule.Owners
+	}
+
+	return []string{}
+}
+
+// Match returns the last rule matching the given path, or nil if there
+// is none.
+func (rs Ruleset) Match(path string) *Rule {
+	path = strings.TrimPrefix(path, "/")
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].Match(path) {
+			return rs[i]
+		}
+	}
+
+	return nil
+}
+
+// stripComment removes the comment, if any, of a line. A comment starts
+// with a # that is not escaped.
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// patternToRegexp converts a CODEOWNERS pattern, which follows most of the
+// gitignore rules, to a regular expression matching the paths it applies to.
+//
+// Patterns containing a slash, except at the end, are relative to the root
+// of the repository, while the others match at any level. Patterns
+// matching a directory match everything inside it, except the ones ending
+// with "/*", which only match the files directly inside it.
+func patternToRegexp(pattern string) string {
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored && !strings.HasPrefix(trimmed, "**") {
+		re.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(trimmed); i++ {
+		c := trimmed[i]
+		switch {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(trimmed[i:], ']')
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+
+			class := trimmed[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(trimmed):
+			i++
+			re.WriteString(regexp.QuoteMeta(string(trimmed[i])))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(pattern, "/"):
+		re.WriteString("/")
+	case strings.HasSuffix(trimmed, "/*"):
+		re.WriteString("$")
+	default:
+		re.WriteString("(?:$|/)")
+	}
+
+	return re.String()
+}
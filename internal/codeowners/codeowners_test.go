@@ -0,0 +1,79 @@
+package codeowners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCodeowners = `# default owners
+*       @global-owner1 @global-owner2
+
+*.js    @js-owner # inline comment
+*.go docs@example.com
+
+**/logs @octocat
+/build/logs/ @doctocat
+docs/*  docs@example.com
+apps/ @octocat
+/docs/ @doctocat
+/scripts/*.sh @ops
+
+# no owners
+/vendor/
+!negated @nobody
+\#hash @hash-owner
+`
+
+func TestParse(t *testing.T) {
+	require := require.New(t)
+	rules, err := Parse(strings.NewReader(testCodeowners))
+	require.NoError(err)
+	require.Len(rules, 11)
+
+	require.Equal("*", rules[0].Pattern)
+	require.Equal([]string{"@global-owner1", "@global-owner2"}, rules[0].Owners)
+	require.Equal([]string{"@js-owner"}, rules[1].Owners)
+	require.Equal("/vendor/", rules[9].Pattern)
+	require.Equal([]string{}, rules[9].Owners)
+	require.Equal("#hash", rules[10].Pattern)
+
+	_, err = Parse(strings.NewReader("a[b-a] @foo\n"))
+	require.True(ErrInvalidPattern.Is(err))
+}
+
+func TestOwners(t *testing.T) {
+	rules, err := Parse(strings.NewReader(testCodeowners))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path     string
+		expected []string
+	}{
+		{"README.md", []string{"@global-owner1", "@global-owner2"}},
+		{"src/app.js", []string{"@js-owner"}},
+		{"main.go", []string{"docs@example.com"}},
+		{"build/logs/2019/out.txt", []string{"@doctocat"}},
+		{"build/logs", []string{"@octocat"}},
+		{"docs/getting-started.md", []string{"@doctocat"}},
+		{"lib/docs/index.md", []string{"@global-owner1", "@global-owner2"}},
+		{"apps/web/main.go", []string{"@octocat"}},
+		{"src/apps/main.go", []string{"@octocat"}},
+		{"src/logs", []string{"@octocat"}},
+		{"scripts/deploy.sh", []string{"@ops"}},
+		{"scripts/ci/deploy.sh", []string{"@global-owner1", "@global-owner2"}},
+		{"vendor/foo/foo.go", []string{}},
+		{"#hash", []string{"@hash-owner"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.path, func(t *testing.T) {
+			require.Equal(t, tt.expected, rules.Owners(tt.path))
+		})
+	}
+
+	var empty Ruleset
+	require.Equal(t, []string{}, empty.Owners("foo.go"))
+	require.Nil(t, empty.Match("foo.go"))
+}
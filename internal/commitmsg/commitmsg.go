@@ -0,0 +1,154 @@
+// Package commitmsg extracts structured information from commit messages,
+// such as the parts of a conventional commit header, the issues referenced
+// by the message or the commit reverted by it.
+package commitmsg
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidPattern is returned when an issue reference pattern is not a
+// valid regular expression.
+var ErrInvalidPattern = errors.NewKind("invalid issue reference pattern %q: %s")
+
+// ConventionalCommit is the header of a commit message following the
+// Conventional Commits specification (https://www.conventionalcommits.org).
+type ConventionalCommit struct {
+	Type     string `json:"type"`
+	Scope    string `json:"scope"`
+	Breaking bool   `json:"breaking"`
+	Subject  string `json:"subject"`
+}
+
+var (
+	headerRegex   = regexp.MustCompile(`^([a-zA-Z][\w-]*)(?:\(([^()\r\n]*)\))?(!)?: +(\S.*)$`)
+	breakingRegex = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// ParseConventionalCommit parses the header of a conventional commit. A
+// commit is breaking if there is a "!" before the colon of the header or
+// the message has a "BREAKING CHANGE" footer. If the message does not
+// follow the specification, nil is returned.
+func ParseConventionalCommit(msg string) *ConventionalCommit {
+	header := msg
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		header = msg[:i]
+	}
+
+	m := headerRegex.FindStringSubmatch(strings.TrimSpace(header))
+	if m == nil {
+		return nil
+	}
+
+	return &ConventionalCommit{
+		Type:     strings.ToLower(m[1]),
+		Scope:    strings.TrimSpace(m[2]),
+		Breaking: m[3] != "" || breakingRegex.MatchString(msg[len(header):]),
+		Subject:  strings.TrimSpace(m[4]),
+	}
+}
+
+var jiraRegex = regexp.MustCompile(`\b([A-Z][A-Z0-9]*[A-Z][A-Z0-9]*-\d+)\b`)
+
+// DefaultIssuePatterns are the patterns used to find issue references when
+// no others are given. They match GitHub style references, such as "#123",
+// and Jira style keys, such as "PROJ-456". Jira keys need at least two
+// letters in the project key, and the names of well-known standards and
+// identifiers, such as "UTF-8" or "CVE-2020", are not issue references.
+var DefaultIssuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|[^\w&/#])(#\d+)\b`),
+	jiraRegex,
+}
+
+// notJiraProjects are prefixes matching the Jira key pattern that are
+// commonly used for other things in commit messages.
+var notJiraProjects = map[string]struct{}{
+	"AES": {}, "AGPL": {}, "ANSI": {}, "CP": {}, "CVE": {}, "CWE": {},
+	"ECMA": {}, "GPL": {}, "HTTP": {}, "IEC": {}, "IEEE": {}, "ISO": {},
+	"LGPL": {}, "MD": {}, "PEP": {}, "RFC": {}, "RSA": {}, "SHA": {},
+	"SSL": {}, "TLS": {}, "UCS": {}, "UTF": {},
+}
+
+func isJiraKey(ref string) bool {
+	project := ref[:strings.IndexByte(ref, '-')]
+	_, ok := notJiraProjects[project]
+	return !ok
+}
+
+// CompileIssuePattern compiles a pattern to find issue references.
+func CompileIssuePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, ErrInvalidPattern.New(pattern, err)
+	}
+
+	return re, nil
+}
+
+// IssueRefs returns the issue references found in the message using the
+// given patterns, in order of appearance and without duplicates. If a
+// pattern has capturing groups, the first one is the reference, otherwise
+// it's the whole match.
+func IssueRefs(msg string, patterns []*regexp.Regexp) []string {
+	type ref struct {
+		pos int
+		id  string
+	}
+
+	var found []ref
+	for _, re := range patterns {
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1
+		}
+
+		for _, m := range re.FindAllStringSubmatchIndex(msg, -1) {
+			start, end := m[2*group], m[2*group+1]
+			if start < 0 {
+				continue
+			}
+
+			if re == jiraRegex && !isJiraKey(msg[start:end]) {
+				continue
+			}
+
+			found = append(found, ref{start, msg[start:end]})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].pos < found[j].pos
+	})
+
+	refs := []string{}
+	seen := make(map[string]struct{})
+	for _, r := range found {
+		if _, ok := seen[r.id]; ok {
+			continue
+		}
+
+		seen[r.id] = struct{}{}
+		refs = append(refs, r.id)
+	}
+
+	return refs
+}
+
+var revertRegex = regexp.MustCompile(`(?m)^This reverts commit ([0-9a-fA-F]{7,40})\b`)
+
+// RevertedCommit returns the hash of the commit reverted by the one with
+// the given message, as found in the message generated by "git revert".
+// If the message is not the one of a revert commit, it returns an empty
+// string.
+func RevertedCommit(msg string) string {
+	m := revertRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return ""
+	}
+
+	return strings.ToLower(m[1])
+}
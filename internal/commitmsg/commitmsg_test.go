@@ -0,0 +1,81 @@
+package commitmsg
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	testCases := []struct {
+		msg      string
+		expected *ConventionalCommit
+	}{
+		{"feat: add foo", &ConventionalCommit{"feat", "", false, "add foo"}},
+		{"fix(parser): handle EOF\n\nbody", &ConventionalCommit{"fix", "parser", false, "handle EOF"}},
+		{"Refactor(api)!: drop v1", &ConventionalCommit{"refactor", "api", true, "drop v1"}},
+		{"feat!: new config", &ConventionalCommit{"feat", "", true, "new config"}},
+		{
+			"feat(cli): add flag\n\nbody\n\nBREAKING CHANGE: removes --foo",
+			&ConventionalCommit{"feat", "cli", true, "add flag"},
+		},
+		{
+			"chore: bump deps\n\nBREAKING-CHANGE: go 1.12 required",
+			&ConventionalCommit{"chore", "", true, "bump deps"},
+		},
+		{"fix: mention BREAKING CHANGE: in subject", &ConventionalCommit{"fix", "", false, "mention BREAKING CHANGE: in subject"}},
+		{"Add foo", nil},
+		{"feat:missing space", nil},
+		{"feat: ", nil},
+		{"Merge branch 'master' into feat: foo", nil},
+		{"", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.msg, func(t *testing.T) {
+			require.Equal(t, tt.expected, ParseConventionalCommit(tt.msg))
+		})
+	}
+}
+
+func TestIssueRefs(t *testing.T) {
+	require := require.New(t)
+
+	msg := "PROJ-456: fix crash (#12)\n\nFixes #3, refs #12 and ABC-1.\n" +
+		"See http://example.com/#7 and color &#39; or ##8."
+	require.Equal(
+		[]string{"PROJ-456", "#12", "#3", "ABC-1"},
+		IssueRefs(msg, DefaultIssuePatterns),
+	)
+
+	require.Equal([]string{}, IssueRefs("no references", DefaultIssuePatterns))
+	require.Equal([]string{}, IssueRefs(
+		"Use UTF-8 and SHA-256, see CVE-2020-1234, ISO-8601 and A-1",
+		DefaultIssuePatterns,
+	))
+	require.Equal([]string{"AB-1", "P2P-3"}, IssueRefs("AB-1 and P2P-3", DefaultIssuePatterns))
+
+	re, err := CompileIssuePattern(`gh-(\d+)`)
+	require.NoError(err)
+	require.Equal([]string{"1", "2"}, IssueRefs("gh-1 gh-2 gh-1 #3", []*regexp.Regexp{re}))
+
+	re, err = CompileIssuePattern(`!\d+`)
+	require.NoError(err)
+	require.Equal([]string{"!42"}, IssueRefs("see !42", []*regexp.Regexp{re}))
+
+	_, err = CompileIssuePattern(`(`)
+	require.True(ErrInvalidPattern.Is(err))
+}
+
+func TestRevertedCommit(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(
+		"e8d3ffab552895c19b9fcf7aa264d277cde33881",
+		RevertedCommit("Revert \"foo\"\n\nThis reverts commit E8D3FFAB552895C19B9FCF7AA264D277CDE33881.\n"),
+	)
+	require.Equal("e8d3ffa", RevertedCommit("revert: foo\n\nThis reverts commit e8d3ffa."))
+	require.Equal("", RevertedCommit("Revert \"foo\""))
+	require.Equal("", RevertedCommit("foo\n\nThis reverts commit xyz."))
+}
@@ -36,9 +36,10 @@ func (s *CommitStats) String() string {
 
 // Calculate calculates the CommitStats for from commit to another.
 // if from is nil the first parent is used, if the commit is orphan the stats
-// are compared against a empty commit.
-func Calculate(r *git.Repository, from, to *object.Commit) (*CommitStats, error) {
-	fs, err := CalculateByFile(r, from, to)
+// are compared against a empty commit. If detection is not nil, renamed and
+// copied files are compared with their source.
+func Calculate(r *git.Repository, from, to *object.Commit, d *Detection) (*CommitStats, error) {
+	fs, err := CalculateByFile(r, from, to, d)
 	if err != nil {
 		return nil, err
 	}
@@ -18,7 +18,12 @@ import (
 
 // CommitFileStats represents the stats for a file in a commit.
 type CommitFileStats struct {
-	Path     string
+	Path string
+	// OldPath is the path of the source of a renamed or copied file.
+	OldPath string
+	// Status is the status of the file: Added, Modified, Deleted, Renamed
+	// or Copied.
+	Status   string
 	Language string
 	Code     KindStats
 	Comment  KindStats
@@ -29,8 +34,10 @@ type CommitFileStats struct {
 
 // CalculateByFile calculates the stats for all files from a commit to another.
 // If from is nil, the first parent is used. if the commit is an orphan,
-// the stats are compared against an empty commit.
-func CalculateByFile(r *git.Repository, from, to *object.Commit) ([]CommitFileStats, error) {
+// the stats are compared against an empty commit. If detection is not nil,
+// renamed and copied files are compared with their source instead of
+// being reported as added files.
+func CalculateByFile(r *git.Repository, from, to *object.Commit, d *Detection) ([]CommitFileStats, error) {
 	var err error
 	if to.NumParents() != 0 && from == nil {
 		from, err = to.Parent(0)
@@ -43,7 +50,7 @@ func CalculateByFile(r *git.Repository, from, to *object.Commit) ([]CommitFileSt
 		return fileStatsFromCommit(to)
 	}
 
-	return fileStatsFromDiff(r, from, to)
+	return fileStatsFromDiff(r, from, to, d)
 }
 
 func fileStatsFromCommit(c *object.Commit) ([]CommitFileStats, error) {
@@ -64,6 +71,7 @@ func fileStatsFromCommit(c *object.Commit) ([]CommitFileStats, error) {
 		}
 
 		stats := commitFileStatsFromFileStats(fi, f.Name, lang)
+		stats.Status = Added
 		result = append(result, stats)
 		return nil
 	})
@@ -99,15 +107,20 @@ func commitFileStatsFromFileStats(fi fileStats, path, lang string) CommitFileSta
 	return stats
 }
 
-func fileStatsFromDiff(r *git.Repository, from, to *object.Commit) ([]CommitFileStats, error) {
+func fileStatsFromDiff(r *git.Repository, from, to *object.Commit, d *Detection) ([]CommitFileStats, error) {
 	ch, err := computeDiff(from, to)
 	if err != nil {
 		return nil, err
 	}
 
+	changes, err := detectRenames(r, ch, d)
+	if err != nil {
+		return nil, err
+	}
+
 	var result []CommitFileStats
-	for _, change := range ch {
-		s, err := fileStatsFromChange(r, change)
+	for _, change := range changes {
+		s, err := fileStatsFromChange(r, change.Change)
 		if err != nil {
 			if err == errIgnored {
 				continue
@@ -115,6 +128,11 @@ func fileStatsFromDiff(r *git.Repository, from, to *object.Commit) ([]CommitFile
 			return nil, err
 		}
 
+		s.Status = change.status
+		if change.status == Renamed || change.status == Copied {
+			s.OldPath = change.From.Name
+		}
+
 		result = append(result, s)
 	}
 
@@ -133,10 +151,14 @@ func fileStatsFromChange(r *git.Repository, ch *object.Change) (CommitFileStats,
 	switch a {
 	case merkletrie.Delete:
 		name = ch.From.Name
-		fi, err = changeEntryFileStats(r, &ch.From)
+		src, err := changeEntryFileStats(r, &ch.From)
 		if err != nil {
 			return CommitFileStats{}, err
 		}
+
+		// the lines of deleted files are deletions.
+		fi = make(fileStats)
+		fi.sub(src)
 	case merkletrie.Insert:
 		name = ch.To.Name
 		fi, err = changeEntryFileStats(r, &ch.To)
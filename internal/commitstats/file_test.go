@@ -53,36 +53,45 @@ func TestCalculateByFile(t *testing.T) {
 			expected: []CommitFileStats{
 				{
 					Path:     "common_test.go",
+					Status:   Modified,
 					Language: "Go",
 					Blank:    KindStats{Deletions: 1},
 					Total:    KindStats{Deletions: 1},
 				},
 				{
 					Path:     "core/storage.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 1},
 					Total:    KindStats{Additions: 1},
 				},
 				{
-					Path: "fixtures/data/pack-a3fed42da1e8189a077c0e6846c040dcf73fc9dd.idx",
+					Path:   "fixtures/data/pack-a3fed42da1e8189a077c0e6846c040dcf73fc9dd.idx",
+					Status: Added,
 				},
 				{
-					Path: "fixtures/data/pack-a3fed42da1e8189a077c0e6846c040dcf73fc9dd.pack",
+					Path:   "fixtures/data/pack-a3fed42da1e8189a077c0e6846c040dcf73fc9dd.pack",
+					Status: Added,
 				},
 				{
-					Path: "fixtures/data/pack-c544593473465e6315ad4182d04d366c4592b829.idx",
+					Path:   "fixtures/data/pack-c544593473465e6315ad4182d04d366c4592b829.idx",
+					Status: Added,
 				},
 				{
-					Path: "fixtures/data/pack-c544593473465e6315ad4182d04d366c4592b829.pack",
+					Path:   "fixtures/data/pack-c544593473465e6315ad4182d04d366c4592b829.pack",
+					Status: Added,
 				},
 				{
-					Path: "fixtures/data/pack-f2e0a8889a746f7600e07d2246a2e29a72f696be.idx",
+					Path:   "fixtures/data/pack-f2e0a8889a746f7600e07d2246a2e29a72f696be.idx",
+					Status: Added,
 				},
 				{
-					Path: "fixtures/data/pack-f2e0a8889a746f7600e07d2246a2e29a72f696be.pack",
+					Path:   "fixtures/data/pack-f2e0a8889a746f7600e07d2246a2e29a72f696be.pack",
+					Status: Added,
 				},
 				{
 					Path:     "fixtures/fixtures.go",
+					Status:   Added,
 					Language: "Go",
 					Code:     KindStats{Additions: 83},
 					Blank:    KindStats{Additions: 19},
@@ -90,6 +99,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/idxfile/decoder.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 3, Deletions: 1},
 					Blank:    KindStats{Deletions: 1},
@@ -97,6 +107,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/idxfile/decoder_test.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 31, Deletions: 11},
 					Blank:    KindStats{Additions: 7},
@@ -104,12 +115,14 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/idxfile/encoder.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 8, Deletions: 9},
 					Total:    KindStats{Additions: 8, Deletions: 9},
 				},
 				{
 					Path:     "formats/idxfile/encoder_test.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 16, Deletions: 27},
 					Comment:  KindStats{Deletions: 0},
@@ -119,10 +132,12 @@ func TestCalculateByFile(t *testing.T) {
 						Deletions: 30},
 				},
 				{
-					Path: "formats/idxfile/fixtures/git-fixture.idx",
+					Path:   "formats/idxfile/fixtures/git-fixture.idx",
+					Status: Deleted,
 				},
 				{
 					Path:     "formats/idxfile/idxfile.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 8, Deletions: 1},
 					Blank:    KindStats{Additions: 1},
@@ -130,6 +145,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/packfile/decoder.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 56, Deletions: 70},
 					Comment:  KindStats{Additions: 2, Deletions: 9},
@@ -138,6 +154,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/packfile/decoder_test.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 23, Deletions: 45},
 					Blank:    KindStats{Deletions: 3},
@@ -145,6 +162,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/packfile/parser.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 53, Deletions: 15},
 					Blank:    KindStats{Additions: 9},
@@ -152,6 +170,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "formats/packfile/parser_test.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 91, Deletions: 59},
 					Comment:  KindStats{Deletions: 328},
@@ -160,6 +179,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "storage/filesystem/internal/dotgit/dotgit.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 23, Deletions: 22},
 					Blank:    KindStats{Additions: 2},
@@ -167,12 +187,14 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "storage/filesystem/internal/index/index.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 8, Deletions: 4},
 					Total:    KindStats{Additions: 8, Deletions: 4},
 				},
 				{
 					Path:     "storage/filesystem/object.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 3},
 					Blank:    KindStats{Additions: 1},
@@ -180,6 +202,7 @@ func TestCalculateByFile(t *testing.T) {
 				},
 				{
 					Path:     "storage/memory/storage.go",
+					Status:   Modified,
 					Language: "Go",
 					Code:     KindStats{Additions: 7},
 					Blank:    KindStats{Additions: 3},
@@ -193,6 +216,7 @@ func TestCalculateByFile(t *testing.T) {
 			expected: []CommitFileStats{
 				{
 					Path:     "LICENSE",
+					Status:   Added,
 					Language: "Text",
 					Other:    KindStats{Additions: 22},
 					Total:    KindStats{Additions: 22},
@@ -204,16 +228,17 @@ func TestCalculateByFile(t *testing.T) {
 			to:      plumbing.NewHash("b8e471f58bcbca63b07bda20e428190409c2db47"),
 			expected: []CommitFileStats{
 				{
-					Path:  "CHANGELOG",
-					Other: KindStats{Additions: 1},
-					Total: KindStats{Additions: 1},
+					Path:   "CHANGELOG",
+					Status: Added,
+					Other:  KindStats{Additions: 1},
+					Total:  KindStats{Additions: 1},
 				},
 			},
 		},
 		"binary": {
 			fixture:  fixtures.Basic().One(),
 			to:       plumbing.NewHash("35e85108805c84807bc66a02d91535e1e24b38b9"),
-			expected: []CommitFileStats{{Path: "binary.jpg"}},
+			expected: []CommitFileStats{{Path: "binary.jpg", Status: Added}},
 		},
 		"vendor": {
 			fixture:  fixtures.Basic().One(),
@@ -244,7 +269,7 @@ func TestCalculateByFile(t *testing.T) {
 				require.NoError(err)
 			}
 
-			stats, err := CalculateByFile(r, from, to)
+			stats, err := CalculateByFile(r, from, to, nil)
 			require.NoError(err)
 
 			assert.Equal(t, test.expected, stats)
@@ -0,0 +1,287 @@
+package commitstats
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/utils/diff"
+)
+
+// patchContextLines is the number of unchanged lines around the changes
+// that are part of the patch, as in the default unified diff of git.
+const patchContextLines = 3
+
+// PatchID computes the stable patch ID of the changes of a commit, which
+// is the same for commits introducing the same changes, such as cherry
+// picked or rebased ones. It's the one computed by "git patch-id --stable"
+// with full blob hashes. The changes are the ones since its first parent,
+// or since an empty tree if the commit is an orphan.
+func PatchID(c *object.Commit) (plumbing.Hash, error) {
+	var from *object.Tree
+	if c.NumParents() != 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		from, err = parent.Tree()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	to, err := c.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	// Files are sorted by path, as they are in the patches of git.
+	sort.Slice(changes, func(i, j int) bool {
+		return changePath(changes[i]) < changePath(changes[j])
+	})
+
+	// The patch ID of each file is computed separately and then added, so
+	// the result does not depend on the order of the files. As in git,
+	// the file following a binary file is part of its patch, and if the
+	// last file is binary an empty patch is added.
+	var id plumbing.Hash
+	var binary bool
+	h := sha1.New()
+	for _, ch := range changes {
+		binary, err = writeChangePatch(h, ch, !binary)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		addPatchHash(&id, h)
+	}
+
+	if binary {
+		addPatchHash(&id, h)
+	}
+
+	return id, nil
+}
+
+// addPatchHash adds the hash of a patch to the patch ID and resets it.
+func addPatchHash(id *plumbing.Hash, h hash.Hash) {
+	var carry uint
+	sum := h.Sum(nil)
+	for i := range id {
+		carry += uint(id[i]) + uint(sum[i])
+		id[i] = byte(carry)
+		carry >>= 8
+	}
+
+	h.Reset()
+}
+
+func changePath(ch *object.Change) string {
+	if ch.To.Name != "" {
+		return ch.To.Name
+	}
+	return ch.From.Name
+}
+
+// writeChangePatch writes the patch of a change to the hash, as written
+// by "git patch-id", that is, without white spaces, blob hashes, hunk
+// headers or missing newline markers. The first line of the patch is
+// only written if header is true. It returns whether the file is binary.
+func writeChangePatch(h hash.Hash, ch *object.Change, header bool) (bool, error) {
+	from, to, err := ch.Files()
+	if err != nil {
+		return false, err
+	}
+
+	fromPath, toPath := ch.From.Name, ch.To.Name
+	if from == nil {
+		fromPath = toPath
+	}
+
+	if to == nil {
+		toPath = fromPath
+	}
+
+	fromPath, toPath = removeSpaces(fromPath), removeSpaces(toPath)
+	if header {
+		writePatchLine(h, "diff--gita/"+fromPath+"b/"+toPath)
+	}
+
+	switch {
+	case from == nil:
+		writePatchLine(h, "newfilemode"+fileModeString(ch.To.TreeEntry.Mode))
+	case to == nil:
+		writePatchLine(h, "deletedfilemode"+fileModeString(ch.From.TreeEntry.Mode))
+	case ch.From.TreeEntry.Mode != ch.To.TreeEntry.Mode:
+		writePatchLine(h, "oldmode"+fileModeString(ch.From.TreeEntry.Mode))
+		writePatchLine(h, "newmode"+fileModeString(ch.To.TreeEntry.Mode))
+	}
+
+	fromContent, fromBinary, err := patchFileContent(from)
+	if err != nil {
+		return false, err
+	}
+
+	toContent, toBinary, err := patchFileContent(to)
+	if err != nil {
+		return false, err
+	}
+
+	// the content of binary files is not part of the patch, only the
+	// hashes of the blobs are.
+	if fromBinary || toBinary {
+		if ch.From.TreeEntry.Hash == ch.To.TreeEntry.Hash {
+			return false, nil
+		}
+
+		writePatchLine(h, ch.From.TreeEntry.Hash.String())
+		writePatchLine(h, ch.To.TreeEntry.Hash.String())
+		return true, nil
+	}
+
+	lines := unifiedDiffLines(fromContent, toContent)
+	if len(lines) == 0 {
+		return false, nil
+	}
+
+	if from == nil {
+		writePatchLine(h, "---/dev/null")
+	} else {
+		writePatchLine(h, "---a/"+fromPath)
+	}
+
+	if to == nil {
+		writePatchLine(h, "+++/dev/null")
+	} else {
+		writePatchLine(h, "+++b/"+toPath)
+	}
+
+	for _, line := range lines {
+		writePatchLine(h, removeSpaces(line))
+	}
+
+	return false, nil
+}
+
+func writePatchLine(h hash.Hash, line string) {
+	h.Write([]byte(line))
+}
+
+func fileModeString(m filemode.FileMode) string {
+	return fmt.Sprintf("%06o", uint32(m))
+}
+
+// patchFileContent returns the content of the file and whether it's a
+// binary file. If the file is nil, it's empty.
+func patchFileContent(f *object.File) (string, bool, error) {
+	if f == nil {
+		return "", false, nil
+	}
+
+	isBinary, err := isBinary(&f.Blob)
+	if err != nil || isBinary {
+		return "", isBinary, err
+	}
+
+	content, err := f.Contents()
+	return content, false, err
+}
+
+// removeSpaces removes all the white space characters of a string.
+func removeSpaces(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// unifiedDiffLines returns the lines of the unified diff between two
+// contents, prefixed by the kind of line, without hunk headers.
+func unifiedDiffLines(from, to string) []string {
+	var lines []string
+	var equal []string
+	var deleted, inserted []string
+
+	flushChanges := func() {
+		if len(deleted)+len(inserted) == 0 {
+			return
+		}
+
+		// unchanged lines between two changes are only kept if they are
+		// the context of any of them.
+		if len(lines) == 0 {
+			equal = lastLines(equal, patchContextLines)
+		} else if len(equal) > 2*patchContextLines {
+			equal = append(
+				equal[:patchContextLines:patchContextLines],
+				lastLines(equal, patchContextLines)...,
+			)
+		}
+
+		lines = append(lines, equal...)
+		lines = append(lines, deleted...)
+		lines = append(lines, inserted...)
+		equal, deleted, inserted = nil, nil, nil
+	}
+
+	for _, d := range diff.Do(from, to) {
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			flushChanges()
+			equal = append(equal, diffLines(' ', d.Text)...)
+		case diffmatchpatch.DiffDelete:
+			deleted = append(deleted, diffLines('-', d.Text)...)
+		case diffmatchpatch.DiffInsert:
+			inserted = append(inserted, diffLines('+', d.Text)...)
+		}
+	}
+
+	flushChanges()
+	if len(lines) > 0 && len(equal) > 0 {
+		if len(equal) > patchContextLines {
+			equal = equal[:patchContextLines]
+		}
+		lines = append(lines, equal...)
+	}
+
+	return lines
+}
+
+func lastLines(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// diffLines splits a text in lines prefixed by the given kind.
+func diffLines(kind byte, text string) []string {
+	var lines []string
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			lines = append(lines, string(kind)+text)
+			break
+		}
+
+		lines = append(lines, string(kind)+text[:i])
+		text = text[i+1:]
+	}
+
+	return lines
+}
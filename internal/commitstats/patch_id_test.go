@@ -0,0 +1,96 @@
+package commitstats
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	fixtures "github.com/src-d/go-git-fixtures"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+)
+
+func TestPatchID(t *testing.T) {
+	defer func() {
+		require.NoError(t, fixtures.Clean())
+	}()
+
+	r, err := git.Open(filesystem.NewStorage(fixtures.Basic().One().DotGit(), cache.NewObjectLRUDefault()), nil)
+	require.NoError(t, err)
+
+	// expected values are the ones of "git patch-id --stable"
+	tests := map[string]struct {
+		commit   string
+		expected string
+	}{
+		"orphan": {
+			commit:   "b029517f6300c2da0f4b651b8642506cd6aaf45d",
+			expected: "b232dee86c61f46101a4c0df249440e1785dc785",
+		},
+		"binary": {
+			commit:   "35e85108805c84807bc66a02d91535e1e24b38b9",
+			expected: "4f8afef256fb942166f324df9c76749e10df3bc0",
+		},
+		"modified": {
+			commit:   "b8e471f58bcbca63b07bda20e428190409c2db47",
+			expected: "654add56d9609afaaac7c05388967247d466a307",
+		},
+		"merge": {
+			commit:   "1669dce138d9b841a518c64b10914d88f5e488ea",
+			expected: "654add56d9609afaaac7c05388967247d466a307",
+		},
+		"several files": {
+			commit:   "918c48b83bd081e863dbe1b80f8998f058cd8294",
+			expected: "eb2afea66d13c50c2981fbae5e354e842271eca8",
+		},
+		"vendor": {
+			commit:   "6ecf0ef2c2dffb796033e5a02219af86ec6584e5",
+			expected: "f918db3e4e98c331403ae6de1e2abc3a85f9fa6c",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			c, err := r.CommitObject(plumbing.NewHash(test.commit))
+			require.NoError(err)
+
+			id, err := PatchID(c)
+			require.NoError(err)
+			require.Equal(test.expected, id.String())
+		})
+	}
+}
+
+func TestUnifiedDiffLines(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, strconv.Itoa(i))
+	}
+	from := strings.Join(lines, "\n") + "\n"
+
+	replace := func(s string, lines ...string) string {
+		for _, l := range lines {
+			s = strings.Replace(s, "\n"+l+"\n", "\n"+l+"b\n", 1)
+		}
+		return s
+	}
+
+	require := require.New(t)
+	require.Equal([]string{
+		" 2", " 3", " 4", "-5", "+5b", " 6", " 7", " 8", " 9", " 10", " 11",
+		"-12", "+12b", " 13", " 14", " 15",
+	}, unifiedDiffLines(from, replace(from, "5", "12")))
+
+	require.Equal([]string{
+		" 2", " 3", " 4", "-5", "+5b", " 6", " 7", " 8",
+		" 10", " 11", " 12", "-13", "+13b", " 14", " 15", " 16",
+	}, unifiedDiffLines(from, replace(from, "5", "13")))
+
+	require.Equal([]string{" 18", " 19", " 20", "+21"}, unifiedDiffLines(from, from+"21"))
+	require.Empty(unifiedDiffLines(from, from))
+}
@@ -0,0 +1,303 @@
+package commitstats
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/src-d/enry/v2"
+	errors "gopkg.in/src-d/go-errors.v1"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
+)
+
+// Status of a file in the changes between two commits, with the same
+// letters used by "git diff --name-status".
+const (
+	Added    = "A"
+	Modified = "M"
+	Deleted  = "D"
+	Renamed  = "R"
+	Copied   = "C"
+)
+
+// DefaultSimilarityThreshold is the similarity threshold used when the
+// detection mode does not give one, the same default used by git.
+const DefaultSimilarityThreshold = 50
+
+// ErrInvalidDetectionMode is returned when a rename and copy detection
+// mode can't be parsed.
+var ErrInvalidDetectionMode = errors.NewKind("invalid rename detection mode %q, expected -M[<n>] or -C[<n>]")
+
+// Detection configures how renamed and copied files are detected.
+type Detection struct {
+	// Copies enables the detection of copies, besides renames. The
+	// sources of copies are the files modified or deleted by the changes.
+	Copies bool
+	// Threshold is the minimum similarity, between 0 and 100, of a
+	// deleted or modified file and an added one to consider the latter a
+	// rename or copy of the former.
+	Threshold int
+}
+
+// ParseDetection parses a detection mode with the syntax of the -M and -C
+// options of git: "M" or "-M" detects renames and "C" or "-C" also copies,
+// followed by an optional similarity threshold. As in git, the threshold
+// is a percentage if it ends with "%", so "-M90%" means 90%, and a
+// fraction with the decimal point before it otherwise, so "-M9" also
+// means 90% and "-M05" means 5%. An empty mode disables the detection and
+// returns nil.
+func ParseDetection(mode string) (*Detection, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(mode), "-")
+	if s == "" {
+		return nil, nil
+	}
+
+	d := &Detection{Threshold: DefaultSimilarityThreshold}
+	switch s[0] {
+	case 'M', 'm':
+	case 'C', 'c':
+		d.Copies = true
+	default:
+		return nil, ErrInvalidDetectionMode.New(mode)
+	}
+
+	s = s[1:]
+	if s == "" {
+		return d, nil
+	}
+
+	if strings.HasSuffix(s, "%") {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 || n > 100 {
+			return nil, ErrInvalidDetectionMode.New(mode)
+		}
+
+		d.Threshold = n
+		return d, nil
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return nil, ErrInvalidDetectionMode.New(mode)
+		}
+	}
+
+	// only the first two digits of the fraction matter for a percentage.
+	digits := (s + "00")[:2]
+	d.Threshold, _ = strconv.Atoi(digits)
+	return d, nil
+}
+
+// detectedChange is a change between two commits with its status.
+type detectedChange struct {
+	*object.Change
+	status string
+}
+
+// detectRenames returns the changes with their status. If detection is
+// enabled, the added files that are similar enough to a deleted file are
+// paired with it as renames, and, if copies are enabled too, the rest of
+// them to a modified or deleted file as copies. Vendored files are never
+// paired.
+func detectRenames(r *git.Repository, changes object.Changes, d *Detection) ([]detectedChange, error) {
+	result := make([]detectedChange, len(changes))
+	var added, deleted, modified []int
+	for i, ch := range changes {
+		a, err := ch.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = detectedChange{Change: ch}
+		switch a {
+		case merkletrie.Insert:
+			result[i].status = Added
+			if !enry.IsVendor(ch.To.Name) {
+				added = append(added, i)
+			}
+		case merkletrie.Delete:
+			result[i].status = Deleted
+			if !enry.IsVendor(ch.From.Name) {
+				deleted = append(deleted, i)
+			}
+		case merkletrie.Modify:
+			result[i].status = Modified
+			if !enry.IsVendor(ch.From.Name) {
+				modified = append(modified, i)
+			}
+		}
+	}
+
+	if d == nil || len(added) == 0 || len(deleted)+len(modified) == 0 {
+		return result, nil
+	}
+
+	contents := make(map[int]*fileContent)
+	content := func(i int, e *object.ChangeEntry) (*fileContent, error) {
+		if c, ok := contents[i]; ok {
+			return c, nil
+		}
+
+		c, err := newFileContent(r, e)
+		if err != nil {
+			return nil, err
+		}
+
+		contents[i] = c
+		return c, nil
+	}
+
+	// the candidates are scored and paired from the most similar ones, so
+	// every file is paired with its best match.
+	type candidate struct {
+		src, dst int
+		score    int
+	}
+
+	var renames, copies []candidate
+	for _, dst := range added {
+		to, err := content(dst, &changes[dst].To)
+		if err != nil {
+			return nil, err
+		}
+
+		sources := deleted
+		if d.Copies {
+			sources = append(append([]int(nil), deleted...), modified...)
+		}
+
+		for _, src := range sources {
+			from, err := content(src, &changes[src].From)
+			if err != nil {
+				return nil, err
+			}
+
+			score := similarity(from, to)
+			if score < d.Threshold {
+				continue
+			}
+
+			c := candidate{src, dst, score}
+			if result[src].status == Deleted {
+				renames = append(renames, c)
+			}
+			if d.Copies {
+				copies = append(copies, c)
+			}
+		}
+	}
+
+	paired := make(map[int]bool)
+	pair := func(candidates []candidate, status string, once bool) {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].score > candidates[j].score
+		})
+
+		for _, c := range candidates {
+			if paired[c.dst] || (once && paired[c.src]) {
+				continue
+			}
+
+			paired[c.dst], paired[c.src] = true, true
+			result[c.dst] = detectedChange{
+				Change: &object.Change{From: changes[c.src].From, To: changes[c.dst].To},
+				status: status,
+			}
+			if status == Renamed {
+				result[c.src].status = ""
+			}
+		}
+	}
+
+	pair(renames, Renamed, true)
+	pair(copies, Copied, false)
+
+	// deleted files renamed to others are not a change by themselves.
+	var detected []detectedChange
+	for _, ch := range result {
+		if ch.status != "" {
+			detected = append(detected, ch)
+		}
+	}
+
+	return detected, nil
+}
+
+// fileContent is the content of a file used to compute its similarity with
+// others.
+type fileContent struct {
+	hash   string
+	size   int
+	binary bool
+	lines  map[string]int
+}
+
+func newFileContent(r *git.Repository, e *object.ChangeEntry) (*fileContent, error) {
+	blob, err := r.BlobObject(e.TreeEntry.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &fileContent{hash: e.TreeEntry.Hash.String(), size: int(blob.Size)}
+	c.binary, err = isBinary(blob)
+	if err != nil || c.binary {
+		return c, err
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		return nil, err
+	}
+
+	c.lines = make(map[string]int)
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			c.lines[line]++
+		}
+	}
+
+	return c, nil
+}
+
+// similarity returns the percentage of the content of two files that is
+// the same, computed as git does: the size of the lines common to both
+// divided by the size of the largest file. Binary files are only similar
+// to identical files.
+func similarity(a, b *fileContent) int {
+	if a.hash == b.hash {
+		return 100
+	}
+
+	if a.binary || b.binary {
+		return 0
+	}
+
+	max := a.size
+	if b.size > max {
+		max = b.size
+	}
+
+	if max == 0 {
+		return 100
+	}
+
+	var common int
+	for line, n := range a.lines {
+		if m := b.lines[line]; m < n {
+			common += m * len(line)
+		} else {
+			common += n * len(line)
+		}
+	}
+
+	return common * 100 / max
+}
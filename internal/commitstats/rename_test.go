@@ -0,0 +1,170 @@
+package commitstats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestParseDetection(t *testing.T) {
+	testCases := []struct {
+		mode     string
+		expected *Detection
+		err      bool
+	}{
+		{"", nil, false},
+		{"M", &Detection{Threshold: 50}, false},
+		{"-M", &Detection{Threshold: 50}, false},
+		{"C", &Detection{Copies: true, Threshold: 50}, false},
+		{"-M90%", &Detection{Threshold: 90}, false},
+		{"-M9", &Detection{Threshold: 90}, false},
+		{"-M05", &Detection{Threshold: 5}, false},
+		{"-C75", &Detection{Copies: true, Threshold: 75}, false},
+		{"-M100%", &Detection{Threshold: 100}, false},
+		{"-M101%", nil, true},
+		{"-Mfoo", nil, true},
+		{"-X", nil, true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.mode, func(t *testing.T) {
+			require := require.New(t)
+			d, err := ParseDetection(tt.mode)
+			if tt.err {
+				require.True(ErrInvalidDetectionMode.Is(err))
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tt.expected, d)
+		})
+	}
+}
+
+func TestCalculateByFileRenames(t *testing.T) {
+	fs := memfs.New()
+	r, err := git.Init(memory.NewStorage(), fs)
+	require.NoError(t, err)
+
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	goCode := "package foo\n\nfunc a() {}\n\nfunc b() {}\n\nfunc c() {}\n\nfunc d() {}\n"
+	pyCode := "def a():\n    pass\n\ndef b():\n    pass\n"
+
+	commit := func(files map[string]string, removed ...string) *object.Commit {
+		for path, content := range files {
+			require.NoError(t, util.WriteFile(fs, path, []byte(content), 0644))
+			_, err := w.Add(path)
+			require.NoError(t, err)
+		}
+
+		for _, path := range removed {
+			_, err := w.Remove(path)
+			require.NoError(t, err)
+		}
+
+		sig := &object.Signature{Name: "foo", Email: "foo@bar.com", When: time.Now()}
+		h, err := w.Commit("commit", &git.CommitOptions{Author: sig})
+		require.NoError(t, err)
+
+		c, err := r.CommitObject(h)
+		require.NoError(t, err)
+		return c
+	}
+
+	commit(map[string]string{
+		"old.go":   goCode,
+		"keep.py":  pyCode,
+		"gone.txt": strings.Repeat("gone\n", 3),
+	})
+
+	to := commit(map[string]string{
+		"new/moved.go": goCode + "\nfunc e() {}\n",
+		"keep.py":      pyCode + "\ndef c():\n    pass\n",
+		"copy.py":      pyCode,
+	}, "old.go", "gone.txt")
+
+	added := func(path string, code, blank int) CommitFileStats {
+		return CommitFileStats{
+			Path:     path,
+			Status:   Added,
+			Language: "Python",
+			Code:     KindStats{Additions: code},
+			Blank:    KindStats{Additions: blank},
+			Total:    KindStats{Additions: code + blank},
+		}
+	}
+
+	copyPy := added("copy.py", 4, 1)
+	gone := CommitFileStats{
+		Path:     "gone.txt",
+		Status:   Deleted,
+		Language: "Text",
+		Other:    KindStats{Deletions: 3},
+		Total:    KindStats{Deletions: 3},
+	}
+	keep := added("keep.py", 2, 1)
+	keep.Status = Modified
+	moved := CommitFileStats{
+		Path:     "new/moved.go",
+		Status:   Added,
+		Language: "Go",
+		Code:     KindStats{Additions: 6},
+		Blank:    KindStats{Additions: 5},
+		Total:    KindStats{Additions: 11},
+	}
+	old := CommitFileStats{
+		Path:     "old.go",
+		Status:   Deleted,
+		Language: "Go",
+		Code:     KindStats{Deletions: 5},
+		Blank:    KindStats{Deletions: 4},
+		Total:    KindStats{Deletions: 9},
+	}
+
+	renamed := CommitFileStats{
+		Path:     "new/moved.go",
+		OldPath:  "old.go",
+		Status:   Renamed,
+		Language: "Go",
+		Code:     KindStats{Additions: 1},
+		Blank:    KindStats{Additions: 1},
+		Total:    KindStats{Additions: 2},
+	}
+
+	copied := CommitFileStats{
+		Path:     "copy.py",
+		OldPath:  "keep.py",
+		Status:   Copied,
+		Language: "Python",
+	}
+
+	testCases := []struct {
+		mode     string
+		expected []CommitFileStats
+	}{
+		{"", []CommitFileStats{copyPy, gone, keep, moved, old}},
+		{"-M", []CommitFileStats{copyPy, gone, keep, renamed}},
+		{"-M100%", []CommitFileStats{copyPy, gone, keep, moved, old}},
+		{"-C", []CommitFileStats{copied, gone, keep, renamed}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.mode, func(t *testing.T) {
+			d, err := ParseDetection(tt.mode)
+			require.NoError(t, err)
+
+			stats, err := CalculateByFile(r, nil, to, d)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, stats)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package function
+
+import (
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// ResetCaches creates again the caches of the functions, with the sizes
+// given by the environment variables, and reads again the maximum size of
+// the blobs sent to bblfsh. It allows changing those variables after the
+// package is initialized, and must be called before running any query.
+func ResetCaches() error {
+	caches := []struct {
+		cache **lru.TwoQueueCache
+		size  int
+	}{
+		{&languageCache, languageCacheSize()},
+		{&attributesCache, attributesCacheSize()},
+		{&commitLOCCache, commitLOCCacheSize()},
+		{&codeOwnersCache, codeOwnersCacheSize()},
+		{&ignoreCache, ignoreCacheSize()},
+		{&patchIDCache, patchIDCacheSize()},
+		{&treeLanguagesCache, treeLanguagesCacheSize()},
+	}
+
+	for _, c := range caches {
+		cache, err := lru.New2Q(c.size)
+		if err != nil {
+			return err
+		}
+
+		*c.cache = cache
+	}
+
+	cache, err := lru.New(uastCacheSize())
+	if err != nil {
+		return err
+	}
+
+	uastCache = cache
+	uastMaxBlobSize = uastMaxBlobSizeFromEnv()
+	return nil
+}
+
+// CacheStats holds the number of entries of a cache of the functions and
+// the maximum number of entries it can hold.
+type CacheStats struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	Size    int    `json:"size"`
+}
+
+// Caches returns the statistics of the caches of the functions. They are
+// named after their GITBASE_*_CACHE_SIZE environment variables.
+func Caches() []CacheStats {
+	return []CacheStats{
+		{"attributes", attributesCache.Len(), attributesCacheSize()},
+		{"code-owners", codeOwnersCache.Len(), codeOwnersCacheSize()},
+		{"commit-loc", commitLOCCache.Len(), commitLOCCacheSize()},
+		{"ignore", ignoreCache.Len(), ignoreCacheSize()},
+		{"language", languageCache.Len(), languageCacheSize()},
+		{"patch-id", patchIDCache.Len(), patchIDCacheSize()},
+		{"tree-languages", treeLanguagesCache.Len(), treeLanguagesCacheSize()},
+		{"uast", uastCache.Len(), uastCacheSize()},
+	}
+}
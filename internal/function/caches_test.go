@@ -0,0 +1,55 @@
+package function
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetCaches(t *testing.T) {
+	require := require.New(t)
+
+	defer func() {
+		os.Unsetenv(languageCacheSizeKey)
+		os.Unsetenv(uastMaxBlobSizeKey)
+		require.NoError(ResetCaches())
+	}()
+
+	languageCache.Add("foo", "bar")
+	require.NoError(os.Setenv(languageCacheSizeKey, "4"))
+	require.NoError(os.Setenv(uastMaxBlobSizeKey, "42"))
+	require.NoError(ResetCaches())
+
+	require.Equal(0, languageCache.Len())
+	require.Equal(42, uastMaxBlobSize)
+
+	for i := 0; i < 10; i++ {
+		languageCache.Add(i, i)
+	}
+	require.Equal(4, languageCache.Len())
+}
+
+func TestCaches(t *testing.T) {
+	require := require.New(t)
+
+	defer func() {
+		os.Unsetenv(languageCacheSizeKey)
+		require.NoError(ResetCaches())
+	}()
+
+	require.NoError(os.Setenv(languageCacheSizeKey, "4"))
+	require.NoError(ResetCaches())
+	languageCache.Add("foo", "bar")
+
+	stats := Caches()
+	require.Len(stats, 8)
+
+	var language CacheStats
+	for _, s := range stats {
+		if s.Name == "language" {
+			language = s
+		}
+	}
+	require.Equal(CacheStats{"language", 1, 4}, language)
+}
@@ -0,0 +1,404 @@
+package function
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+
+	bblfsh "github.com/bblfsh/go-client/v4"
+	derrors "github.com/bblfsh/sdk/v3/driver/errors"
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/role"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+var (
+	aliasType         = uast.TypeOf(uast.Alias{})
+	functionType      = uast.TypeOf(uast.Function{})
+	functionGroupType = uast.TypeOf(uast.FunctionGroup{})
+)
+
+// FunctionMetrics holds the metrics of a single function.
+type FunctionMetrics struct {
+	Name       string `json:"Name"`
+	Line       int    `json:"Line"`
+	Length     int    `json:"Length"`
+	Params     int    `json:"Params"`
+	Complexity int    `json:"Complexity"`
+	Nesting    int    `json:"Nesting"`
+}
+
+// FileMetrics is the result of the CodeMetrics function for each file.
+type FileMetrics struct {
+	Language   string            `json:"Language"`
+	Complexity int               `json:"Complexity"`
+	Functions  []FunctionMetrics `json:"Functions"`
+}
+
+// codeMetricsFunc shouldn't be used as an sql.Expression itself.
+// It's intended to be embedded in functions computing metrics
+// from semantic UASTs, like CodeMetrics and CyclomaticComplexity.
+type codeMetricsFunc struct {
+	Lang sql.Expression
+	Blob sql.Expression
+
+	h hash.Hash
+	m sync.Mutex
+}
+
+func newCodeMetricsFunc(lang, blob sql.Expression) *codeMetricsFunc {
+	return &codeMetricsFunc{Lang: lang, Blob: blob, h: sha1.New()}
+}
+
+// Resolved implements the Expression interface.
+func (f *codeMetricsFunc) Resolved() bool {
+	return f.Lang.Resolved() && f.Blob.Resolved()
+}
+
+// IsNullable implements the Expression interface.
+func (f *codeMetricsFunc) IsNullable() bool {
+	return true
+}
+
+// Children implements the Expression interface.
+func (f *codeMetricsFunc) Children() []sql.Expression {
+	return []sql.Expression{f.Lang, f.Blob}
+}
+
+func (f *codeMetricsFunc) metrics(ctx *sql.Context, row sql.Row) (*FileMetrics, error) {
+	lang, err := exprToString(ctx, f.Lang, row)
+	if err != nil {
+		return nil, err
+	}
+
+	lang = strings.ToLower(lang)
+
+	blob, err := f.Blob.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if blob == nil {
+		return nil, nil
+	}
+
+	blob, err = sql.Blob.Convert(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	bytes := blob.([]byte)
+	if len(bytes) == 0 {
+		return nil, nil
+	}
+
+	node, err := semanticUAST(ctx, f.h, &f.m, lang, bytes)
+	if err != nil || node == nil {
+		return nil, err
+	}
+
+	return fileMetrics(lang, node), nil
+}
+
+// semanticUAST returns the semantic UAST of the blob, using the UAST
+// cache. The hash used to compute the cache key is guarded by the given
+// mutex. If the blob is too big or can't be parsed, nil is returned.
+func semanticUAST(
+	ctx *sql.Context,
+	h hash.Hash,
+	m *sync.Mutex,
+	lang string,
+	blob []byte,
+) (nodes.Node, error) {
+	if isUASTBlobTooBig(ctx, blob) {
+		return nil, nil
+	}
+
+	mode := bblfsh.Semantic
+	m.Lock()
+	key, err := computeKey(h, mode.String(), lang, blob)
+	m.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	node, _, err := getCachedUAST(ctx, key, blob, lang, mode)
+	if err != nil {
+		if ErrParseBlob.Is(err) || derrors.ErrSyntax.Is(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// CodeMetrics returns the metrics of every function in a blob, such as
+// its cyclomatic complexity, nesting depth or number of parameters.
+type CodeMetrics struct {
+	*codeMetricsFunc
+}
+
+// NewCodeMetrics creates a new CodeMetrics UDF.
+func NewCodeMetrics(lang, blob sql.Expression) sql.Expression {
+	return &CodeMetrics{newCodeMetricsFunc(lang, blob)}
+}
+
+// String implements the fmt.Stringer interface.
+func (f *CodeMetrics) String() string {
+	return fmt.Sprintf("code_metrics(%s, %s)", f.Lang, f.Blob)
+}
+
+// Type implements the Expression interface.
+func (*CodeMetrics) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *CodeMetrics) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewCodeMetrics(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *CodeMetrics) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("code_metrics: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.CodeMetrics")
+	defer span.Finish()
+
+	m, err := f.metrics(ctx, row)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	return *m, nil
+}
+
+// CyclomaticComplexity returns the cyclomatic complexity of a blob, which
+// is the sum of the complexity of all its functions.
+type CyclomaticComplexity struct {
+	*codeMetricsFunc
+}
+
+// NewCyclomaticComplexity creates a new CyclomaticComplexity UDF.
+func NewCyclomaticComplexity(lang, blob sql.Expression) sql.Expression {
+	return &CyclomaticComplexity{newCodeMetricsFunc(lang, blob)}
+}
+
+// String implements the fmt.Stringer interface.
+func (f *CyclomaticComplexity) String() string {
+	return fmt.Sprintf("cyclomatic_complexity(%s, %s)", f.Lang, f.Blob)
+}
+
+// Type implements the Expression interface.
+func (*CyclomaticComplexity) Type() sql.Type {
+	return sql.Int64
+}
+
+// WithChildren implements the Expression interface.
+func (f *CyclomaticComplexity) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewCyclomaticComplexity(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *CyclomaticComplexity) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cyclomatic_complexity: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.CyclomaticComplexity")
+	defer span.Finish()
+
+	m, err := f.metrics(ctx, row)
+	if err != nil || m == nil {
+		return nil, err
+	}
+
+	return int64(m.Complexity), nil
+}
+
+// fileMetrics computes the metrics of all the functions found in the
+// given semantic UAST. Nested functions are reported on their own and
+// don't add up to the complexity of the function containing them.
+func fileMetrics(lang string, root nodes.Node) *FileMetrics {
+	m := &FileMetrics{Language: lang, Functions: []FunctionMetrics{}}
+
+	var visit func(n nodes.Node, name string, pos uast.Positions)
+	visit = func(n nodes.Node, name string, pos uast.Positions) {
+		switch n := n.(type) {
+		case nodes.Array:
+			for _, c := range n {
+				visit(c, "", nil)
+			}
+		case nodes.Object:
+			if p := uast.PositionsOf(n); p.Start() != nil {
+				pos = p
+			}
+
+			switch uast.TypeOf(n) {
+			case functionGroupType:
+				if ns, ok := n["Nodes"].(nodes.Array); ok {
+					for _, c := range ns {
+						visit(c, "", pos)
+					}
+				}
+				return
+			case aliasType:
+				if uast.TypeOf(n["Node"]) == functionType {
+					visit(n["Node"], aliasName(n), pos)
+					return
+				}
+			case functionType:
+				m.Functions = append(m.Functions, functionMetrics(n, name, pos))
+				visit(n["Body"], "", nil)
+				return
+			}
+
+			for _, k := range n.Keys() {
+				if !isCommonProp(k) {
+					visit(n[k], "", nil)
+				}
+			}
+		}
+	}
+
+	visit(root, "", nil)
+
+	for _, f := range m.Functions {
+		m.Complexity += f.Complexity
+	}
+
+	if len(m.Functions) == 0 {
+		var f FunctionMetrics
+		branchMetrics(root, 0, &f)
+		m.Complexity = f.Complexity + 1
+	}
+
+	return m
+}
+
+func aliasName(n nodes.Object) string {
+	name, ok := n["Name"].(nodes.Object)
+	if !ok {
+		return ""
+	}
+
+	s, _ := name["Name"].(nodes.String)
+	return string(s)
+}
+
+func functionMetrics(n nodes.Object, name string, pos uast.Positions) FunctionMetrics {
+	f := FunctionMetrics{Name: name, Complexity: 1}
+
+	if start, end := pos.Start(), pos.End(); start != nil {
+		f.Line = int(start.Line)
+		if end != nil && end.Line >= start.Line {
+			f.Length = int(end.Line-start.Line) + 1
+		}
+	}
+
+	if typ, ok := n["Type"].(nodes.Object); ok {
+		if args, ok := typ["Arguments"].(nodes.Array); ok {
+			for _, a := range args {
+				arg, ok := a.(nodes.Object)
+				if !ok {
+					continue
+				}
+
+				if receiver, _ := arg["Receiver"].(nodes.Bool); !receiver {
+					f.Params++
+				}
+			}
+		}
+	}
+
+	branchMetrics(n["Body"], 0, &f)
+	return f
+}
+
+// branchMetrics adds the decision points found in the given node to the
+// complexity of f and updates its maximum nesting depth. Nested functions
+// are not visited.
+func branchMetrics(n nodes.Node, depth int, f *FunctionMetrics) {
+	switch n := n.(type) {
+	case nodes.Array:
+		for _, c := range n {
+			branchMetrics(c, depth, f)
+		}
+	case nodes.Object:
+		if uast.TypeOf(n) == functionType {
+			return
+		}
+
+		roles := uast.RolesOf(n)
+		if isBranch(roles) {
+			f.Complexity++
+			depth++
+			if depth > f.Nesting {
+				f.Nesting = depth
+			}
+		} else if isBooleanOperator(roles) {
+			f.Complexity++
+		}
+
+		for _, k := range n.Keys() {
+			if !isCommonProp(k) {
+				branchMetrics(n[k], depth, f)
+			}
+		}
+	}
+}
+
+// isBranch reports whether the roles belong to a node that opens a new
+// execution path, such as an if statement or a loop, and not to one of
+// its parts, such as the condition or the body.
+func isBranch(roles role.Roles) bool {
+	if !hasAnyRole(roles,
+		role.If, role.Case, role.For, role.While, role.DoWhile, role.Catch,
+	) {
+		return false
+	}
+
+	return !hasAnyRole(roles,
+		role.Condition, role.Then, role.Else, role.Body, role.Default,
+		role.Initialization, role.Update, role.Iterator, role.Finally,
+	)
+}
+
+func isBooleanOperator(roles role.Roles) bool {
+	return hasAnyRole(roles, role.Operator) &&
+		hasAnyRole(roles, role.Boolean) &&
+		!hasAnyRole(roles, role.Bitwise) &&
+		hasAnyRole(roles, role.And, role.Or)
+}
+
+func hasAnyRole(roles role.Roles, expected ...role.Role) bool {
+	for _, r := range roles {
+		for _, e := range expected {
+			if r == e {
+				return true
+			}
+		}
+	}
+
+	return false
+}
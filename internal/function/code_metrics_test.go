@@ -0,0 +1,139 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/role"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeMetricsNullInputs(t *testing.T) {
+	fns := []sql.Expression{
+		NewCodeMetrics(
+			expression.NewGetField(0, sql.Text, "", true),
+			expression.NewGetField(1, sql.Blob, "", true),
+		),
+		NewCyclomaticComplexity(
+			expression.NewGetField(0, sql.Text, "", true),
+			expression.NewGetField(1, sql.Blob, "", true),
+		),
+	}
+
+	rows := []sql.Row{
+		sql.NewRow("Go", nil),
+		sql.NewRow("Go", []byte{}),
+	}
+
+	ctx := sql.NewEmptyContext()
+	for _, fn := range fns {
+		for _, row := range rows {
+			val, err := fn.Eval(ctx, row)
+			require.NoError(t, err)
+			require.Nil(t, val)
+		}
+	}
+}
+
+func TestFileMetrics(t *testing.T) {
+	require := require.New(t)
+
+	node := func(typ string, roles ...role.Role) nodes.Object {
+		n := nodes.Object{}
+		if typ != "" {
+			n[uast.KeyType] = nodes.String(typ)
+		}
+
+		if len(roles) > 0 {
+			n[uast.KeyRoles] = uast.RoleList(roles...)
+		}
+
+		return n
+	}
+
+	pos := func(start, end uint32) nodes.Object {
+		return uast.Positions{
+			uast.KeyStart: {Line: start, Col: 1},
+			uast.KeyEnd:   {Line: end, Col: 1},
+		}.ToObject()
+	}
+
+	and := node("", role.Operator, role.Boolean, role.And)
+	cond := node("", role.If, role.Condition)
+	cond["Op"] = and
+
+	inner := node("", role.Statement, role.If)
+	then := node("", role.If, role.Then, role.Body)
+	then["Stmt"] = inner
+
+	outer := node("", role.Statement, role.If)
+	outer["Cond"] = cond
+	outer["Then"] = then
+
+	loop := node("", role.Statement, role.For)
+	loop["Init"] = node("", role.For, role.Initialization)
+
+	closure := node(functionType)
+	closure["Body"] = nodes.Array{node("", role.Statement, role.If)}
+
+	receiver := node(uast.TypeOf(uast.Argument{}))
+	receiver["Receiver"] = nodes.Bool(true)
+
+	fnType := node(uast.TypeOf(uast.FunctionType{}))
+	fnType["Arguments"] = nodes.Array{
+		receiver,
+		node(uast.TypeOf(uast.Argument{})),
+		node(uast.TypeOf(uast.Argument{})),
+	}
+
+	body := node(uast.TypeOf(uast.Block{}))
+	body["Statements"] = nodes.Array{outer, loop, closure}
+
+	fn := node(functionType)
+	fn["Type"] = fnType
+	fn["Body"] = body
+
+	name := node(uast.TypeOf(uast.Identifier{}))
+	name["Name"] = nodes.String("foo")
+
+	alias := node(aliasType)
+	alias["Name"] = name
+	alias["Node"] = fn
+
+	group := node(functionGroupType)
+	group[uast.KeyPos] = pos(3, 12)
+	group["Nodes"] = nodes.Array{alias}
+
+	root := node("File")
+	root["Body"] = nodes.Array{group}
+
+	expected := &FileMetrics{
+		Language:   "go",
+		Complexity: 7,
+		Functions: []FunctionMetrics{
+			{
+				Name:       "foo",
+				Line:       3,
+				Length:     10,
+				Params:     2,
+				Complexity: 5,
+				Nesting:    2,
+			},
+			{Complexity: 2, Nesting: 1},
+		},
+	}
+
+	require.Equal(expected, fileMetrics("go", root))
+
+	noFuncs := node("File")
+	noFuncs["Body"] = nodes.Array{outer}
+
+	require.Equal(&FileMetrics{
+		Language:   "python",
+		Complexity: 4,
+		Functions:  []FunctionMetrics{},
+	}, fileMetrics("python", noFuncs))
+}
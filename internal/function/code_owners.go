@@ -0,0 +1,161 @@
+package function
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/codeowners"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	codeOwnersCacheSizeKey     = "GITBASE_CODEOWNERS_CACHE_SIZE"
+	defaultCodeOwnersCacheSize = 1000
+)
+
+func codeOwnersCacheSize() int {
+	v := os.Getenv(codeOwnersCacheSizeKey)
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		size = defaultCodeOwnersCacheSize
+	}
+
+	return size
+}
+
+var codeOwnersCache *lru.TwoQueueCache
+
+func init() {
+	var err error
+	codeOwnersCache, err = lru.New2Q(codeOwnersCacheSize())
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize code owners cache: %s", err))
+	}
+}
+
+// CodeOwners returns the owners of a file at a commit according to the
+// CODEOWNERS file of its tree.
+type CodeOwners struct {
+	Repository sql.Expression
+	Commit     sql.Expression
+	Path       sql.Expression
+}
+
+// NewCodeOwners creates a new CodeOwners UDF.
+func NewCodeOwners(repo, commit, path sql.Expression) sql.Expression {
+	return &CodeOwners{repo, commit, path}
+}
+
+func (f *CodeOwners) String() string {
+	return fmt.Sprintf("code_owners(%s, %s, %s)", f.Repository, f.Commit, f.Path)
+}
+
+// Type implements the Expression interface.
+func (CodeOwners) Type() sql.Type {
+	return sql.JSON
+}
+
+// IsNullable implements the Expression interface.
+func (*CodeOwners) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *CodeOwners) Resolved() bool {
+	return f.Repository.Resolved() && f.Commit.Resolved() && f.Path.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *CodeOwners) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Commit, f.Path}
+}
+
+// WithChildren implements the Expression interface.
+func (f *CodeOwners) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewCodeOwners(children[0], children[1], children[2]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *CodeOwners) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.CodeOwners")
+	defer span.Finish()
+
+	path, err := f.Path.Eval(ctx, row)
+	if err != nil || path == nil {
+		return nil, err
+	}
+
+	path, err = sql.Text.Convert(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, commit, err := resolveRepoCommit(ctx, "code_owners", row, f.Repository, f.Commit)
+	if err != nil || commit == nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var rules codeowners.Ruleset
+	if cached, ok := codeOwnersCache.Get(commit.TreeHash); ok {
+		rules = cached.(codeowners.Ruleset)
+	} else {
+		rules, err = commitCodeOwners(commit)
+		if err != nil {
+			ctx.Warn(0, "code_owners: unable to read CODEOWNERS of commit %s", commit.Hash)
+			logrus.WithFields(logrus.Fields{
+				"err":        err,
+				"repository": r,
+				"commit":     commit.Hash,
+			}).Error("code_owners: unable to read CODEOWNERS")
+			return nil, nil
+		}
+
+		codeOwnersCache.Add(commit.TreeHash, rules)
+	}
+
+	return rules.Owners(path.(string)), nil
+}
+
+func commitCodeOwners(commit *object.Commit) (codeowners.Ruleset, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return readCodeOwners(tree)
+}
+
+// readCodeOwners parses the first CODEOWNERS file found in the tree. If
+// there is none, no rules are returned.
+func readCodeOwners(tree *object.Tree) (codeowners.Ruleset, error) {
+	for _, p := range codeowners.Paths {
+		f, err := tree.File(p)
+		if err == object.ErrFileNotFound {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := f.Reader()
+		if err != nil {
+			return nil, err
+		}
+
+		rules, err := codeowners.Parse(r)
+		r.Close()
+		return rules, err
+	}
+
+	return nil, nil
+}
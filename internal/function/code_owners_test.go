@@ -0,0 +1,76 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeOwners(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewCodeOwners(
+		expression.NewGetField(0, sql.Text, "repository_id", true),
+		expression.NewGetField(1, sql.Text, "commit_hash", true),
+		expression.NewGetField(2, sql.Text, "file_path", true),
+	)
+
+	const commit = "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null path", sql.NewRow("worktree", commit, nil), nil},
+		{"null repository", sql.NewRow(nil, commit, "LICENSE"), nil},
+		{"unknown repository", sql.NewRow("foo", commit, "LICENSE"), nil},
+		{"unknown commit", sql.NewRow("worktree", "foo", "LICENSE"), nil},
+		{"no CODEOWNERS", sql.NewRow("worktree", commit, "LICENSE"), []string{}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestReadCodeOwners(t *testing.T) {
+	require := require.New(t)
+
+	tree := newTestTree(t, map[string]string{
+		"CODEOWNERS":         "* @root\n",
+		"docs/CODEOWNERS":    "* @docs\n",
+		".github/CODEOWNERS": "* @github\n*.go @gophers\n",
+		"main.go":            "",
+	})
+
+	rules, err := readCodeOwners(tree)
+	require.NoError(err)
+	require.Equal([]string{"@gophers"}, rules.Owners("cmd/main.go"))
+	require.Equal([]string{"@github"}, rules.Owners("README.md"))
+
+	tree = newTestTree(t, map[string]string{
+		"docs/CODEOWNERS": "* @docs\n",
+		"main.go":         "",
+	})
+
+	rules, err = readCodeOwners(tree)
+	require.NoError(err)
+	require.Equal([]string{"@docs"}, rules.Owners("main.go"))
+
+	tree = newTestTree(t, map[string]string{"main.go": ""})
+	rules, err = readCodeOwners(tree)
+	require.NoError(err)
+	require.Nil(rules)
+}
@@ -16,6 +16,7 @@ type CommitFileStats struct {
 	Repository sql.Expression
 	From       sql.Expression
 	To         sql.Expression
+	Detection  sql.Expression
 }
 
 // NewCommitFileStats creates a new COMMIT_FILE_STATS function.
@@ -26,8 +27,10 @@ func NewCommitFileStats(args ...sql.Expression) (sql.Expression, error) {
 		f.Repository, f.To = args[0], args[1]
 	case 3:
 		f.Repository, f.From, f.To = args[0], args[1], args[2]
+	case 4:
+		f.Repository, f.From, f.To, f.Detection = args[0], args[1], args[2], args[3]
 	default:
-		return nil, sql.ErrInvalidArgumentNumber.New("COMMIT_FILE_STATS", "2 or 3", len(args))
+		return nil, sql.ErrInvalidArgumentNumber.New("COMMIT_FILE_STATS", "2, 3 or 4", len(args))
 	}
 
 	return &f, nil
@@ -38,6 +41,10 @@ func (f *CommitFileStats) String() string {
 		return fmt.Sprintf("commit_file_stats(%s, %s)", f.Repository, f.To)
 	}
 
+	if f.Detection != nil {
+		return fmt.Sprintf("commit_file_stats(%s, %s, %s, %s)", f.Repository, f.From, f.To, f.Detection)
+	}
+
 	return fmt.Sprintf("commit_file_stats(%s, %s, %s)", f.Repository, f.From, f.To)
 }
 
@@ -48,25 +55,12 @@ func (CommitFileStats) Type() sql.Type {
 
 // WithChildren implements the Expression interface.
 func (f *CommitFileStats) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	expected := 2
-	if f.From != nil {
-		expected = 3
-	}
-
+	expected := len(f.Children())
 	if len(children) != expected {
 		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
 	}
 
-	repo := children[0]
-	var from, to sql.Expression
-	if f.From != nil {
-		from = children[1]
-		to = children[2]
-	} else {
-		to = children[1]
-	}
-
-	return &CommitFileStats{repo, from, to}, nil
+	return NewCommitFileStats(children...)
 }
 
 // Children implements the Expression interface.
@@ -75,6 +69,10 @@ func (f *CommitFileStats) Children() []sql.Expression {
 		return []sql.Expression{f.Repository, f.To}
 	}
 
+	if f.Detection != nil {
+		return []sql.Expression{f.Repository, f.From, f.To, f.Detection}
+	}
+
 	return []sql.Expression{f.Repository, f.From, f.To}
 }
 
@@ -87,7 +85,8 @@ func (*CommitFileStats) IsNullable() bool {
 func (f *CommitFileStats) Resolved() bool {
 	return f.Repository.Resolved() &&
 		f.To.Resolved() &&
-		(f.From == nil || f.From.Resolved())
+		(f.From == nil || f.From.Resolved()) &&
+		(f.Detection == nil || f.Detection.Resolved())
 }
 
 // Eval implements the Expression interface.
@@ -96,9 +95,13 @@ func (f *CommitFileStats) Eval(ctx *sql.Context, row sql.Row) (interface{}, erro
 		ctx,
 		"commit_file_stats",
 		row,
-		f.Repository, f.From, f.To,
-		func(r *git.Repository, from, to *object.Commit) (interface{}, error) {
-			stats, err := commitstats.CalculateByFile(r, from, to)
+		f.Repository, f.From, f.To, f.Detection,
+		func(
+			r *git.Repository,
+			from, to *object.Commit,
+			d *commitstats.Detection,
+		) (interface{}, error) {
+			stats, err := commitstats.CalculateByFile(r, from, to, d)
 			if err != nil {
 				return nil, err
 			}
@@ -22,12 +22,13 @@ func TestCommitFileStats(t *testing.T) {
 	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
 
 	testCases := []struct {
-		name     string
-		repo     sql.Expression
-		from     sql.Expression
-		to       sql.Expression
-		row      sql.Row
-		expected interface{}
+		name      string
+		repo      sql.Expression
+		from      sql.Expression
+		to        sql.Expression
+		detection sql.Expression
+		row       sql.Row
+		expected  interface{}
 	}{
 		{
 			name: "init commit",
@@ -38,6 +39,24 @@ func TestCommitFileStats(t *testing.T) {
 			expected: []interface{}{
 				commitstats.CommitFileStats{
 					Path:     "LICENSE",
+					Status:   commitstats.Added,
+					Language: "Text",
+					Other:    commitstats.KindStats{Additions: 22},
+					Total:    commitstats.KindStats{Additions: 22},
+				},
+			},
+		},
+		{
+			name:      "init commit with detection",
+			repo:      expression.NewGetField(0, sql.Text, "repository_id", false),
+			from:      expression.NewGetField(2, sql.Text, "from", true),
+			to:        expression.NewGetField(1, sql.Text, "commit_hash", false),
+			detection: expression.NewLiteral("-C90%", sql.Text),
+			row:       sql.NewRow("worktree", "b029517f6300c2da0f4b651b8642506cd6aaf45d", nil),
+			expected: []interface{}{
+				commitstats.CommitFileStats{
+					Path:     "LICENSE",
+					Status:   commitstats.Added,
 					Language: "Text",
 					Other:    commitstats.KindStats{Additions: 22},
 					Total:    commitstats.KindStats{Additions: 22},
@@ -72,7 +91,12 @@ func TestCommitFileStats(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			diff, err := NewCommitFileStats(tc.repo, tc.from, tc.to)
+			args := []sql.Expression{tc.repo, tc.from, tc.to}
+			if tc.detection != nil {
+				args = append(args, tc.detection)
+			}
+
+			diff, err := NewCommitFileStats(args...)
 			require.NoError(t, err)
 
 			result, err := diff.Eval(ctx, tc.row)
@@ -83,6 +107,25 @@ func TestCommitFileStats(t *testing.T) {
 	}
 }
 
+func TestCommitFileStatsInvalidDetection(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn, err := NewCommitFileStats(
+		expression.NewLiteral("worktree", sql.Text),
+		expression.NewLiteral(nil, sql.Null),
+		expression.NewLiteral("b029517f6300c2da0f4b651b8642506cd6aaf45d", sql.Text),
+		expression.NewLiteral("-X", sql.Text),
+	)
+	require.NoError(t, err)
+
+	_, err = fn.Eval(ctx, nil)
+	require.True(t, commitstats.ErrInvalidDetectionMode.Is(err))
+}
+
 func TestWithChildren(t *testing.T) {
 	repo := expression.NewGetField(0, sql.Text, "repository_id", false)
 	from := expression.NewGetField(2, sql.Text, "commit_hash", false)
@@ -0,0 +1,202 @@
+package function
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hhatto/gocloc"
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/enry/v2"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	commitLOCCacheSizeKey     = "GITBASE_COMMIT_LOC_CACHE_SIZE"
+	defaultCommitLOCCacheSize = 100000
+)
+
+func commitLOCCacheSize() int {
+	v := os.Getenv(commitLOCCacheSizeKey)
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		size = defaultCommitLOCCacheSize
+	}
+
+	return size
+}
+
+var commitLOCCache *lru.TwoQueueCache
+
+func init() {
+	var err error
+	commitLOCCache, err = lru.New2Q(commitLOCCacheSize())
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize commit loc cache: %s", err))
+	}
+}
+
+// LanguageLOC are the lines of code of the files of a language.
+type LanguageLOC struct {
+	Files    int `json:"Files"`
+	Code     int `json:"Code"`
+	Comments int `json:"Comment"`
+	Blanks   int `json:"Blank"`
+}
+
+// CommitLOC returns the lines of code, comments and blank lines of each
+// language in the tree of a commit. Vendored and generated files are not
+// taken into account.
+type CommitLOC struct {
+	Repository sql.Expression
+	Commit     sql.Expression
+}
+
+// NewCommitLOC creates a new CommitLOC UDF.
+func NewCommitLOC(repo, commit sql.Expression) sql.Expression {
+	return &CommitLOC{repo, commit}
+}
+
+func (f *CommitLOC) String() string {
+	return fmt.Sprintf("commit_loc(%s, %s)", f.Repository, f.Commit)
+}
+
+// Type implements the Expression interface.
+func (CommitLOC) Type() sql.Type {
+	return sql.JSON
+}
+
+// IsNullable implements the Expression interface.
+func (*CommitLOC) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *CommitLOC) Resolved() bool {
+	return f.Repository.Resolved() && f.Commit.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *CommitLOC) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Commit}
+}
+
+// WithChildren implements the Expression interface.
+func (f *CommitLOC) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewCommitLOC(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *CommitLOC) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.CommitLOC")
+	defer span.Finish()
+
+	r, commit, err := resolveRepoCommit(ctx, "commit_loc", row, f.Repository, f.Commit)
+	if err != nil || commit == nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	result, err := commitLOC(commit)
+	if err != nil {
+		ctx.Warn(0, "commit_loc: unable to compute lines of code of commit %s", commit.Hash)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+			"commit":     commit.Hash,
+		}).Error("commit_loc: unable to compute lines of code")
+		return nil, nil
+	}
+
+	return result, nil
+}
+
+// blobLOC are the lines of a blob with a given file name, which is needed
+// to know its language and whether it's generated.
+type blobLOC struct {
+	lang      string
+	generated bool
+	file      *gocloc.ClocFile
+}
+
+// commitLOC returns the lines of each language in the tree of the commit.
+// Vendored files are skipped as in commitstats, but generated files are
+// skipped as well, which commitstats doesn't do.
+// The lines of every blob are cached by its hash and file name, so only
+// the files changed since the previous commits are analyzed.
+func commitLOC(commit *object.Commit) (map[string]LanguageLOC, error) {
+	files, err := commit.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]LanguageLOC)
+	err = files.ForEach(func(f *object.File) error {
+		if enry.IsVendor(f.Name) {
+			return nil
+		}
+
+		loc, err := fileLOC(f)
+		if err != nil || loc == nil || loc.generated {
+			return err
+		}
+
+		stats := result[loc.lang]
+		stats.Files++
+		stats.Code += int(loc.file.Code)
+		stats.Comments += int(loc.file.Comments)
+		stats.Blanks += int(loc.file.Blanks)
+		result[loc.lang] = stats
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// fileLOC returns the lines of a file. If it's binary or its language is
+// not known by gocloc, nil is returned.
+func fileLOC(f *object.File) (*blobLOC, error) {
+	name := path.Base(f.Name)
+	key := f.Hash.String() + "/" + name
+	if loc, ok := commitLOCCache.Get(key); ok {
+		return loc.(*blobLOC), nil
+	}
+
+	isBinary, err := f.IsBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	var loc *blobLOC
+	if !isBinary {
+		content, err := f.Contents()
+		if err != nil {
+			return nil, err
+		}
+
+		lang := enry.GetLanguage(name, []byte(content))
+		if l, ok := languages.Langs[lang]; ok {
+			loc = &blobLOC{
+				lang:      lang,
+				generated: isGenerated(name, []byte(content)),
+				file: gocloc.AnalyzeReader(
+					name, l, strings.NewReader(content), &gocloc.ClocOptions{},
+				),
+			}
+		}
+	}
+
+	commitLOCCache.Add(key, loc)
+	return loc, nil
+}
@@ -0,0 +1,54 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitLOC(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewCommitLOC(
+		expression.NewGetField(0, sql.Text, "repository_id", true),
+		expression.NewGetField(1, sql.Text, "commit_hash", true),
+	)
+
+	const commit = "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null repository", sql.NewRow(nil, commit), nil},
+		{"null commit", sql.NewRow("worktree", nil), nil},
+		{"unknown repository", sql.NewRow("foo", commit), nil},
+		{"no code", sql.NewRow("worktree", "b029517f6300c2da0f4b651b8642506cd6aaf45d"), map[string]LanguageLOC{}},
+		{
+			// vendor/foo.go is not taken into account
+			"code",
+			sql.NewRow("worktree", commit),
+			map[string]LanguageLOC{
+				"Go":   {Files: 1, Code: 116, Blanks: 26},
+				"JSON": {Files: 2, Comments: 6514},
+				"PHP":  {Files: 1, Code: 186, Comments: 54, Blanks: 19},
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
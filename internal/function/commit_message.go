@@ -0,0 +1,274 @@
+package function
+
+import (
+	"fmt"
+	"regexp"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/src-d/gitbase/internal/commitmsg"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// ParseConventionalCommit returns the type, scope, subject and whether it
+// is a breaking change of a commit message following the Conventional
+// Commits specification, as JSON.
+type ParseConventionalCommit struct {
+	expression.UnaryExpression
+}
+
+// NewParseConventionalCommit creates a new ParseConventionalCommit UDF.
+func NewParseConventionalCommit(e sql.Expression) sql.Expression {
+	return &ParseConventionalCommit{expression.UnaryExpression{Child: e}}
+}
+
+func (f *ParseConventionalCommit) String() string {
+	return fmt.Sprintf("parse_conventional_commit(%s)", f.Child)
+}
+
+// Type implements the Expression interface.
+func (ParseConventionalCommit) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *ParseConventionalCommit) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewParseConventionalCommit(children[0]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *ParseConventionalCommit) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.ParseConventionalCommit")
+	defer span.Finish()
+
+	msg, err := evalMessage(ctx, f.Child, row)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+
+	c := commitmsg.ParseConventionalCommit(*msg)
+	if c == nil {
+		return nil, nil
+	}
+
+	return *c, nil
+}
+
+// issuePatternsCacheSize is the number of user given issue reference
+// patterns kept compiled.
+const issuePatternsCacheSize = 100
+
+var issuePatternsCache *lru.Cache
+
+func init() {
+	var err error
+	issuePatternsCache, err = lru.New(issuePatternsCacheSize)
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize issue patterns cache: %s", err))
+	}
+}
+
+// IssueRefs returns the issue references found in a commit message. The
+// patterns used to find them can be optionally given as a regular
+// expression or an array of them.
+type IssueRefs struct {
+	Message  sql.Expression
+	Patterns sql.Expression
+}
+
+// NewIssueRefs creates a new IssueRefs UDF.
+func NewIssueRefs(args ...sql.Expression) (sql.Expression, error) {
+	var msg, patterns sql.Expression
+	switch len(args) {
+	case 1:
+		msg = args[0]
+	case 2:
+		msg = args[0]
+		patterns = args[1]
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("issue_refs", "1 or 2", len(args))
+	}
+
+	return &IssueRefs{msg, patterns}, nil
+}
+
+func (f *IssueRefs) String() string {
+	if f.Patterns == nil {
+		return fmt.Sprintf("issue_refs(%s)", f.Message)
+	}
+	return fmt.Sprintf("issue_refs(%s, %s)", f.Message, f.Patterns)
+}
+
+// Type implements the Expression interface.
+func (IssueRefs) Type() sql.Type {
+	return sql.Array(sql.Text)
+}
+
+// IsNullable implements the Expression interface.
+func (*IssueRefs) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *IssueRefs) Resolved() bool {
+	return f.Message.Resolved() && (f.Patterns == nil || f.Patterns.Resolved())
+}
+
+// Children implements the Expression interface.
+func (f *IssueRefs) Children() []sql.Expression {
+	if f.Patterns == nil {
+		return []sql.Expression{f.Message}
+	}
+
+	return []sql.Expression{f.Message, f.Patterns}
+}
+
+// WithChildren implements the Expression interface.
+func (f *IssueRefs) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := 1
+	if f.Patterns != nil {
+		expected = 2
+	}
+
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
+	}
+
+	return NewIssueRefs(children...)
+}
+
+// Eval implements the Expression interface.
+func (f *IssueRefs) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.IssueRefs")
+	defer span.Finish()
+
+	msg, err := evalMessage(ctx, f.Message, row)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+
+	patterns := commitmsg.DefaultIssuePatterns
+	if f.Patterns != nil {
+		patterns, err = f.evalPatterns(ctx, row)
+		if err != nil || patterns == nil {
+			return nil, err
+		}
+	}
+
+	refs := commitmsg.IssueRefs(*msg, patterns)
+
+	// Since the type is an array, it must be converted to []interface{}.
+	result := make([]interface{}, len(refs))
+	for i, ref := range refs {
+		result[i] = ref
+	}
+
+	return result, nil
+}
+
+// evalPatterns returns the compiled patterns given to the function, which
+// can be a single pattern or an array of them. If the value is null, nil
+// is returned.
+func (f *IssueRefs) evalPatterns(ctx *sql.Context, row sql.Row) ([]*regexp.Regexp, error) {
+	v, err := f.Patterns.Eval(ctx, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(values))
+	for _, v := range values {
+		v, err := sql.Text.Convert(v)
+		if err != nil {
+			return nil, err
+		}
+
+		p := v.(string)
+		if re, ok := issuePatternsCache.Get(p); ok {
+			patterns = append(patterns, re.(*regexp.Regexp))
+			continue
+		}
+
+		re, err := commitmsg.CompileIssuePattern(p)
+		if err != nil {
+			return nil, err
+		}
+
+		issuePatternsCache.Add(p, re)
+		patterns = append(patterns, re)
+	}
+
+	return patterns, nil
+}
+
+// IsRevert returns the hash of the commit reverted by the commit with the
+// given message, or null if it is not a revert commit.
+type IsRevert struct {
+	expression.UnaryExpression
+}
+
+// NewIsRevert creates a new IsRevert UDF.
+func NewIsRevert(e sql.Expression) sql.Expression {
+	return &IsRevert{expression.UnaryExpression{Child: e}}
+}
+
+func (f *IsRevert) String() string {
+	return fmt.Sprintf("is_revert(%s)", f.Child)
+}
+
+// Type implements the Expression interface.
+func (IsRevert) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *IsRevert) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewIsRevert(children[0]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *IsRevert) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.IsRevert")
+	defer span.Finish()
+
+	msg, err := evalMessage(ctx, f.Child, row)
+	if err != nil || msg == nil {
+		return nil, err
+	}
+
+	hash := commitmsg.RevertedCommit(*msg)
+	if hash == "" {
+		return nil, nil
+	}
+
+	return hash, nil
+}
+
+// evalMessage evaluates the given expression as a commit message. If the
+// result is null, nil is returned.
+func evalMessage(ctx *sql.Context, e sql.Expression, row sql.Row) (*string, error) {
+	v, err := e.Eval(ctx, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	v, err = sql.Text.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := v.(string)
+	return &msg, nil
+}
@@ -0,0 +1,99 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/src-d/gitbase/internal/commitmsg"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	f := NewParseConventionalCommit(expression.NewGetField(0, sql.Text, "", true))
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null", sql.NewRow(nil), nil},
+		{"not conventional", sql.NewRow("Update README"), nil},
+		{
+			"conventional",
+			sql.NewRow("feat(sql)!: add issue_refs\n\nCloses #12\n"),
+			commitmsg.ConventionalCommit{Type: "feat", Scope: "sql", Breaking: true, Subject: "add issue_refs"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestIssueRefs(t *testing.T) {
+	f1, err := NewIssueRefs(expression.NewGetField(0, sql.Text, "", true))
+	require.NoError(t, err)
+
+	f2, err := NewIssueRefs(
+		expression.NewGetField(0, sql.Text, "", true),
+		expression.NewGetField(1, sql.Array(sql.Text), "", true),
+	)
+	require.NoError(t, err)
+
+	const msg = "PROJ-1: fix foo (#2)\n\nSee gh-3."
+	testCases := []struct {
+		name     string
+		f        sql.Expression
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null message", f1, sql.NewRow(nil), nil},
+		{"default patterns", f1, sql.NewRow(msg), []interface{}{"PROJ-1", "#2"}},
+		{"no references", f1, sql.NewRow("fix foo"), []interface{}{}},
+		{"null patterns", f2, sql.NewRow(msg, nil), nil},
+		{"single pattern", f2, sql.NewRow(msg, `gh-(\d+)`), []interface{}{"3"}},
+		{"pattern array", f2, sql.NewRow(msg, []interface{}{`gh-\d+`, `#\d+`}), []interface{}{"#2", "gh-3"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := tt.f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+
+	_, err = f2.Eval(sql.NewEmptyContext(), sql.NewRow(msg, "("))
+	require.True(t, commitmsg.ErrInvalidPattern.Is(err))
+}
+
+func TestIsRevert(t *testing.T) {
+	f := NewIsRevert(expression.NewGetField(0, sql.Text, "", true))
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null", sql.NewRow(nil), nil},
+		{"not a revert", sql.NewRow("Revert the changes"), nil},
+		{
+			"revert",
+			sql.NewRow("Revert \"foo\"\n\nThis reverts commit 35e85108805c84807bc66a02d91535e1e24b38b9.\n"),
+			"35e85108805c84807bc66a02d91535e1e24b38b9",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+}
@@ -19,6 +19,7 @@ type CommitStats struct {
 	Repository sql.Expression
 	From       sql.Expression
 	To         sql.Expression
+	Detection  sql.Expression
 }
 
 // NewCommitStats creates a new COMMIT_STATS function.
@@ -29,8 +30,10 @@ func NewCommitStats(args ...sql.Expression) (sql.Expression, error) {
 		f.Repository, f.To = args[0], args[1]
 	case 3:
 		f.Repository, f.From, f.To = args[0], args[1], args[2]
+	case 4:
+		f.Repository, f.From, f.To, f.Detection = args[0], args[1], args[2], args[3]
 	default:
-		return nil, sql.ErrInvalidArgumentNumber.New("COMMIT_STATS", "2 or 3", len(args))
+		return nil, sql.ErrInvalidArgumentNumber.New("COMMIT_STATS", "2, 3 or 4", len(args))
 	}
 
 	return f, nil
@@ -41,6 +44,10 @@ func (f *CommitStats) String() string {
 		return fmt.Sprintf("commit_stats(%s, %s)", f.Repository, f.To)
 	}
 
+	if f.Detection != nil {
+		return fmt.Sprintf("commit_stats(%s, %s, %s, %s)", f.Repository, f.From, f.To, f.Detection)
+	}
+
 	return fmt.Sprintf("commit_stats(%s, %s, %s)", f.Repository, f.From, f.To)
 }
 
@@ -51,25 +58,12 @@ func (CommitStats) Type() sql.Type {
 
 // WithChildren implements the Expression interface.
 func (f *CommitStats) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	expected := 2
-	if f.From != nil {
-		expected = 3
-	}
-
+	expected := len(f.Children())
 	if len(children) != expected {
 		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
 	}
 
-	repo := children[0]
-	var from, to sql.Expression
-	if f.From != nil {
-		from = children[1]
-		to = children[2]
-	} else {
-		to = children[1]
-	}
-
-	return &CommitStats{repo, from, to}, nil
+	return NewCommitStats(children...)
 }
 
 // Children implements the Expression interface.
@@ -78,6 +72,10 @@ func (f *CommitStats) Children() []sql.Expression {
 		return []sql.Expression{f.Repository, f.To}
 	}
 
+	if f.Detection != nil {
+		return []sql.Expression{f.Repository, f.From, f.To, f.Detection}
+	}
+
 	return []sql.Expression{f.Repository, f.From, f.To}
 }
 
@@ -90,7 +88,8 @@ func (*CommitStats) IsNullable() bool {
 func (f *CommitStats) Resolved() bool {
 	return f.Repository.Resolved() &&
 		f.To.Resolved() &&
-		(f.From == nil || f.From.Resolved())
+		(f.From == nil || f.From.Resolved()) &&
+		(f.Detection == nil || f.Detection.Resolved())
 }
 
 // Eval implements the Expression interface.
@@ -99,9 +98,13 @@ func (f *CommitStats) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 		ctx,
 		"commit_stats",
 		row,
-		f.Repository, f.From, f.To,
-		func(r *git.Repository, from, to *object.Commit) (interface{}, error) {
-			return commitstats.Calculate(r, from, to)
+		f.Repository, f.From, f.To, f.Detection,
+		func(
+			r *git.Repository,
+			from, to *object.Commit,
+			d *commitstats.Detection,
+		) (interface{}, error) {
+			return commitstats.Calculate(r, from, to, d)
 		},
 	)
 }
@@ -147,16 +150,67 @@ func resolveCommit(
 	return r.CommitObject(*commitHash)
 }
 
+// resolveRepoCommit resolves the repository and commit given by the
+// expressions. If any of them is null or can't be resolved, a warning is
+// added to the context and nil is returned. The returned repository must
+// be closed by the caller.
+func resolveRepoCommit(
+	ctx *sql.Context,
+	name string,
+	row sql.Row,
+	repoExpr, commitExpr sql.Expression,
+) (*gitbase.Repository, *object.Commit, error) {
+	repoID, err := repoExpr.Eval(ctx, row)
+	if err != nil || repoID == nil {
+		return nil, nil, err
+	}
+
+	commitHash, err := commitExpr.Eval(ctx, row)
+	if err != nil || commitHash == nil {
+		return nil, nil, err
+	}
+
+	r, err := resolveRepo(ctx, row, repoExpr)
+	if err != nil {
+		ctx.Warn(0, name+": unable to resolve repository")
+		logrus.WithField("err", err).Error(name + ": unable to resolve repository")
+		return nil, nil, nil
+	}
+
+	commit, err := resolveCommit(ctx, r, row, commitExpr)
+	if err != nil || commit == nil {
+		ctx.Warn(0, name+": unable to resolve commit of repository: %v", r)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+		}).Error(name + ": unable to resolve commit")
+		r.Close()
+		return nil, nil, nil
+	}
+
+	return r, commit, nil
+}
+
 func evalStatsFunc(
 	ctx *sql.Context,
 	name string,
 	row sql.Row,
-	repoExpr, fromExpr, toExpr sql.Expression,
-	fn func(r *git.Repository, from, to *object.Commit) (interface{}, error),
+	repoExpr, fromExpr, toExpr, detectionExpr sql.Expression,
+	fn func(r *git.Repository, from, to *object.Commit, d *commitstats.Detection) (interface{}, error),
 ) (interface{}, error) {
 	span, ctx := ctx.Span("gitbase." + name)
 	defer span.Finish()
 
+	mode, err := exprToString(ctx, detectionExpr, row)
+	if err != nil {
+		return nil, err
+	}
+
+	detection, err := commitstats.ParseDetection(mode)
+	if err != nil {
+		return nil, err
+	}
+
 	r, err := resolveRepo(ctx, row, repoExpr)
 	if err != nil {
 		ctx.Warn(0, name+": unable to resolve repository")
@@ -180,7 +234,7 @@ func evalStatsFunc(
 		return nil, nil
 	}
 
-	result, err := fn(r.Repository, from, to)
+	result, err := fn(r.Repository, from, to, detection)
 	if err != nil {
 		ctx.Warn(0, name+": unable to calculate for repository: %v, from: %v, to: %v", r, from, to)
 		log.WithFields(logrus.Fields{
@@ -0,0 +1,244 @@
+package function
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hhatto/gocloc"
+	"github.com/src-d/gitbase/internal/minhash"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// ContentSignature returns the MinHash signature of the content of a blob,
+// used to find near-duplicate files. If the language of the blob is given
+// comments are ignored.
+type ContentSignature struct {
+	Blob sql.Expression
+	Lang sql.Expression
+}
+
+// NewContentSignature creates a new ContentSignature UDF.
+func NewContentSignature(args ...sql.Expression) (sql.Expression, error) {
+	var blob, lang sql.Expression
+	switch len(args) {
+	case 1:
+		blob = args[0]
+	case 2:
+		blob = args[0]
+		lang = args[1]
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("content_signature", "1 or 2", len(args))
+	}
+
+	return &ContentSignature{blob, lang}, nil
+}
+
+// Resolved implements the Expression interface.
+func (f *ContentSignature) Resolved() bool {
+	return f.Blob.Resolved() && (f.Lang == nil || f.Lang.Resolved())
+}
+
+func (f *ContentSignature) String() string {
+	if f.Lang == nil {
+		return fmt.Sprintf("content_signature(%s)", f.Blob)
+	}
+	return fmt.Sprintf("content_signature(%s, %s)", f.Blob, f.Lang)
+}
+
+// IsNullable implements the Expression interface.
+func (f *ContentSignature) IsNullable() bool {
+	return true
+}
+
+// Type implements the Expression interface.
+func (ContentSignature) Type() sql.Type {
+	return sql.Blob
+}
+
+// Children implements the Expression interface.
+func (f *ContentSignature) Children() []sql.Expression {
+	if f.Lang == nil {
+		return []sql.Expression{f.Blob}
+	}
+
+	return []sql.Expression{f.Blob, f.Lang}
+}
+
+// WithChildren implements the Expression interface.
+func (f *ContentSignature) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := 1
+	if f.Lang != nil {
+		expected = 2
+	}
+
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
+	}
+
+	return NewContentSignature(children...)
+}
+
+// Eval implements the Expression interface.
+func (f *ContentSignature) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.ContentSignature")
+	defer span.Finish()
+
+	blob, err := f.Blob.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if blob == nil {
+		return nil, nil
+	}
+
+	blob, err = sql.Blob.Convert(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	lang, err := exprToString(ctx, f.Lang, row)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := minhash.New(contentShingles(blob.([]byte), lang))
+	if sig == nil {
+		return nil, nil
+	}
+
+	return sig.Bytes(), nil
+}
+
+// contentShingles returns the set of groups of consecutive normalized
+// tokens of the given content. If the language is known by gocloc, only
+// code lines are taken into account.
+func contentShingles(content []byte, lang string) []string {
+	if l, ok := languages.Langs[lang]; ok {
+		var code bytes.Buffer
+		gocloc.AnalyzeReader("", l, bytes.NewReader(content), &gocloc.ClocOptions{
+			OnCode: func(line string) {
+				code.WriteString(line)
+				code.WriteByte('\n')
+			},
+		})
+		content = code.Bytes()
+	}
+
+	return minhash.Shingles(string(content))
+}
+
+// SignatureSimilarity estimates the similarity, between 0 and 1, of the
+// contents of two blobs given their content signatures.
+type SignatureSimilarity struct {
+	expression.BinaryExpression
+}
+
+// NewSignatureSimilarity creates a new SignatureSimilarity UDF.
+func NewSignatureSimilarity(a, b sql.Expression) sql.Expression {
+	return &SignatureSimilarity{expression.BinaryExpression{Left: a, Right: b}}
+}
+
+func (f *SignatureSimilarity) String() string {
+	return fmt.Sprintf("signature_similarity(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (SignatureSimilarity) Type() sql.Type {
+	return sql.Float64
+}
+
+// WithChildren implements the Expression interface.
+func (f *SignatureSimilarity) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewSignatureSimilarity(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *SignatureSimilarity) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.SignatureSimilarity")
+	defer span.Finish()
+
+	a, err := evalSignature(ctx, f.Left, row)
+	if err != nil || a == nil {
+		return nil, err
+	}
+
+	b, err := evalSignature(ctx, f.Right, row)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	return a.Similarity(b), nil
+}
+
+// SignatureBuckets returns the locality-sensitive hashing buckets of a
+// content signature. Blobs sharing at least one bucket are candidates to
+// be near-duplicates.
+type SignatureBuckets struct {
+	expression.UnaryExpression
+}
+
+// NewSignatureBuckets creates a new SignatureBuckets UDF.
+func NewSignatureBuckets(sig sql.Expression) sql.Expression {
+	return &SignatureBuckets{expression.UnaryExpression{Child: sig}}
+}
+
+func (f *SignatureBuckets) String() string {
+	return fmt.Sprintf("signature_buckets(%s)", f.Child)
+}
+
+// Type implements the Expression interface.
+func (SignatureBuckets) Type() sql.Type {
+	return sql.Array(sql.Text)
+}
+
+// WithChildren implements the Expression interface.
+func (f *SignatureBuckets) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewSignatureBuckets(children[0]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *SignatureBuckets) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.SignatureBuckets")
+	defer span.Finish()
+
+	sig, err := evalSignature(ctx, f.Child, row)
+	if err != nil || sig == nil {
+		return nil, err
+	}
+
+	buckets := sig.Buckets()
+	result := make([]interface{}, len(buckets))
+	for i, b := range buckets {
+		result[i] = b
+	}
+
+	return result, nil
+}
+
+func evalSignature(ctx *sql.Context, e sql.Expression, row sql.Row) (minhash.Signature, error) {
+	v, err := e.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	v, err = sql.Blob.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return minhash.Decode(v.([]byte))
+}
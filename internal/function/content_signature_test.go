@@ -0,0 +1,120 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/src-d/gitbase/internal/minhash"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+const signatureCode = `package main
+
+import "fmt"
+
+func main() {
+	for i := 0; i < 10; i++ {
+		fmt.Println("hello", i)
+	}
+}
+`
+
+const signatureCodeWithComments = `package main
+
+import "fmt"
+
+// main prints hello ten times.
+func main() {
+	// TODO: make the number of iterations configurable
+	for i := 0; i < 20; i++ {
+		fmt.Println("hello", i)
+	}
+}
+`
+
+const signatureOtherCode = `#!/usr/bin/env python
+
+def sum(a, b):
+	return a + b
+
+print(sum(3, 5))
+`
+
+func TestContentSignature(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	_, err := NewContentSignature()
+	require.Error(err)
+
+	sig := func(args ...interface{}) []byte {
+		var exprs = make([]sql.Expression, len(args))
+		for i := range args {
+			exprs[i] = expression.NewGetField(i, sql.Blob, "", true)
+		}
+
+		f, err := NewContentSignature(exprs...)
+		require.NoError(err)
+
+		v, err := f.Eval(ctx, sql.NewRow(args...))
+		require.NoError(err)
+		if v == nil {
+			return nil
+		}
+		return v.([]byte)
+	}
+
+	similarity := func(a, b []byte) float64 {
+		f := NewSignatureSimilarity(
+			expression.NewGetField(0, sql.Blob, "", true),
+			expression.NewGetField(1, sql.Blob, "", true),
+		)
+
+		v, err := f.Eval(ctx, sql.NewRow(a, b))
+		require.NoError(err)
+		return v.(float64)
+	}
+
+	require.Nil(sig(nil))
+	require.Nil(sig([]byte("   \n\t")))
+
+	plain := sig([]byte(signatureCode))
+	require.Len(plain, minhash.NumHashes*4)
+
+	withLang := sig([]byte(signatureCode), "Go")
+	commented := sig([]byte(signatureCodeWithComments), "Go")
+	require.Equal(1.0, similarity(withLang, commented))
+	require.True(similarity(plain, sig([]byte(signatureCodeWithComments))) < 1.0)
+	require.True(similarity(plain, sig([]byte(signatureOtherCode))) < 0.2)
+
+	f := NewSignatureSimilarity(
+		expression.NewGetField(0, sql.Blob, "", true),
+		expression.NewGetField(1, sql.Blob, "", true),
+	)
+
+	v, err := f.Eval(ctx, sql.NewRow(plain, nil))
+	require.NoError(err)
+	require.Nil(v)
+
+	_, err = f.Eval(ctx, sql.NewRow(plain, []byte("foo")))
+	require.Error(err)
+	require.True(minhash.ErrInvalidSignature.Is(err))
+}
+
+func TestSignatureBuckets(t *testing.T) {
+	require := require.New(t)
+	ctx := sql.NewEmptyContext()
+
+	f := NewSignatureBuckets(expression.NewGetField(0, sql.Blob, "", true))
+
+	v, err := f.Eval(ctx, sql.NewRow(nil))
+	require.NoError(err)
+	require.Nil(v)
+
+	sig := minhash.New(contentShingles([]byte(signatureCode), "Go"))
+	v, err = f.Eval(ctx, sql.NewRow(sig.Bytes()))
+	require.NoError(err)
+	require.Len(v, minhash.Bands)
+	require.Equal(sig.Buckets()[0], v.([]interface{})[0])
+}
@@ -0,0 +1,85 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/src-d/gitbase/internal/secrets"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// FindSecrets returns the secrets, such as credentials or private keys,
+// found in a file. Binary and vendored files are ignored.
+type FindSecrets struct {
+	expression.BinaryExpression
+}
+
+// NewFindSecrets creates a new FindSecrets UDF.
+func NewFindSecrets(path, blob sql.Expression) sql.Expression {
+	return &FindSecrets{expression.BinaryExpression{Left: path, Right: blob}}
+}
+
+func (f *FindSecrets) String() string {
+	return fmt.Sprintf("find_secrets(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (FindSecrets) Type() sql.Type {
+	return sql.Array(sql.JSON)
+}
+
+// WithChildren implements the Expression interface.
+func (f *FindSecrets) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewFindSecrets(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *FindSecrets) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.FindSecrets")
+	defer span.Finish()
+
+	path, err := exprToString(ctx, f.Left, row)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := f.Right.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if blob == nil {
+		return nil, nil
+	}
+
+	blob, err = sql.Blob.Convert(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner, err := secrets.DefaultScanner()
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := scanner.Scan(path, blob.([]byte))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(findings) == 0 {
+		return nil, nil
+	}
+
+	// Since the type is an array, it must be converted to []interface{}.
+	result := make([]interface{}, len(findings))
+	for i, f := range findings {
+		result[i] = f
+	}
+
+	return result, nil
+}
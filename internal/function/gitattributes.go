@@ -0,0 +1,253 @@
+package function
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+	enry "github.com/src-d/enry/v2"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/gitattributes"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	attributesCacheSizeKey     = "GITBASE_ATTRIBUTES_CACHE_SIZE"
+	defaultAttributesCacheSize = 1000
+
+	gitattributesFile = ".gitattributes"
+
+	linguistLanguage  = "linguist-language"
+	linguistVendored  = "linguist-vendored"
+	linguistGenerated = "linguist-generated"
+)
+
+func attributesCacheSize() int {
+	v := os.Getenv(attributesCacheSizeKey)
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		size = defaultAttributesCacheSize
+	}
+
+	return size
+}
+
+var attributesCache *lru.TwoQueueCache
+
+func init() {
+	var err error
+	attributesCache, err = lru.New2Q(attributesCacheSize())
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize attributes cache: %s", err))
+	}
+}
+
+// linguistAttributes are the rules of the .gitattributes files of a tree
+// setting linguist attributes, sorted by increasing priority.
+type linguistAttributes []gitattributes.MatchAttribute
+
+// match returns the linguist attributes of the given path. When several
+// rules set the same attribute, the one with the highest priority wins.
+func (a linguistAttributes) match(path string) map[string]gitattributes.Attribute {
+	parts := strings.Split(path, "/")
+	result := make(map[string]gitattributes.Attribute)
+	for _, rule := range a {
+		if !rule.Pattern.Match(parts) {
+			continue
+		}
+
+		for _, attr := range rule.Attributes {
+			result[attr.Name()] = attr
+		}
+	}
+
+	return result
+}
+
+// language returns the language set for the given path with the
+// linguist-language attribute, if any.
+func (a linguistAttributes) language(path string) (string, bool) {
+	attr, ok := a.match(path)[linguistLanguage]
+	if !ok || !attr.IsValueSet() {
+		return "", false
+	}
+
+	if lang, ok := enry.GetLanguageByAlias(attr.Value()); ok {
+		return lang, true
+	}
+
+	return attr.Value(), true
+}
+
+// flag returns whether the given boolean attribute is set or unset for
+// the path. The second value is false if the attribute is not specified.
+func (a linguistAttributes) flag(path, name string) (bool, bool) {
+	attr, ok := a.match(path)[name]
+	switch {
+	case !ok:
+		return false, false
+	case attr.IsSet():
+		return true, true
+	case attr.IsUnset():
+		return false, true
+	case attr.IsValueSet():
+		v, err := strconv.ParseBool(attr.Value())
+		return v, err == nil
+	default:
+		return false, false
+	}
+}
+
+// readLinguistAttributes reads the rules setting linguist attributes from
+// all the .gitattributes files of the given tree. Rules of files in
+// deeper directories have a higher priority.
+func readLinguistAttributes(tree *object.Tree) (linguistAttributes, error) {
+	var result linguistAttributes
+	err := walkTreeFiles(tree, gitattributesFile, func(domain []string, r io.Reader) error {
+		attrs, err := gitattributes.ReadAttributes(r, domain, len(domain) == 0)
+		if err != nil {
+			return err
+		}
+
+		for _, attr := range attrs {
+			if attr.Pattern != nil && hasLinguistAttribute(attr) {
+				result = append(result, attr)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// walkTreeFiles calls fn with the content of all the files with the given
+// name in the tree and its subtrees, along with the path of the directory
+// containing them. Parent directories are visited before their children.
+func walkTreeFiles(
+	tree *object.Tree,
+	name string,
+	fn func(dir []string, r io.Reader) error,
+) error {
+	var walk func(t *object.Tree, dir []string) error
+	walk = func(t *object.Tree, dir []string) error {
+		for _, e := range t.Entries {
+			if e.Name != name || !e.Mode.IsFile() {
+				continue
+			}
+
+			f, err := t.TreeEntryFile(&e)
+			if err != nil {
+				return err
+			}
+
+			r, err := f.Reader()
+			if err != nil {
+				return err
+			}
+
+			err = fn(dir, r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, e := range t.Entries {
+			if e.Mode != filemode.Dir {
+				continue
+			}
+
+			sub, err := t.Tree(e.Name)
+			if err != nil {
+				return err
+			}
+
+			subdir := make([]string, len(dir), len(dir)+1)
+			copy(subdir, dir)
+			if err := walk(sub, append(subdir, e.Name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return walk(tree, nil)
+}
+
+func hasLinguistAttribute(attr gitattributes.MatchAttribute) bool {
+	for _, a := range attr.Attributes {
+		if strings.HasPrefix(a.Name(), "linguist-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// treeAttributes returns the linguist attributes of the tree of the given
+// commit. Attributes are cached by tree hash. If the repository or commit
+// expressions are nil or evaluate to null, no attributes are returned.
+func treeAttributes(
+	ctx *sql.Context,
+	name string,
+	row sql.Row,
+	repoExpr, commitExpr sql.Expression,
+) (linguistAttributes, error) {
+	if repoExpr == nil || commitExpr == nil {
+		return nil, nil
+	}
+
+	r, commit, err := resolveRepoCommit(ctx, name, row, repoExpr, commitExpr)
+	if err != nil || commit == nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if attrs, ok := attributesCache.Get(commit.TreeHash); ok {
+		return attrs.(linguistAttributes), nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := cachedLinguistAttributes(tree)
+	if err != nil {
+		ctx.Warn(0, name+": unable to read .gitattributes of tree %s", tree.Hash)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+		}).Error(name + ": unable to read .gitattributes")
+		return nil, nil
+	}
+
+	return attrs, nil
+}
+
+// cachedLinguistAttributes returns the linguist attributes of a tree,
+// which are cached by its hash.
+func cachedLinguistAttributes(tree *object.Tree) (linguistAttributes, error) {
+	if attrs, ok := attributesCache.Get(tree.Hash); ok {
+		return attrs.(linguistAttributes), nil
+	}
+
+	attrs, err := readLinguistAttributes(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	attributesCache.Add(tree.Hash, attrs)
+	return attrs, nil
+}
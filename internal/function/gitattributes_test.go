@@ -0,0 +1,122 @@
+package function
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+func TestReadLinguistAttributes(t *testing.T) {
+	tree := newTestTree(t, map[string]string{
+		".gitattributes": "*.h linguist-language=C++\n" +
+			"third_party/** linguist-vendored\n" +
+			"*.txt text\n",
+		"lib/.gitattributes": "*.h linguist-language=objective-c\n" +
+			"gen/* linguist-generated=true\n",
+		"third_party/.gitattributes": "own/** -linguist-vendored\n",
+		"lib/foo.h":                  "",
+	})
+
+	attrs, err := readLinguistAttributes(tree)
+	require.NoError(t, err)
+	require.Len(t, attrs, 5)
+
+	testCases := []struct {
+		path      string
+		language  string
+		vendored  bool
+		generated bool
+		specified bool
+	}{
+		{"foo.h", "C++", false, false, false},
+		{"lib/foo.h", "Objective-C", false, false, false},
+		{"lib/gen/foo.go", "", false, true, true},
+		{"third_party/foo.go", "", true, false, true},
+		{"third_party/own/foo.go", "", false, false, true},
+		{"foo.txt", "", false, false, false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.path, func(t *testing.T) {
+			lang, ok := attrs.language(tt.path)
+			require.Equal(t, tt.language != "", ok)
+			require.Equal(t, tt.language, lang)
+
+			vendored, okv := attrs.flag(tt.path, linguistVendored)
+			generated, okg := attrs.flag(tt.path, linguistGenerated)
+			require.Equal(t, tt.vendored, vendored)
+			require.Equal(t, tt.generated, generated)
+			require.Equal(t, tt.specified, okv || okg)
+		})
+	}
+
+	var empty linguistAttributes
+	_, ok := empty.language("foo.h")
+	require.False(t, ok)
+}
+
+// newTestTree creates a tree in memory with the given files.
+func newTestTree(t *testing.T, files map[string]string) *object.Tree {
+	t.Helper()
+	s := memory.NewStorage()
+
+	var write func(prefix string) plumbing.Hash
+	write = func(prefix string) plumbing.Hash {
+		var entries []object.TreeEntry
+		dirs := make(map[string]struct{})
+		for path, content := range files {
+			if len(path) <= len(prefix) || path[:len(prefix)] != prefix {
+				continue
+			}
+
+			name := path[len(prefix):]
+			for i, c := range name {
+				if c == '/' {
+					dirs[name[:i]] = struct{}{}
+					name = ""
+					break
+				}
+			}
+
+			if name == "" {
+				continue
+			}
+
+			obj := s.NewEncodedObject()
+			obj.SetType(plumbing.BlobObject)
+			w, err := obj.Writer()
+			require.NoError(t, err)
+			_, err = w.Write([]byte(content))
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			h, err := s.SetEncodedObject(obj)
+			require.NoError(t, err)
+			entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: h})
+		}
+
+		for dir := range dirs {
+			h := write(prefix + dir + "/")
+			entries = append(entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: h})
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+
+		obj := s.NewEncodedObject()
+		require.NoError(t, (&object.Tree{Entries: entries}).Encode(obj))
+		h, err := s.SetEncodedObject(obj)
+		require.NoError(t, err)
+		return h
+	}
+
+	tree, err := object.GetTree(s, write(""))
+	require.NoError(t, err)
+	return tree
+}
@@ -0,0 +1,195 @@
+package function
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// generatedLines is the number of lines at the beginning of a file where
+// the generated markers are looked for.
+const generatedLines = 10
+
+var (
+	generatedSuffixes = []string{
+		".min.js", "-min.js", ".min.css", "-min.css", ".js.map", ".css.map",
+		".pb.go", ".pb.gw.go", ".pb.cc", ".pb.h", "_pb2.py", "_pb2_grpc.py",
+		".designer.cs", ".designer.vb", ".g.cs", ".generated.cs",
+	}
+
+	generatedNames = map[string]struct{}{
+		"package-lock.json":   {},
+		"npm-shrinkwrap.json": {},
+		"yarn.lock":           {},
+		"composer.lock":       {},
+		"Gemfile.lock":        {},
+		"Cargo.lock":          {},
+		"Gopkg.lock":          {},
+		"glide.lock":          {},
+		"go.sum":              {},
+		"poetry.lock":         {},
+		"Pipfile.lock":        {},
+	}
+
+	generatedMarkers = []string{
+		"code generated by",
+		"do not edit",
+		"@generated",
+		"<auto-generated",
+		"autogenerated by",
+		"auto-generated by",
+		"automatically generated",
+		"generated by the protocol buffer compiler",
+	}
+)
+
+// IsGenerated reports whether files are generated or not given their
+// path and optional content. If the repository and commit are given, the
+// linguist-generated attribute set in the .gitattributes files of the
+// commit tree takes precedence.
+type IsGenerated struct {
+	Path       sql.Expression
+	Blob       sql.Expression
+	Repository sql.Expression
+	Commit     sql.Expression
+}
+
+// NewIsGenerated creates a new IsGenerated function.
+func NewIsGenerated(args ...sql.Expression) (sql.Expression, error) {
+	f := &IsGenerated{}
+	switch len(args) {
+	case 2:
+		f.Path, f.Blob = args[0], args[1]
+	case 4:
+		f.Path, f.Blob, f.Repository, f.Commit = args[0], args[1], args[2], args[3]
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("is_generated", "2 or 4", len(args))
+	}
+
+	return f, nil
+}
+
+// Type implements the sql.Expression interface.
+func (f *IsGenerated) Type() sql.Type { return sql.Boolean }
+
+// IsNullable implements the sql.Expression interface.
+func (f *IsGenerated) IsNullable() bool { return f.Path.IsNullable() }
+
+// Resolved implements the sql.Expression interface.
+func (f *IsGenerated) Resolved() bool {
+	for _, e := range f.Children() {
+		if !e.Resolved() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Children implements the sql.Expression interface.
+func (f *IsGenerated) Children() []sql.Expression {
+	if f.Repository == nil {
+		return []sql.Expression{f.Path, f.Blob}
+	}
+
+	return []sql.Expression{f.Path, f.Blob, f.Repository, f.Commit}
+}
+
+func (f *IsGenerated) String() string {
+	if f.Repository == nil {
+		return fmt.Sprintf("is_generated(%s, %s)", f.Path, f.Blob)
+	}
+
+	return fmt.Sprintf("is_generated(%s, %s, %s, %s)", f.Path, f.Blob, f.Repository, f.Commit)
+}
+
+// WithChildren implements the Expression interface.
+func (f *IsGenerated) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := len(f.Children())
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
+	}
+
+	return NewIsGenerated(children...)
+}
+
+// Eval implements the sql.Expression interface.
+func (f *IsGenerated) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.IsGenerated")
+	defer span.Finish()
+
+	val, err := f.Path.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if val == nil {
+		return nil, nil
+	}
+
+	val, err = sql.Text.Convert(val)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath := val.(string)
+
+	attrs, err := treeAttributes(ctx, "is_generated", row, f.Repository, f.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if generated, ok := attrs.flag(filePath, linguistGenerated); ok {
+		return generated, nil
+	}
+
+	var blob []byte
+	val, err = f.Blob.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if val != nil {
+		val, err = sql.Blob.Convert(val)
+		if err != nil {
+			return nil, err
+		}
+
+		blob = val.([]byte)
+	}
+
+	return isGenerated(filePath, blob), nil
+}
+
+// isGenerated reports whether the file is generated, either because its
+// name is one of a well-known generated file or because it contains a
+// generated code marker in its first lines.
+func isGenerated(filePath string, content []byte) bool {
+	name := path.Base(filePath)
+	if _, ok := generatedNames[name]; ok {
+		return true
+	}
+
+	for _, s := range generatedSuffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for i := 0; i < generatedLines && scanner.Scan(); i++ {
+		line := strings.ToLower(scanner.Text())
+		for _, m := range generatedMarkers {
+			if strings.Contains(line, m) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,51 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGenerated(t *testing.T) {
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null path", sql.NewRow(nil, nil), nil},
+		{"regular file", sql.NewRow("main.go", "package main\n"), false},
+		{"null content", sql.NewRow("main.go", nil), false},
+		{"lock file", sql.NewRow("web/yarn.lock", nil), true},
+		{"minified", sql.NewRow("static/app.min.js", "var a=1;"), true},
+		{"protobuf", sql.NewRow("api/api.pb.go", nil), true},
+		{
+			"go generated",
+			sql.NewRow("foo.go", "// Code generated by go-bindata. DO NOT EDIT.\n\npackage foo\n"),
+			true,
+		},
+		{
+			"marker after first lines",
+			sql.NewRow("foo.go", "package foo\n\n\n\n\n\n\n\n\n\n// @generated\n"),
+			false,
+		},
+	}
+
+	fn, err := NewIsGenerated(
+		expression.NewGetField(0, sql.Text, "file_path", true),
+		expression.NewGetField(1, sql.Blob, "blob_content", true),
+	)
+	require.NoError(t, err)
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+
+	_, err = NewIsGenerated(expression.NewGetField(0, sql.Text, "file_path", true))
+	require.True(t, sql.ErrInvalidArgumentNumber.Is(err))
+}
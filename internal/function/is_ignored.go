@@ -0,0 +1,256 @@
+package function
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/gitignore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	ignoreCacheSizeKey     = "GITBASE_IGNORE_CACHE_SIZE"
+	defaultIgnoreCacheSize = 1000
+
+	gitignoreFile = ".gitignore"
+	excludeFile   = "info/exclude"
+)
+
+func ignoreCacheSize() int {
+	v := os.Getenv(ignoreCacheSizeKey)
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		size = defaultIgnoreCacheSize
+	}
+
+	return size
+}
+
+var ignoreCache *lru.TwoQueueCache
+
+func init() {
+	var err error
+	ignoreCache, err = lru.New2Q(ignoreCacheSize())
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize ignore cache: %s", err))
+	}
+}
+
+// IsIgnored checks whether a file path matches the ignore rules of a
+// commit, that is, the ones in all the .gitignore files of its tree and
+// in the .git/info/exclude file of the repository.
+type IsIgnored struct {
+	Repository sql.Expression
+	Commit     sql.Expression
+	Path       sql.Expression
+}
+
+var _ gitbase.IgnoreFilter = (*IsIgnored)(nil)
+
+// NewIsIgnored creates a new IsIgnored UDF.
+func NewIsIgnored(repo, commit, path sql.Expression) sql.Expression {
+	return &IsIgnored{repo, commit, path}
+}
+
+func (f *IsIgnored) String() string {
+	return fmt.Sprintf("is_ignored(%s, %s, %s)", f.Repository, f.Commit, f.Path)
+}
+
+// Type implements the Expression interface.
+func (IsIgnored) Type() sql.Type {
+	return sql.Boolean
+}
+
+// IsNullable implements the Expression interface.
+func (*IsIgnored) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *IsIgnored) Resolved() bool {
+	return f.Repository.Resolved() && f.Commit.Resolved() && f.Path.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *IsIgnored) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Commit, f.Path}
+}
+
+// WithChildren implements the Expression interface.
+func (f *IsIgnored) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewIsIgnored(children[0], children[1], children[2]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *IsIgnored) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.IsIgnored")
+	defer span.Finish()
+
+	path, err := f.Path.Eval(ctx, row)
+	if err != nil || path == nil {
+		return nil, err
+	}
+
+	path, err = sql.Text.Convert(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r, commit, err := resolveRepoCommit(ctx, "is_ignored", row, f.Repository, f.Commit)
+	if err != nil || commit == nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	rules, ok, err := commitIgnoreRules(ctx, r, commit)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return rules.ignored(path.(string)), nil
+}
+
+// IgnoreArgs implements the gitbase.IgnoreFilter interface.
+func (f *IsIgnored) IgnoreArgs() (repo, commit, path sql.Expression) {
+	return f.Repository, f.Commit, f.Path
+}
+
+// IgnoreMatcher implements the gitbase.IgnoreFilter interface.
+func (f *IsIgnored) IgnoreMatcher(
+	ctx *sql.Context,
+	r *gitbase.Repository,
+	commit *object.Commit,
+) (func(path string) bool, error) {
+	rules, ok, err := commitIgnoreRules(ctx, r, commit)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return rules.ignored, nil
+}
+
+// commitIgnoreRules returns the ignore rules of the given commit. Rules
+// are cached by repository and tree hash. If the rules can't be read, the
+// second value is false.
+func commitIgnoreRules(
+	ctx *sql.Context,
+	r *gitbase.Repository,
+	commit *object.Commit,
+) (ignoreRules, bool, error) {
+	key := r.ID() + ":" + commit.TreeHash.String()
+	if rules, ok := ignoreCache.Get(key); ok {
+		return rules.(ignoreRules), true, nil
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// siva repositories don't have an exclude file, so the filesystem is
+	// only used when it's available.
+	fs, _ := r.FS()
+	rules, err := readIgnoreRules(fs, tree)
+	if err != nil {
+		ctx.Warn(0, "is_ignored: unable to read ignore rules of tree %s", tree.Hash)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+		}).Error("is_ignored: unable to read ignore rules")
+		return nil, false, nil
+	}
+
+	ignoreCache.Add(key, rules)
+	return rules, true, nil
+}
+
+// ignoreRules are the gitignore patterns of a tree, sorted by increasing
+// priority.
+type ignoreRules []gitignore.Pattern
+
+// ignored reports whether the file in the given path is ignored. As in
+// git, a file can't be included again if any of its parent directories
+// is ignored.
+func (rules ignoreRules) ignored(path string) bool {
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if rules.match(parts[:i], true) == gitignore.Exclude {
+			return true
+		}
+	}
+
+	return rules.match(parts, false) == gitignore.Exclude
+}
+
+// match returns the result of the pattern with the highest priority
+// matching the path.
+func (rules ignoreRules) match(path []string, isDir bool) gitignore.MatchResult {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if m := rules[i].Match(path, isDir); m != gitignore.NoMatch {
+			return m
+		}
+	}
+
+	return gitignore.NoMatch
+}
+
+// readIgnoreRules reads the ignore patterns of the exclude file in the
+// given git directory filesystem, if any, and the ones in the .gitignore
+// files of the tree. Patterns in deeper directories have a higher
+// priority, and all of them have a higher priority than the excluded ones.
+func readIgnoreRules(fs billy.Filesystem, tree *object.Tree) (ignoreRules, error) {
+	var rules ignoreRules
+	if fs != nil {
+		f, err := fs.Open(excludeFile)
+		if err == nil {
+			rules, err = readIgnorePatterns(f, nil)
+			f.Close()
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	err := walkTreeFiles(tree, gitignoreFile, func(dir []string, r io.Reader) error {
+		patterns, err := readIgnorePatterns(r, dir)
+		rules = append(rules, patterns...)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// readIgnorePatterns parses the patterns of an ignore file in the given
+// directory, skipping blank lines and comments.
+func readIgnorePatterns(r io.Reader, dir []string) ([]gitignore.Pattern, error) {
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, gitignore.ParsePattern(line, dir))
+	}
+
+	return patterns, scanner.Err()
+}
@@ -0,0 +1,114 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+func TestIsIgnored(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewIsIgnored(
+		expression.NewGetField(0, sql.Text, "repository_id", true),
+		expression.NewGetField(1, sql.Text, "commit_hash", true),
+		expression.NewGetField(2, sql.Text, "file_path", true),
+	)
+
+	// the exclude file is empty
+	result, err := fn.Eval(ctx, sql.NewRow(
+		"worktree", "b029517f6300c2da0f4b651b8642506cd6aaf45d", "debug.log",
+	))
+	require.NoError(t, err)
+	require.Equal(t, false, result)
+
+	repo, err := pool.GetRepo("worktree")
+	require.NoError(t, err)
+	fs, err := repo.FS()
+	require.NoError(t, err)
+	require.NoError(t, util.WriteFile(fs, excludeFile, []byte("# local\n*.log\n"), 0644))
+	require.NoError(t, repo.Close())
+
+	const commit = "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null path", sql.NewRow("worktree", commit, nil), nil},
+		{"null commit", sql.NewRow("worktree", nil, "foo.jar"), nil},
+		{"unknown repository", sql.NewRow("foo", commit, "foo.jar"), nil},
+		{"unknown commit", sql.NewRow("worktree", "foo", "foo.jar"), nil},
+		{"not ignored", sql.NewRow("worktree", commit, "go/example.go"), false},
+		{"ignored by .gitignore", sql.NewRow("worktree", commit, "lib/foo.jar"), true},
+		{"ignored by exclude", sql.NewRow("worktree", commit, "debug.log"), true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestReadIgnoreRules(t *testing.T) {
+	require := require.New(t)
+
+	fs := memfs.New()
+	require.NoError(util.WriteFile(fs, excludeFile, []byte("*.tmp\n"), 0644))
+
+	tree := newTestTree(t, map[string]string{
+		".gitignore": "# build output\n" +
+			"build/\n" +
+			"!build/keep.txt\n" +
+			"*.o\n" +
+			"!important.o\n" +
+			"/.env\n" +
+			"!*.tmp\n",
+		"src/.gitignore": "\n*.gen.go\n!keep.gen.go\n",
+		"src/main.go":    "",
+	})
+
+	rules, err := readIgnoreRules(fs, tree)
+	require.NoError(err)
+
+	testCases := []struct {
+		path    string
+		ignored bool
+	}{
+		{"src/main.go", false},
+		{"build/out.bin", true},
+		// parent directory is excluded, so it can't be included again
+		{"build/keep.txt", true},
+		{"foo.o", true},
+		{"lib/foo.o", true},
+		{"important.o", false},
+		{".env", true},
+		{"config/.env", false},
+		{"src/api.gen.go", true},
+		{"src/keep.gen.go", false},
+		{"api.gen.go", false},
+		// .gitignore has a higher priority than the exclude file
+		{"foo.tmp", false},
+	}
+
+	for _, tt := range testCases {
+		require.Equal(tt.ignored, rules.ignored(tt.path), tt.path)
+	}
+
+	rules, err = readIgnoreRules(memfs.New(), tree)
+	require.NoError(err)
+	require.Len(rules, 8)
+}
@@ -5,28 +5,64 @@ import (
 
 	enry "github.com/src-d/enry/v2"
 	"github.com/src-d/go-mysql-server/sql"
-	"github.com/src-d/go-mysql-server/sql/expression"
 )
 
-// IsVendor reports whether files are vendored or not.
+// IsVendor reports whether files are vendored or not. If the repository
+// and commit are given, the linguist-vendored attribute set in the
+// .gitattributes files of the commit tree takes precedence.
 type IsVendor struct {
-	expression.UnaryExpression
+	Path       sql.Expression
+	Repository sql.Expression
+	Commit     sql.Expression
 }
 
 // NewIsVendor creates a new IsVendor function.
-func NewIsVendor(filePath sql.Expression) sql.Expression {
-	return &IsVendor{expression.UnaryExpression{Child: filePath}}
+func NewIsVendor(args ...sql.Expression) (sql.Expression, error) {
+	f := &IsVendor{}
+	switch len(args) {
+	case 1:
+		f.Path = args[0]
+	case 3:
+		f.Path, f.Repository, f.Commit = args[0], args[1], args[2]
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("is_vendor", "1 or 3", len(args))
+	}
+
+	return f, nil
 }
 
 // Type implements the sql.Expression interface.
 func (v *IsVendor) Type() sql.Type { return sql.Boolean }
 
+// IsNullable implements the sql.Expression interface.
+func (v *IsVendor) IsNullable() bool { return v.Path.IsNullable() }
+
+// Resolved implements the sql.Expression interface.
+func (v *IsVendor) Resolved() bool {
+	for _, e := range v.Children() {
+		if !e.Resolved() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Children implements the sql.Expression interface.
+func (v *IsVendor) Children() []sql.Expression {
+	if v.Repository == nil {
+		return []sql.Expression{v.Path}
+	}
+
+	return []sql.Expression{v.Path, v.Repository, v.Commit}
+}
+
 // Eval implements the sql.Expression interface.
 func (v *IsVendor) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 	span, ctx := ctx.Span("function.IsVendor")
 	defer span.Finish()
 
-	val, err := v.Child.Eval(ctx, row)
+	val, err := v.Path.Eval(ctx, row)
 	if err != nil {
 		return nil, err
 	}
@@ -40,17 +76,34 @@ func (v *IsVendor) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 		return nil, err
 	}
 
-	return enry.IsVendor(val.(string)), nil
+	path := val.(string)
+
+	attrs, err := treeAttributes(ctx, "is_vendor", row, v.Repository, v.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if vendored, ok := attrs.flag(path, linguistVendored); ok {
+		return vendored, nil
+	}
+
+	return enry.IsVendor(path), nil
 }
 
 func (v *IsVendor) String() string {
-	return fmt.Sprintf("IS_VENDOR(%s)", v.Child)
+	if v.Repository == nil {
+		return fmt.Sprintf("IS_VENDOR(%s)", v.Path)
+	}
+
+	return fmt.Sprintf("IS_VENDOR(%s, %s, %s)", v.Path, v.Repository, v.Commit)
 }
 
 // WithChildren implements the Expression interface.
-func (v IsVendor) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	if len(children) != 1 {
-		return nil, sql.ErrInvalidChildrenNumber.New(v, len(children), 1)
+func (v *IsVendor) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := len(v.Children())
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(v, len(children), expected)
 	}
-	return NewIsVendor(children[0]), nil
+
+	return NewIsVendor(children...)
 }
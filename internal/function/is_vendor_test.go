@@ -36,7 +36,8 @@ func TestIsVendor(t *testing.T) {
 		},
 	}
 
-	fn := NewIsVendor(expression.NewGetField(0, sql.Text, "x", true))
+	fn, err := NewIsVendor(expression.NewGetField(0, sql.Text, "x", true))
+	require.NoError(t, err)
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
 			result, err := fn.Eval(sql.NewEmptyContext(), sql.Row{tt.path})
@@ -38,38 +38,53 @@ func init() {
 }
 
 // Language gets the language of a file given its path and
-// the optional content of the file.
+// the optional content of the file. If the repository and commit
+// are given, the linguist-language attribute set in the
+// .gitattributes files of the commit tree takes precedence.
 type Language struct {
-	Left  sql.Expression
-	Right sql.Expression
+	Left       sql.Expression
+	Right      sql.Expression
+	Repository sql.Expression
+	Commit     sql.Expression
 }
 
 // NewLanguage creates a new Language UDF.
 func NewLanguage(args ...sql.Expression) (sql.Expression, error) {
-	var left, right sql.Expression
+	f := &Language{}
 	switch len(args) {
 	case 1:
-		left = args[0]
+		f.Left = args[0]
 	case 2:
-		left = args[0]
-		right = args[1]
+		f.Left, f.Right = args[0], args[1]
+	case 4:
+		f.Left, f.Right, f.Repository, f.Commit = args[0], args[1], args[2], args[3]
 	default:
-		return nil, sql.ErrInvalidArgumentNumber.New("1 or 2", len(args))
+		return nil, sql.ErrInvalidArgumentNumber.New("language", "1, 2 or 4", len(args))
 	}
 
-	return &Language{left, right}, nil
+	return f, nil
 }
 
 // Resolved implements the Expression interface.
 func (f *Language) Resolved() bool {
-	return f.Left.Resolved() && (f.Right == nil || f.Right.Resolved())
+	for _, e := range f.Children() {
+		if !e.Resolved() {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (f *Language) String() string {
-	if f.Right == nil {
+	switch {
+	case f.Right == nil:
 		return fmt.Sprintf("language(%s)", f.Left)
+	case f.Repository == nil:
+		return fmt.Sprintf("language(%s, %s)", f.Left, f.Right)
+	default:
+		return fmt.Sprintf("language(%s, %s, %s, %s)", f.Left, f.Right, f.Repository, f.Commit)
 	}
-	return fmt.Sprintf("language(%s, %s)", f.Left, f.Right)
 }
 
 // IsNullable implements the Expression interface.
@@ -84,11 +99,7 @@ func (Language) Type() sql.Type {
 
 // WithChildren implements the Expression interface.
 func (f *Language) WithChildren(children ...sql.Expression) (sql.Expression, error) {
-	expected := 1
-	if f.Right != nil {
-		expected = 2
-	}
-
+	expected := len(f.Children())
 	if len(children) != expected {
 		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
 	}
@@ -116,6 +127,16 @@ func (f *Language) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
 	}
 
 	path := left.(string)
+
+	attrs, err := treeAttributes(ctx, "language", row, f.Repository, f.Commit)
+	if err != nil {
+		return nil, err
+	}
+
+	if lang, ok := attrs.language(path); ok {
+		return lang, nil
+	}
+
 	var blob []byte
 
 	if f.Right != nil {
@@ -187,9 +208,12 @@ func filenameHash(filename string) []byte {
 
 // Children implements the Expression interface.
 func (f *Language) Children() []sql.Expression {
-	if f.Right == nil {
+	switch {
+	case f.Right == nil:
 		return []sql.Expression{f.Left}
+	case f.Repository == nil:
+		return []sql.Expression{f.Left, f.Right}
+	default:
+		return []sql.Expression{f.Left, f.Right, f.Repository, f.Commit}
 	}
-
-	return []sql.Expression{f.Left, f.Right}
 }
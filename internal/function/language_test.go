@@ -1,8 +1,11 @@
 package function
 
 import (
+	"context"
 	"testing"
 
+	"github.com/src-d/gitbase"
+
 	"github.com/stretchr/testify/require"
 	errors "gopkg.in/src-d/go-errors.v1"
 	"github.com/src-d/go-mysql-server/sql"
@@ -53,3 +56,37 @@ func TestLanguage(t *testing.T) {
 		})
 	}
 }
+
+func TestLanguageWithRepository(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	f, err := NewLanguage(
+		expression.NewGetField(0, sql.Text, "file_path", false),
+		expression.NewGetField(1, sql.Blob, "blob_content", true),
+		expression.NewGetField(2, sql.Text, "repository_id", true),
+		expression.NewGetField(3, sql.Text, "commit_hash", true),
+	)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		row  sql.Row
+	}{
+		{"no attributes", sql.NewRow("foo.rb", "", "worktree", "HEAD")},
+		{"null repository", sql.NewRow("foo.rb", "", nil, "HEAD")},
+		{"unknown repository", sql.NewRow("foo.rb", "", "foo", "HEAD")},
+		{"unknown commit", sql.NewRow("foo.rb", "", "worktree", "foo")},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, "Ruby", val)
+		})
+	}
+}
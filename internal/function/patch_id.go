@@ -0,0 +1,120 @@
+package function
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/gitbase/internal/commitstats"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+const (
+	patchIDCacheSizeKey     = "GITBASE_PATCH_ID_CACHE_SIZE"
+	defaultPatchIDCacheSize = 10000
+)
+
+func patchIDCacheSize() int {
+	v := os.Getenv(patchIDCacheSizeKey)
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		size = defaultPatchIDCacheSize
+	}
+
+	return size
+}
+
+var patchIDCache *lru.TwoQueueCache
+
+func init() {
+	var err error
+	patchIDCache, err = lru.New2Q(patchIDCacheSize())
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize patch id cache: %s", err))
+	}
+}
+
+// PatchID returns the stable patch ID of a commit, which is the same for
+// commits introducing the same changes, such as cherry-picked or rebased
+// ones.
+type PatchID struct {
+	Repository sql.Expression
+	Commit     sql.Expression
+}
+
+// NewPatchID creates a new PatchID UDF.
+func NewPatchID(repo, commit sql.Expression) sql.Expression {
+	return &PatchID{repo, commit}
+}
+
+func (f *PatchID) String() string {
+	return fmt.Sprintf("patch_id(%s, %s)", f.Repository, f.Commit)
+}
+
+// Type implements the Expression interface.
+func (PatchID) Type() sql.Type {
+	return sql.Text
+}
+
+// IsNullable implements the Expression interface.
+func (*PatchID) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *PatchID) Resolved() bool {
+	return f.Repository.Resolved() && f.Commit.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *PatchID) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Commit}
+}
+
+// WithChildren implements the Expression interface.
+func (f *PatchID) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewPatchID(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *PatchID) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.PatchID")
+	defer span.Finish()
+
+	hash, err := exprToString(ctx, f.Commit, row)
+	if err != nil {
+		return nil, err
+	}
+
+	// commits with the same hash have the same changes, so there is no
+	// need to resolve the repository if the patch ID is cached.
+	if id, ok := patchIDCache.Get(hash); ok {
+		return id, nil
+	}
+
+	r, commit, err := resolveRepoCommit(ctx, "patch_id", row, f.Repository, f.Commit)
+	if err != nil || commit == nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	id, err := commitstats.PatchID(commit)
+	if err != nil {
+		ctx.Warn(0, "patch_id: unable to compute patch id of commit %s", commit.Hash)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+			"commit":     commit.Hash,
+		}).Error("patch_id: unable to compute patch id")
+		return nil, nil
+	}
+
+	patchIDCache.Add(commit.Hash.String(), id.String())
+	return id.String(), nil
+}
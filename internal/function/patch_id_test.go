@@ -0,0 +1,58 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchID(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewPatchID(
+		expression.NewGetField(0, sql.Text, "repository_id", true),
+		expression.NewGetField(1, sql.Text, "commit_hash", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null repository", sql.NewRow(nil, "b8e471f58bcbca63b07bda20e428190409c2db47"), nil},
+		{"null commit", sql.NewRow("worktree", nil), nil},
+		{"unknown repository", sql.NewRow("foo", "b8e471f58bcbca63b07bda20e428190409c2db47"), nil},
+		{"unknown commit", sql.NewRow("worktree", "foo"), nil},
+		{
+			"commit",
+			sql.NewRow("worktree", "b8e471f58bcbca63b07bda20e428190409c2db47"),
+			"654add56d9609afaaac7c05388967247d466a307",
+		},
+		{
+			"cached commit",
+			sql.NewRow("foo", "b8e471f58bcbca63b07bda20e428190409c2db47"),
+			"654add56d9609afaaac7c05388967247d466a307",
+		},
+		{
+			"merge with the same changes",
+			sql.NewRow("worktree", "1669dce138d9b841a518c64b10914d88f5e488ea"),
+			"654add56d9609afaaac7c05388967247d466a307",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
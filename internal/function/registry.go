@@ -6,8 +6,10 @@ import "github.com/src-d/go-mysql-server/sql"
 var Functions = []sql.Function{
 	sql.FunctionN{Name: "commit_stats", Fn: NewCommitStats},
 	sql.FunctionN{Name: "commit_file_stats", Fn: NewCommitFileStats},
+	sql.Function2{Name: "commit_loc", Fn: NewCommitLOC},
 	sql.Function1{Name: "is_tag", Fn: NewIsTag},
 	sql.Function1{Name: "is_remote", Fn: NewIsRemote},
+	sql.Function2{Name: "repository_label", Fn: NewRepositoryLabel},
 	sql.FunctionN{Name: "language", Fn: NewLanguage},
 	sql.FunctionN{Name: "loc", Fn: NewLOC},
 	sql.FunctionN{Name: "uast", Fn: NewUAST},
@@ -15,5 +17,30 @@ var Functions = []sql.Function{
 	sql.Function2{Name: "uast_xpath", Fn: NewUASTXPath},
 	sql.Function2{Name: "uast_extract", Fn: NewUASTExtract},
 	sql.Function1{Name: "uast_children", Fn: NewUASTChildren},
-	sql.Function1{Name: "is_vendor", Fn: NewIsVendor},
+	sql.Function2{Name: "uast_find", Fn: NewUASTFind},
+	sql.Function1{Name: "uast_to_json", Fn: NewUASTToJSON},
+	sql.Function1{Name: "uast_to_yaml", Fn: NewUASTToYAML},
+	sql.Function1{Name: "uast_to_dot", Fn: NewUASTToDot},
+	sql.FunctionN{Name: "is_vendor", Fn: NewIsVendor},
+	sql.FunctionN{Name: "is_generated", Fn: NewIsGenerated},
+	sql.Function3{Name: "is_ignored", Fn: NewIsIgnored},
+	sql.Function3{Name: "code_owners", Fn: NewCodeOwners},
+	sql.Function1{Name: "parse_conventional_commit", Fn: NewParseConventionalCommit},
+	sql.FunctionN{Name: "issue_refs", Fn: NewIssueRefs},
+	sql.Function1{Name: "is_revert", Fn: NewIsRevert},
+	sql.Function2{Name: "patch_id", Fn: NewPatchID},
+	sql.Function2{Name: "tree_languages", Fn: NewTreeLanguages},
+	sql.Function2{Name: "code_metrics", Fn: NewCodeMetrics},
+	sql.Function2{Name: "cyclomatic_complexity", Fn: NewCyclomaticComplexity},
+	sql.Function3{Name: "uast_diff", Fn: NewUASTDiff},
+	sql.Function2{Name: "identifiers", Fn: NewIdentifiers},
+	sql.FunctionN{Name: "tokens", Fn: NewTokens},
+	sql.FunctionN{Name: "content_signature", Fn: NewContentSignature},
+	sql.Function2{Name: "signature_similarity", Fn: NewSignatureSimilarity},
+	sql.Function1{Name: "signature_buckets", Fn: NewSignatureBuckets},
+	sql.Function2{Name: "find_secrets", Fn: NewFindSecrets},
+	sql.Function1{Name: "semver_parse", Fn: NewSemverParse},
+	sql.Function2{Name: "semver_compare", Fn: NewSemverCompare},
+	sql.Function2{Name: "semver_satisfies", Fn: NewSemverSatisfies},
+	sql.Function1{Name: "is_semver", Fn: NewIsSemver},
 }
@@ -0,0 +1,91 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// RepositoryLabel returns the value of a label of a repository, as given in
+// the configuration of the directory the repository was loaded from.
+type RepositoryLabel struct {
+	Repository sql.Expression
+	Name       sql.Expression
+}
+
+// NewRepositoryLabel creates a new RepositoryLabel UDF.
+func NewRepositoryLabel(repo, name sql.Expression) sql.Expression {
+	return &RepositoryLabel{repo, name}
+}
+
+func (f *RepositoryLabel) String() string {
+	return fmt.Sprintf("repository_label(%s, %s)", f.Repository, f.Name)
+}
+
+// Type implements the Expression interface.
+func (RepositoryLabel) Type() sql.Type {
+	return sql.Text
+}
+
+// IsNullable implements the Expression interface.
+func (*RepositoryLabel) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *RepositoryLabel) Resolved() bool {
+	return f.Repository.Resolved() && f.Name.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *RepositoryLabel) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Name}
+}
+
+// WithChildren implements the Expression interface.
+func (f *RepositoryLabel) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewRepositoryLabel(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *RepositoryLabel) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.RepositoryLabel")
+	defer span.Finish()
+
+	repo, err := f.Repository.Eval(ctx, row)
+	if err != nil || repo == nil {
+		return nil, err
+	}
+
+	name, err := f.Name.Eval(ctx, row)
+	if err != nil || name == nil {
+		return nil, err
+	}
+
+	repo, err = sql.Text.Convert(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err = sql.Text.Convert(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := ctx.Session.(*gitbase.Session)
+	if !ok {
+		return nil, gitbase.ErrInvalidGitbaseSession.New(ctx.Session)
+	}
+
+	value, ok := s.Pool.Labels(repo.(string))[name.(string)]
+	if !ok {
+		return nil, nil
+	}
+
+	return value, nil
+}
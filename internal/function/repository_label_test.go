@@ -0,0 +1,48 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryLabel(t *testing.T) {
+	pool := gitbase.NewRepositoryPool(nil, nil)
+	pool.SetLabels("foo", map[string]string{"team": "core"})
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewRepositoryLabel(
+		expression.NewGetField(0, sql.Text, "repository_id", true),
+		expression.NewGetField(1, sql.Text, "name", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null repository", sql.NewRow(nil, "team"), nil},
+		{"null name", sql.NewRow("foo", nil), nil},
+		{"label", sql.NewRow("foo", "team"), "core"},
+		{"unknown label", sql.NewRow("foo", "owner"), nil},
+		{"repository without labels", sql.NewRow("bar", "team"), nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+
+	ctx = sql.NewContext(context.TODO(), sql.WithSession(sql.NewBaseSession()))
+	_, err := fn.Eval(ctx, sql.NewRow("foo", "team"))
+	require.True(t, gitbase.ErrInvalidGitbaseSession.Is(err))
+}
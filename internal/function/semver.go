@@ -0,0 +1,218 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/src-d/gitbase/internal/semver"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// SemverParse returns the parts of a semantic version, which may be the
+// name of a tag reference, as JSON.
+type SemverParse struct {
+	expression.UnaryExpression
+}
+
+// NewSemverParse creates a new SemverParse UDF.
+func NewSemverParse(e sql.Expression) sql.Expression {
+	return &SemverParse{expression.UnaryExpression{Child: e}}
+}
+
+func (f *SemverParse) String() string {
+	return fmt.Sprintf("semver_parse(%s)", f.Child)
+}
+
+// Type implements the Expression interface.
+func (SemverParse) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *SemverParse) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewSemverParse(children[0]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *SemverParse) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.SemverParse")
+	defer span.Finish()
+
+	v, err := evalVersion(ctx, f.Child, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	return *v, nil
+}
+
+// SemverCompare compares two semantic versions, returning -1, 0 or 1 if
+// the first one has lower, equal or higher precedence than the second one.
+type SemverCompare struct {
+	expression.BinaryExpression
+}
+
+// NewSemverCompare creates a new SemverCompare UDF.
+func NewSemverCompare(a, b sql.Expression) sql.Expression {
+	return &SemverCompare{expression.BinaryExpression{Left: a, Right: b}}
+}
+
+func (f *SemverCompare) String() string {
+	return fmt.Sprintf("semver_compare(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (SemverCompare) Type() sql.Type {
+	return sql.Int64
+}
+
+// WithChildren implements the Expression interface.
+func (f *SemverCompare) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewSemverCompare(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *SemverCompare) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.SemverCompare")
+	defer span.Finish()
+
+	a, err := evalVersion(ctx, f.Left, row)
+	if err != nil || a == nil {
+		return nil, err
+	}
+
+	b, err := evalVersion(ctx, f.Right, row)
+	if err != nil || b == nil {
+		return nil, err
+	}
+
+	return int64(a.Compare(b)), nil
+}
+
+// SemverSatisfies checks whether a semantic version satisfies a version
+// constraint, such as ">=1.2.0, <2.0.0" or "^1.2".
+type SemverSatisfies struct {
+	expression.BinaryExpression
+}
+
+// NewSemverSatisfies creates a new SemverSatisfies UDF.
+func NewSemverSatisfies(version, constraint sql.Expression) sql.Expression {
+	return &SemverSatisfies{expression.BinaryExpression{Left: version, Right: constraint}}
+}
+
+func (f *SemverSatisfies) String() string {
+	return fmt.Sprintf("semver_satisfies(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (SemverSatisfies) Type() sql.Type {
+	return sql.Boolean
+}
+
+// WithChildren implements the Expression interface.
+func (f *SemverSatisfies) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewSemverSatisfies(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *SemverSatisfies) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.SemverSatisfies")
+	defer span.Finish()
+
+	v, err := evalVersion(ctx, f.Left, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	constraint, err := f.Right.Eval(ctx, row)
+	if err != nil || constraint == nil {
+		return nil, err
+	}
+
+	constraint, err = sql.Text.Convert(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := semver.ParseConstraint(constraint.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Check(v), nil
+}
+
+// IsSemver checks whether the given string, which may be the name of a
+// tag reference, is a semantic version.
+type IsSemver struct {
+	expression.UnaryExpression
+}
+
+// NewIsSemver creates a new IsSemver UDF.
+func NewIsSemver(e sql.Expression) sql.Expression {
+	return &IsSemver{expression.UnaryExpression{Child: e}}
+}
+
+func (f *IsSemver) String() string {
+	return fmt.Sprintf("is_semver(%s)", f.Child)
+}
+
+// Type implements the Expression interface.
+func (IsSemver) Type() sql.Type {
+	return sql.Boolean
+}
+
+// WithChildren implements the Expression interface.
+func (f *IsSemver) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewIsSemver(children[0]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *IsSemver) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.IsSemver")
+	defer span.Finish()
+
+	v, err := evalVersion(ctx, f.Child, row)
+	if err != nil {
+		return nil, err
+	}
+
+	return v != nil, nil
+}
+
+// evalVersion evaluates the given expression and parses it as a semantic
+// version. If the result is null or not a valid version, nil is returned.
+func evalVersion(ctx *sql.Context, e sql.Expression, row sql.Row) (*semver.Version, error) {
+	v, err := e.Eval(ctx, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	v, err = sql.Text.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := semver.Parse(v.(string))
+	if err != nil {
+		return nil, nil
+	}
+
+	return version, nil
+}
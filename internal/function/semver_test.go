@@ -0,0 +1,116 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/src-d/gitbase/internal/semver"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemverParse(t *testing.T) {
+	f := NewSemverParse(expression.NewGetField(0, sql.Text, "", true))
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null", sql.NewRow(nil), nil},
+		{"invalid", sql.NewRow("refs/heads/master"), nil},
+		{
+			"tag",
+			sql.NewRow("refs/tags/v1.2.3-rc.1+build"),
+			semver.Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1", Build: "build"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	f := NewSemverCompare(
+		expression.NewGetField(0, sql.Text, "", true),
+		expression.NewGetField(1, sql.Text, "", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null", sql.NewRow(nil, "v1.0.0"), nil},
+		{"invalid", sql.NewRow("v1.0.0", "latest"), nil},
+		{"lower", sql.NewRow("refs/tags/v1.0.0-rc.1", "v1.0.0"), int64(-1)},
+		{"equal", sql.NewRow("refs/tags/v1.0.0", "1.0.0+build"), int64(0)},
+		{"higher", sql.NewRow("v1.10.0", "v1.9.0"), int64(1)},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestSemverSatisfies(t *testing.T) {
+	f := NewSemverSatisfies(
+		expression.NewGetField(0, sql.Text, "", true),
+		expression.NewGetField(1, sql.Text, "", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null version", sql.NewRow(nil, "^1.0"), nil},
+		{"null constraint", sql.NewRow("v1.0.0", nil), nil},
+		{"invalid version", sql.NewRow("latest", "^1.0"), nil},
+		{"satisfies", sql.NewRow("refs/tags/v1.2.3", ">=1.2, <2"), true},
+		{"does not satisfy", sql.NewRow("refs/tags/v2.0.0", "^1.2"), false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+
+	_, err := f.Eval(sql.NewEmptyContext(), sql.NewRow("v1.0.0", "foo"))
+	require.True(t, semver.ErrInvalidConstraint.Is(err))
+}
+
+func TestIsSemver(t *testing.T) {
+	f := NewIsSemver(expression.NewGetField(0, sql.Text, "", true))
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null", sql.NewRow(nil), false},
+		{"branch", sql.NewRow("refs/heads/master"), false},
+		{"tag", sql.NewRow("refs/tags/v1.0.0"), true},
+		{"version", sql.NewRow("1.0.0-beta"), true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package function
+
+import (
+	"fmt"
+
+	"github.com/src-d/gitbase/internal/tokenizer"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidNGramSize is returned when the size of the n-grams is not a
+// positive number.
+var ErrInvalidNGramSize = errors.NewKind("tokens: n-gram size must be greater than 0, got %d")
+
+// Identifiers returns the identifiers of a blob, split in words by their
+// camelCase and snake_case parts, without using bblfsh.
+type Identifiers struct {
+	expression.BinaryExpression
+}
+
+// NewIdentifiers creates a new Identifiers UDF.
+func NewIdentifiers(lang, blob sql.Expression) sql.Expression {
+	return &Identifiers{expression.BinaryExpression{Left: lang, Right: blob}}
+}
+
+func (f *Identifiers) String() string {
+	return fmt.Sprintf("identifiers(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the Expression interface.
+func (*Identifiers) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *Identifiers) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewIdentifiers(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *Identifiers) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.Identifiers")
+	defer span.Finish()
+
+	lang, blob, err := evalLangBlob(ctx, f.Left, f.Right, row)
+	if err != nil || blob == nil {
+		return nil, err
+	}
+
+	ids, ok := tokenizer.Identifiers(lang, blob)
+	if !ok {
+		return nil, nil
+	}
+
+	if ids == nil {
+		ids = []string{}
+	}
+
+	return ids, nil
+}
+
+// Tokens returns the lexical tokens of a blob, or the n-grams of them,
+// without comments and string literals, without using bblfsh.
+type Tokens struct {
+	Lang sql.Expression
+	Blob sql.Expression
+	N    sql.Expression
+}
+
+// NewTokens creates a new Tokens UDF.
+func NewTokens(args ...sql.Expression) (sql.Expression, error) {
+	var f Tokens
+	switch len(args) {
+	case 3:
+		f.N = args[2]
+		fallthrough
+	case 2:
+		f.Lang, f.Blob = args[0], args[1]
+	default:
+		return nil, sql.ErrInvalidArgumentNumber.New("tokens", "2 or 3", len(args))
+	}
+
+	return &f, nil
+}
+
+func (f *Tokens) String() string {
+	if f.N != nil {
+		return fmt.Sprintf("tokens(%s, %s, %s)", f.Lang, f.Blob, f.N)
+	}
+
+	return fmt.Sprintf("tokens(%s, %s)", f.Lang, f.Blob)
+}
+
+// Type implements the Expression interface.
+func (*Tokens) Type() sql.Type {
+	return sql.JSON
+}
+
+// IsNullable implements the Expression interface.
+func (*Tokens) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *Tokens) Resolved() bool {
+	return f.Lang.Resolved() && f.Blob.Resolved() && (f.N == nil || f.N.Resolved())
+}
+
+// Children implements the Expression interface.
+func (f *Tokens) Children() []sql.Expression {
+	if f.N != nil {
+		return []sql.Expression{f.Lang, f.Blob, f.N}
+	}
+
+	return []sql.Expression{f.Lang, f.Blob}
+}
+
+// WithChildren implements the Expression interface.
+func (f *Tokens) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	expected := len(f.Children())
+	if len(children) != expected {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), expected)
+	}
+
+	return NewTokens(children...)
+}
+
+// Eval implements the Expression interface.
+func (f *Tokens) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.Tokens")
+	defer span.Finish()
+
+	n := 1
+	if f.N != nil {
+		v, err := f.N.Eval(ctx, row)
+		if err != nil || v == nil {
+			return nil, err
+		}
+
+		v, err = sql.Int64.Convert(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if v.(int64) < 1 {
+			return nil, ErrInvalidNGramSize.New(v)
+		}
+
+		n = int(v.(int64))
+	}
+
+	lang, blob, err := evalLangBlob(ctx, f.Lang, f.Blob, row)
+	if err != nil || blob == nil {
+		return nil, err
+	}
+
+	tokens, ok := tokenizer.Tokens(lang, blob)
+	if !ok {
+		return nil, nil
+	}
+
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.Text
+	}
+
+	grams := tokenizer.NGrams(texts, n)
+	if grams == nil {
+		grams = []string{}
+	}
+
+	return grams, nil
+}
+
+// evalLangBlob evaluates the language and the blob of a tokenizer
+// function. The blob is nil if any of them is null.
+func evalLangBlob(
+	ctx *sql.Context,
+	langExpr, blobExpr sql.Expression,
+	row sql.Row,
+) (string, []byte, error) {
+	lang, err := langExpr.Eval(ctx, row)
+	if err != nil || lang == nil {
+		return "", nil, err
+	}
+
+	lang, err = sql.Text.Convert(lang)
+	if err != nil {
+		return "", nil, err
+	}
+
+	blob, err := blobExpr.Eval(ctx, row)
+	if err != nil || blob == nil {
+		return "", nil, err
+	}
+
+	blob, err = sql.Blob.Convert(blob)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return lang.(string), blob.([]byte), nil
+}
@@ -0,0 +1,110 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+const testTokensBlob = `package main
+
+// printValue prints a value.
+func printValue(myValue int) {
+	fmt.Println("value:", myValue)
+}
+`
+
+func TestIdentifiers(t *testing.T) {
+	f := NewIdentifiers(
+		expression.NewGetField(0, sql.Text, "", true),
+		expression.NewGetField(1, sql.Blob, "", true),
+	)
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null language", sql.NewRow(nil, testTokensBlob), nil},
+		{"null blob", sql.NewRow("Go", nil), nil},
+		{"unsupported language", sql.NewRow("Haskell", testTokensBlob), nil},
+		{"empty blob", sql.NewRow("Go", ""), []string{}},
+		{
+			"identifiers",
+			sql.NewRow("Go", testTokensBlob),
+			[]string{"main", "print", "value", "my", "value", "int", "fmt", "println", "my", "value"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(err)
+			require.Equal(tt.expected, val)
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null language", sql.NewRow(nil, testTokensBlob), nil},
+		{"null blob", sql.NewRow("Go", nil), nil},
+		{"unsupported language", sql.NewRow("Haskell", testTokensBlob), nil},
+		{"empty blob", sql.NewRow("Go", ""), []string{}},
+		{
+			"tokens",
+			sql.NewRow("go", "x := f(\"s\") // call\n"),
+			[]string{"x", ":=", "f", "(", ")"},
+		},
+		{
+			"bigrams",
+			sql.NewRow("go", "x := f(\"s\") // call\n", 2),
+			[]string{"x :=", ":= f", "f (", "( )"},
+		},
+		{"null n", sql.NewRow("go", "x := f()", nil), nil},
+		{"n longer than tokens", sql.NewRow("go", "x", 2), []string{}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			args := []sql.Expression{
+				expression.NewGetField(0, sql.Text, "", true),
+				expression.NewGetField(1, sql.Blob, "", true),
+			}
+			if len(tt.row) == 3 {
+				args = append(args, expression.NewGetField(2, sql.Int64, "", true))
+			}
+
+			f, err := NewTokens(args...)
+			require.NoError(err)
+
+			val, err := f.Eval(sql.NewEmptyContext(), tt.row)
+			require.NoError(err)
+			require.Equal(tt.expected, val)
+		})
+	}
+}
+
+func TestTokensInvalidNGramSize(t *testing.T) {
+	require := require.New(t)
+	f, err := NewTokens(
+		expression.NewLiteral("Go", sql.Text),
+		expression.NewLiteral([]byte("x"), sql.Blob),
+		expression.NewLiteral(int64(0), sql.Int64),
+	)
+	require.NoError(err)
+
+	_, err = f.Eval(sql.NewEmptyContext(), nil)
+	require.True(ErrInvalidNGramSize.Is(err))
+
+	_, err = NewTokens(expression.NewLiteral("Go", sql.Text))
+	require.True(sql.ErrInvalidArgumentNumber.Is(err))
+}
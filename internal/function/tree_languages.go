@@ -0,0 +1,301 @@
+package function
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hhatto/gocloc"
+	"github.com/sirupsen/logrus"
+	"github.com/src-d/enry/v2"
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	treeLanguagesCacheSizeKey     = "GITBASE_TREE_LANGUAGES_CACHE_SIZE"
+	defaultTreeLanguagesCacheSize = 10000
+)
+
+func treeLanguagesCacheSize() int {
+	v := os.Getenv(treeLanguagesCacheSizeKey)
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		size = defaultTreeLanguagesCacheSize
+	}
+
+	return size
+}
+
+var treeLanguagesCache *lru.TwoQueueCache
+
+func init() {
+	var err error
+	treeLanguagesCache, err = lru.New2Q(treeLanguagesCacheSize())
+	if err != nil {
+		panic(fmt.Errorf("cannot initialize tree languages cache: %s", err))
+	}
+}
+
+// LanguageStats are the stats of the files of a language in a tree.
+type LanguageStats struct {
+	Files     int   `json:"files"`
+	Bytes     int64 `json:"bytes"`
+	CodeLines int   `json:"code_lines"`
+}
+
+// TreeLanguages returns the number of files, bytes and lines of code of
+// each language in a tree, or in the tree of a commit.
+type TreeLanguages struct {
+	Repository sql.Expression
+	Tree       sql.Expression
+}
+
+// NewTreeLanguages creates a new TreeLanguages UDF.
+func NewTreeLanguages(repo, tree sql.Expression) sql.Expression {
+	return &TreeLanguages{repo, tree}
+}
+
+func (f *TreeLanguages) String() string {
+	return fmt.Sprintf("tree_languages(%s, %s)", f.Repository, f.Tree)
+}
+
+// Type implements the Expression interface.
+func (TreeLanguages) Type() sql.Type {
+	return sql.JSON
+}
+
+// IsNullable implements the Expression interface.
+func (*TreeLanguages) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *TreeLanguages) Resolved() bool {
+	return f.Repository.Resolved() && f.Tree.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *TreeLanguages) Children() []sql.Expression {
+	return []sql.Expression{f.Repository, f.Tree}
+}
+
+// WithChildren implements the Expression interface.
+func (f *TreeLanguages) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewTreeLanguages(children[0], children[1]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *TreeLanguages) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	span, ctx := ctx.Span("gitbase.TreeLanguages")
+	defer span.Finish()
+
+	repoID, err := f.Repository.Eval(ctx, row)
+	if err != nil || repoID == nil {
+		return nil, err
+	}
+
+	hash, err := exprToString(ctx, f.Tree, row)
+	if err != nil || hash == "" {
+		return nil, err
+	}
+
+	r, err := resolveRepo(ctx, row, f.Repository)
+	if err != nil {
+		ctx.Warn(0, "tree_languages: unable to resolve repository")
+		logrus.WithField("err", err).Error("tree_languages: unable to resolve repository")
+		return nil, nil
+	}
+	defer r.Close()
+
+	tree, err := resolveTree(r, hash)
+	if err != nil {
+		ctx.Warn(0, "tree_languages: unable to resolve tree %s of repository: %v", hash, r)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+			"tree":       hash,
+		}).Error("tree_languages: unable to resolve tree")
+		return nil, nil
+	}
+
+	stats, err := treeLanguageStats(tree)
+	if err != nil {
+		ctx.Warn(0, "tree_languages: unable to compute stats of tree %s", tree.Hash)
+		logrus.WithFields(logrus.Fields{
+			"err":        err,
+			"repository": r,
+			"tree":       tree.Hash,
+		}).Error("tree_languages: unable to compute stats")
+		return nil, nil
+	}
+
+	return stats, nil
+}
+
+// resolveTree returns the tree with the given hash or the tree of the
+// commit with the given hash or revision.
+func resolveTree(r *gitbase.Repository, str string) (*object.Tree, error) {
+	if commitHash, err := r.ResolveRevision(plumbing.Revision(str)); err == nil {
+		if commit, err := r.CommitObject(*commitHash); err == nil {
+			return commit.Tree()
+		}
+	}
+
+	return r.TreeObject(plumbing.NewHash(str))
+}
+
+// languageSniffLen is the number of bytes of every file used to detect
+// its language and whether it's generated. The rest of the file is only
+// streamed to count its lines.
+const languageSniffLen = 16 * 1024
+
+// treeLanguageStats returns the stats of each language in the tree and all
+// its subtrees. As in commit_loc, vendored and generated files are skipped,
+// and as in language, is_vendor and is_generated, the linguist attributes
+// of the .gitattributes files of the tree take precedence.
+func treeLanguageStats(tree *object.Tree) (map[string]LanguageStats, error) {
+	attrs, err := cachedLinguistAttributes(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	// the stats of a subtree depend on its path and on the attributes of
+	// the whole tree, if any.
+	var scope string
+	if len(attrs) > 0 {
+		scope = tree.Hash.String()
+	}
+
+	return subtreeLanguageStats(tree, attrs, scope, "")
+}
+
+// subtreeLanguageStats returns the stats of the tree in the given
+// directory. The stats of every tree are cached by its hash, directory and
+// scope, so the subtrees that didn't change between commits are only
+// walked once. Submodules, symbolic links and files without a known
+// language are not taken into account.
+func subtreeLanguageStats(
+	tree *object.Tree,
+	attrs linguistAttributes,
+	scope, dir string,
+) (map[string]LanguageStats, error) {
+	key := tree.Hash.String() + ":" + scope + ":" + dir
+	if stats, ok := treeLanguagesCache.Get(key); ok {
+		return stats.(map[string]LanguageStats), nil
+	}
+
+	stats := make(map[string]LanguageStats)
+	for _, e := range tree.Entries {
+		p := path.Join(dir, e.Name)
+		switch e.Mode {
+		case filemode.Dir:
+			subtree, err := tree.Tree(e.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			substats, err := subtreeLanguageStats(subtree, attrs, scope, p)
+			if err != nil {
+				return nil, err
+			}
+
+			for lang, s := range substats {
+				stats[lang] = stats[lang].add(s)
+			}
+		case filemode.Regular, filemode.Executable, filemode.Deprecated:
+			vendored, ok := attrs.flag(p, linguistVendored)
+			if !ok {
+				vendored = enry.IsVendor(p)
+			}
+
+			if vendored {
+				continue
+			}
+
+			f, err := tree.TreeEntryFile(&e)
+			if err != nil {
+				return nil, err
+			}
+
+			lang, s, err := blobLanguageStats(p, &f.Blob, attrs)
+			if err != nil {
+				return nil, err
+			}
+
+			if lang != "" {
+				stats[lang] = stats[lang].add(s)
+			}
+		}
+	}
+
+	treeLanguagesCache.Add(key, stats)
+	return stats, nil
+}
+
+// blobLanguageStats returns the language of a file and its stats, or no
+// language if it's generated. Lines of code are only counted for the
+// languages known by gocloc.
+func blobLanguageStats(
+	p string,
+	blob *object.Blob,
+	attrs linguistAttributes,
+) (string, LanguageStats, error) {
+	reader, err := blob.Reader()
+	if err != nil {
+		return "", LanguageStats{}, err
+	}
+	defer reader.Close()
+
+	prefix, err := ioutil.ReadAll(io.LimitReader(reader, languageSniffLen))
+	if err != nil {
+		return "", LanguageStats{}, err
+	}
+
+	generated, ok := attrs.flag(p, linguistGenerated)
+	if !ok {
+		generated = isGenerated(p, prefix)
+	}
+
+	if generated {
+		return "", LanguageStats{}, nil
+	}
+
+	lang, ok := attrs.language(p)
+	if !ok {
+		lang = enry.GetLanguage(p, prefix)
+	}
+
+	if lang == "" {
+		return "", LanguageStats{}, nil
+	}
+
+	stats := LanguageStats{Files: 1, Bytes: blob.Size}
+	if l, ok := languages.Langs[lang]; ok {
+		content := io.MultiReader(bytes.NewReader(prefix), reader)
+		file := gocloc.AnalyzeReader(p, l, content, &gocloc.ClocOptions{})
+		stats.CodeLines = int(file.Code)
+	}
+
+	return lang, stats, nil
+}
+
+func (s LanguageStats) add(other LanguageStats) LanguageStats {
+	return LanguageStats{
+		Files:     s.Files + other.Files,
+		Bytes:     s.Bytes + other.Bytes,
+		CodeLines: s.CodeLines + other.CodeLines,
+	}
+}
@@ -0,0 +1,95 @@
+package function
+
+import (
+	"context"
+	"testing"
+
+	"github.com/src-d/gitbase"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestTreeLanguages(t *testing.T) {
+	pool, cleanup := setupPool(t)
+	defer cleanup()
+
+	session := gitbase.NewSession(pool)
+	ctx := sql.NewContext(context.TODO(), sql.WithSession(session))
+
+	fn := NewTreeLanguages(
+		expression.NewGetField(0, sql.Text, "repository_id", true),
+		expression.NewGetField(1, sql.Text, "tree_hash", true),
+	)
+
+	r, err := pool.GetRepo("worktree")
+	require.NoError(t, err)
+	commit, err := r.CommitObject(plumbing.NewHash("6ecf0ef2c2dffb796033e5a02219af86ec6584e5"))
+	require.NoError(t, err)
+	treeHash := commit.TreeHash.String()
+	r.Close()
+
+	// .gitignore and vendor/foo.go are vendored files
+	first := map[string]LanguageStats{
+		"Text": {Files: 1, Bytes: 1072},
+	}
+
+	last := map[string]LanguageStats{
+		"Go":   {Files: 1, Bytes: 2780, CodeLines: 116},
+		"JSON": {Files: 2, Bytes: 218554},
+		"PHP":  {Files: 1, Bytes: 11488, CodeLines: 186},
+		"Text": {Files: 1, Bytes: 1072},
+	}
+
+	testCases := []struct {
+		name     string
+		row      sql.Row
+		expected interface{}
+	}{
+		{"null repository", sql.NewRow(nil, treeHash), nil},
+		{"null tree", sql.NewRow("worktree", nil), nil},
+		{"unknown repository", sql.NewRow("foo", treeHash), nil},
+		{"unknown tree", sql.NewRow("worktree", "0000000000000000000000000000000000000000"), nil},
+		{"first commit", sql.NewRow("worktree", "b029517f6300c2da0f4b651b8642506cd6aaf45d"), first},
+		{"commit", sql.NewRow("worktree", "6ecf0ef2c2dffb796033e5a02219af86ec6584e5"), last},
+		{"tree", sql.NewRow("worktree", treeHash), last},
+		{"cached tree of unknown repository", sql.NewRow("foo", treeHash), nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fn.Eval(ctx, tt.row)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestTreeLanguagesAttributes(t *testing.T) {
+	require := require.New(t)
+
+	tree := newTestTree(t, map[string]string{
+		".gitattributes": "*.txt linguist-language=Go\n" +
+			"third_party/** linguist-vendored\n" +
+			"vendor/** -linguist-vendored\n" +
+			"gen.go linguist-generated\n" +
+			"lib/*.go -linguist-generated\n",
+		"main.go":            "package main\n\nfunc main() {}\n",
+		"gen.go":             "package main\n",
+		"notes.txt":          "package notes\n",
+		"lib/lib.go":         "// Code generated by foo. DO NOT EDIT.\npackage lib\n",
+		"lib/other.go":       "package lib\n",
+		"third_party/a.go":   "package a\n",
+		"vendor/b.go":        "package b\n",
+		"node_modules/c.js":  "var c = 1;\n",
+		"src/gen/parser.go":  "// Code generated by goyacc. DO NOT EDIT.\npackage gen\n",
+		"src/gen/README.txt": "readme\n",
+	})
+
+	stats, err := treeLanguageStats(tree)
+	require.NoError(err)
+	require.Equal(map[string]LanguageStats{
+		"Go": {Files: 6, Bytes: 123, CodeLines: 7},
+	}, stats)
+}
@@ -56,22 +56,57 @@ func observeQuery(lang, xpath string, t time.Time) func(bool) {
 var uastCache *lru.Cache
 var uastMaxBlobSize int
 
-func init() {
-	s := os.Getenv(uastCacheSizeKey)
-	size, err := strconv.Atoi(s)
+func uastCacheSize() int {
+	size, err := strconv.Atoi(os.Getenv(uastCacheSizeKey))
 	if err != nil || size <= 0 {
 		size = defaultUASTCacheSize
 	}
 
-	uastCache, err = lru.New(size)
+	return size
+}
+
+func uastMaxBlobSizeFromEnv() int {
+	size, err := strconv.Atoi(os.Getenv(uastMaxBlobSizeKey))
 	if err != nil {
-		panic(fmt.Errorf("cannot initialize UAST cache: %s", err))
+		size = defaultUASTMaxBlobSize
 	}
 
-	uastMaxBlobSize, err = strconv.Atoi(os.Getenv(uastMaxBlobSizeKey))
+	return size
+}
+
+func init() {
+	var err error
+	uastCache, err = lru.New(uastCacheSize())
 	if err != nil {
-		uastMaxBlobSize = defaultUASTMaxBlobSize
+		panic(fmt.Errorf("cannot initialize UAST cache: %s", err))
 	}
+
+	uastMaxBlobSize = uastMaxBlobSizeFromEnv()
+}
+
+// isUASTBlobTooBig reports whether the given blob exceeds the maximum size
+// of blobs that can be sent to bblfsh. If so, it also warns the user.
+func isUASTBlobTooBig(ctx *sql.Context, blob []byte) bool {
+	if uastMaxBlobSize < 0 || len(blob) <= uastMaxBlobSize {
+		return false
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"max":  uastMaxBlobSize,
+		"size": len(blob),
+	}).Warnf(
+		"uast will be skipped, file is too big to send to bblfsh."+
+			"This can be configured using %s environment variable",
+		uastMaxBlobSizeKey,
+	)
+
+	ctx.Warn(
+		0,
+		"uast will be skipped, file is too big to send to bblfsh."+
+			"This can be configured using %s environment variable",
+		uastMaxBlobSizeKey,
+	)
+	return true
 }
 
 // uastFunc shouldn't be used as an sql.Expression itself.
@@ -200,22 +235,7 @@ func (u *uastFunc) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err err
 		return nil, nil
 	}
 
-	if uastMaxBlobSize >= 0 && len(bytes) > uastMaxBlobSize {
-		logrus.WithFields(logrus.Fields{
-			"max":  uastMaxBlobSize,
-			"size": len(bytes),
-		}).Warnf(
-			"uast will be skipped, file is too big to send to bblfsh."+
-				"This can be configured using %s environment variable",
-			uastMaxBlobSizeKey,
-		)
-
-		ctx.Warn(
-			0,
-			"uast will be skipped, file is too big to send to bblfsh."+
-				"This can be configured using %s environment variable",
-			uastMaxBlobSizeKey,
-		)
+	if isUASTBlobTooBig(ctx, bytes) {
 		return nil, nil
 	}
 
@@ -250,22 +270,13 @@ func (u *uastFunc) getUAST(
 		return nil, err
 	}
 
-	var node nodes.Node
-	value, ok := uastCache.Get(key)
-	if ok {
-		node = value.(nodes.Node)
-	} else {
-		var err error
-		node, err = getUASTFromBblfsh(ctx, blob, lang, xpath, mode)
-		if err != nil {
-			if ErrParseBlob.Is(err) || derrors.ErrSyntax.Is(err) {
-				return nil, nil
-			}
-
-			return nil, err
+	node, ok, err := getCachedUAST(ctx, key, blob, lang, mode)
+	if err != nil {
+		if ErrParseBlob.Is(err) || derrors.ErrSyntax.Is(err) {
+			return nil, nil
 		}
 
-		uastCache.Add(key, node)
+		return nil, err
 	}
 
 	var nodeArray nodes.Array
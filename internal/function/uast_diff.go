@@ -0,0 +1,376 @@
+package function
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// Classifications of the changes between two UASTs.
+const (
+	// noChange is the classification of UASTs that are the same once
+	// positions and comments are ignored.
+	noChange = "none"
+	// apiChange is the classification of changes adding or removing
+	// functions, or changing their signatures.
+	apiChange = "api change"
+	// refactorChange is the classification of changes that keep all the
+	// functions and their signatures.
+	refactorChange = "refactor"
+)
+
+// Kinds of the nodes reported by UASTDiff.
+const (
+	functionNode = "function"
+	importNode   = "import"
+)
+
+// Kinds of changes of a function.
+const (
+	signatureChange = "signature"
+	bodyChange      = "body"
+)
+
+var (
+	commentType        = uast.TypeOf(uast.Comment{})
+	identifierType     = uast.TypeOf(uast.Identifier{})
+	qualifiedIdentType = uast.TypeOf(uast.QualifiedIdentifier{})
+	stringType         = uast.TypeOf(uast.String{})
+	importTypes        = map[string]bool{
+		uast.TypeOf(uast.Import{}):          true,
+		uast.TypeOf(uast.RuntimeImport{}):   true,
+		uast.TypeOf(uast.RuntimeReImport{}): true,
+		uast.TypeOf(uast.InlineImport{}):    true,
+	}
+)
+
+// NodeChange is a function or import added, removed or changed between
+// two UASTs.
+type NodeChange struct {
+	Kind   string `json:"Kind"`
+	Name   string `json:"Name"`
+	Line   int    `json:"Line"`
+	Change string `json:"Change,omitempty"`
+}
+
+// UASTDiffResult is the result of the UASTDiff function.
+type UASTDiffResult struct {
+	Language       string       `json:"Language"`
+	Classification string       `json:"Classification"`
+	Added          []NodeChange `json:"Added"`
+	Removed        []NodeChange `json:"Removed"`
+	Changed        []NodeChange `json:"Changed"`
+}
+
+// UASTDiff compares the semantic UASTs of two blobs and returns the
+// functions and imports added, removed or changed between them. Changes
+// in positions, white spaces and comments are ignored.
+type UASTDiff struct {
+	Left  sql.Expression
+	Right sql.Expression
+	Lang  sql.Expression
+
+	h hash.Hash
+	m sync.Mutex
+}
+
+// NewUASTDiff creates a new UASTDiff UDF.
+func NewUASTDiff(left, right, lang sql.Expression) sql.Expression {
+	return &UASTDiff{Left: left, Right: right, Lang: lang, h: sha1.New()}
+}
+
+func (f *UASTDiff) String() string {
+	return fmt.Sprintf("uast_diff(%s, %s, %s)", f.Left, f.Right, f.Lang)
+}
+
+// Type implements the Expression interface.
+func (*UASTDiff) Type() sql.Type {
+	return sql.JSON
+}
+
+// IsNullable implements the Expression interface.
+func (*UASTDiff) IsNullable() bool {
+	return true
+}
+
+// Resolved implements the Expression interface.
+func (f *UASTDiff) Resolved() bool {
+	return f.Left.Resolved() && f.Right.Resolved() && f.Lang.Resolved()
+}
+
+// Children implements the Expression interface.
+func (f *UASTDiff) Children() []sql.Expression {
+	return []sql.Expression{f.Left, f.Right, f.Lang}
+}
+
+// WithChildren implements the Expression interface.
+func (f *UASTDiff) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 3 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 3)
+	}
+
+	return NewUASTDiff(children[0], children[1], children[2]), nil
+}
+
+// Eval implements the Expression interface.
+func (f *UASTDiff) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("uast_diff: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.UASTDiff")
+	defer span.Finish()
+
+	lang, err := exprToString(ctx, f.Lang, row)
+	if err != nil {
+		return nil, err
+	}
+
+	lang = strings.ToLower(lang)
+
+	left, ok, err := f.evalUAST(ctx, f.Left, lang, row)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	right, ok, err := f.evalUAST(ctx, f.Right, lang, row)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return diffUASTs(lang, left, right), nil
+}
+
+// evalUAST returns the semantic UAST of the blob of the given expression.
+// An empty blob has an empty UAST. If the blob is null or can't be parsed,
+// the returned boolean is false.
+func (f *UASTDiff) evalUAST(
+	ctx *sql.Context,
+	e sql.Expression,
+	lang string,
+	row sql.Row,
+) (nodes.Node, bool, error) {
+	blob, err := e.Eval(ctx, row)
+	if err != nil || blob == nil {
+		return nil, false, err
+	}
+
+	blob, err = sql.Blob.Convert(blob)
+	if err != nil {
+		return nil, false, err
+	}
+
+	bytes := blob.([]byte)
+	if len(bytes) == 0 {
+		return nil, true, nil
+	}
+
+	node, err := semanticUAST(ctx, f.h, &f.m, lang, bytes)
+	if err != nil || node == nil {
+		return nil, false, err
+	}
+
+	return node, true, nil
+}
+
+// diffUASTs compares the functions and imports of two semantic UASTs.
+func diffUASTs(lang string, left, right nodes.Node) UASTDiffResult {
+	result := UASTDiffResult{
+		Language:       lang,
+		Classification: noChange,
+		Added:          []NodeChange{},
+		Removed:        []NodeChange{},
+		Changed:        []NodeChange{},
+	}
+
+	if canonicalHash(left) == canonicalHash(right) {
+		return result
+	}
+
+	before, after := collectDecls(left), collectDecls(right)
+	for _, d := range after.list {
+		old, ok := before.byKey[d.key]
+		switch {
+		case !ok:
+			result.Added = append(result.Added, d.change(""))
+		case old.signature != d.signature:
+			result.Changed = append(result.Changed, d.change(signatureChange))
+		case old.body != d.body:
+			result.Changed = append(result.Changed, d.change(bodyChange))
+		}
+	}
+
+	for _, d := range before.list {
+		if _, ok := after.byKey[d.key]; !ok {
+			result.Removed = append(result.Removed, d.change(""))
+		}
+	}
+
+	result.Classification = refactorChange
+	for _, changes := range [][]NodeChange{result.Added, result.Removed, result.Changed} {
+		for _, c := range changes {
+			if c.Kind == functionNode && c.Change != bodyChange {
+				result.Classification = apiChange
+			}
+		}
+	}
+
+	return result
+}
+
+// decl is a function or import found in a UAST.
+type decl struct {
+	kind      string
+	name      string
+	key       string
+	line      int
+	signature nodes.Hash
+	body      nodes.Hash
+}
+
+func (d *decl) change(kind string) NodeChange {
+	return NodeChange{Kind: d.kind, Name: d.name, Line: d.line, Change: kind}
+}
+
+// decls are the declarations of a UAST, in order of appearance and by
+// key. Declarations with the same name, such as methods of different
+// types, are told apart by the number of previous ones with the name.
+type decls struct {
+	list  []*decl
+	byKey map[string]*decl
+}
+
+func (ds *decls) add(d *decl) {
+	d.key = d.kind + ":" + d.name
+	for i := 2; ds.byKey[d.key] != nil; i++ {
+		d.key = fmt.Sprintf("%s:%s#%d", d.kind, d.name, i)
+	}
+
+	ds.list = append(ds.list, d)
+	ds.byKey[d.key] = d
+}
+
+// collectDecls returns the named functions and the imports of a UAST.
+// Functions declared inside other functions are part of their body.
+func collectDecls(root nodes.Node) *decls {
+	ds := &decls{byKey: make(map[string]*decl)}
+
+	var visit func(n nodes.Node, line int)
+	visit = func(n nodes.Node, line int) {
+		switch n := n.(type) {
+		case nodes.Array:
+			for _, c := range n {
+				visit(c, line)
+			}
+		case nodes.Object:
+			if start := uast.PositionsOf(n).Start(); start != nil {
+				line = int(start.Line)
+			}
+
+			typ := uast.TypeOf(n)
+			switch {
+			case typ == aliasType && uast.TypeOf(n["Node"]) == functionType:
+				fn := n["Node"].(nodes.Object)
+				ds.add(&decl{
+					kind:      functionNode,
+					name:      aliasName(n),
+					line:      line,
+					signature: canonicalHash(fn["Type"]),
+					body:      canonicalHash(fn["Body"]),
+				})
+				return
+			case typ == functionType:
+				return
+			case importTypes[typ]:
+				if name := importPath(n["Path"]); name != "" {
+					ds.add(&decl{kind: importNode, name: name, line: line})
+				}
+				return
+			}
+
+			for _, k := range n.Keys() {
+				if !isCommonProp(k) {
+					visit(n[k], line)
+				}
+			}
+		}
+	}
+
+	visit(root, 0)
+	return ds
+}
+
+// importPath returns the name of the module imported, which may be a
+// string, a qualified identifier or an alias of any of them.
+func importPath(n nodes.Node) string {
+	obj, ok := n.(nodes.Object)
+	if !ok {
+		return ""
+	}
+
+	switch uast.TypeOf(obj) {
+	case stringType:
+		s, _ := obj["Value"].(nodes.String)
+		return string(s)
+	case identifierType:
+		s, _ := obj["Name"].(nodes.String)
+		return string(s)
+	case qualifiedIdentType:
+		names, _ := obj["Names"].(nodes.Array)
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, importPath(name))
+		}
+		return strings.Join(parts, ".")
+	case aliasType:
+		return importPath(obj["Node"])
+	}
+
+	return ""
+}
+
+// canonicalHash returns the hash of a node ignoring its positions and
+// comments, so nodes that only differ in formatting or comments have the
+// same hash.
+func canonicalHash(n nodes.Node) nodes.Hash {
+	return nodes.HashOf(withoutComments(n))
+}
+
+// withoutComments returns a copy of the node without positions and comment
+// nodes.
+func withoutComments(n nodes.Node) nodes.Node {
+	switch n := n.(type) {
+	case nodes.Array:
+		arr := make(nodes.Array, 0, len(n))
+		for _, c := range n {
+			if !isComment(c) {
+				arr = append(arr, withoutComments(c))
+			}
+		}
+		return arr
+	case nodes.Object:
+		obj := make(nodes.Object, len(n))
+		for k, v := range n {
+			if k == uast.KeyPos || isComment(v) {
+				continue
+			}
+			obj[k] = withoutComments(v)
+		}
+		return obj
+	}
+
+	return n
+}
+
+func isComment(n nodes.Node) bool {
+	obj, ok := n.(nodes.Object)
+	return ok && uast.TypeOf(obj) == commentType
+}
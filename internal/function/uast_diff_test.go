@@ -0,0 +1,150 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUASTDiffNullInputs(t *testing.T) {
+	fn := NewUASTDiff(
+		expression.NewGetField(0, sql.Blob, "", true),
+		expression.NewGetField(1, sql.Blob, "", true),
+		expression.NewGetField(2, sql.Text, "", true),
+	)
+
+	ctx := sql.NewEmptyContext()
+	for _, row := range []sql.Row{
+		sql.NewRow(nil, []byte{}, "Go"),
+		sql.NewRow([]byte{}, nil, "Go"),
+	} {
+		val, err := fn.Eval(ctx, row)
+		require.NoError(t, err)
+		require.Nil(t, val)
+	}
+
+	val, err := fn.Eval(ctx, sql.NewRow([]byte{}, []byte{}, "Go"))
+	require.NoError(t, err)
+	require.Equal(t, UASTDiffResult{
+		Language:       "go",
+		Classification: "none",
+		Added:          []NodeChange{},
+		Removed:        []NodeChange{},
+		Changed:        []NodeChange{},
+	}, val)
+}
+
+func TestDiffUASTs(t *testing.T) {
+	require := require.New(t)
+
+	node := func(typ string, fields nodes.Object) nodes.Object {
+		fields[uast.KeyType] = nodes.String(typ)
+		return fields
+	}
+
+	ident := func(name string) nodes.Object {
+		return node(identifierType, nodes.Object{"Name": nodes.String(name)})
+	}
+
+	comment := func(text string) nodes.Object {
+		return node(commentType, nodes.Object{"Text": nodes.String(text)})
+	}
+
+	withPos := func(n nodes.Object, line uint32) nodes.Object {
+		n[uast.KeyPos] = uast.Positions{
+			uast.KeyStart: {Line: line, Col: 1},
+		}.ToObject()
+		return n
+	}
+
+	function := func(line uint32, name string, args int, stmts ...nodes.Node) nodes.Object {
+		var arguments nodes.Array
+		for i := 0; i < args; i++ {
+			arguments = append(arguments, node(uast.TypeOf(uast.Argument{}), nodes.Object{}))
+		}
+
+		fn := node(functionType, nodes.Object{
+			"Type": node(uast.TypeOf(uast.FunctionType{}), nodes.Object{"Arguments": arguments}),
+			"Body": node(uast.TypeOf(uast.Block{}), nodes.Object{"Statements": nodes.Array(stmts)}),
+		})
+
+		return withPos(node(aliasType, nodes.Object{"Name": ident(name), "Node": fn}), line)
+	}
+
+	imp := func(line uint32, path string) nodes.Object {
+		return withPos(node(uast.TypeOf(uast.Import{}), nodes.Object{
+			"Path": node(stringType, nodes.Object{"Value": nodes.String(path)}),
+		}), line)
+	}
+
+	file := func(children ...nodes.Node) nodes.Object {
+		return node("File", nodes.Object{"Body": nodes.Array(children)})
+	}
+
+	before := file(
+		imp(1, "fmt"),
+		comment("foo does things"),
+		function(3, "foo", 1, ident("a")),
+		function(7, "bar", 0, ident("b")),
+		function(9, "baz", 2),
+	)
+
+	// positions and comments changed
+	same := file(
+		imp(2, "fmt"),
+		function(5, "foo", 1, comment("TODO"), ident("a")),
+		function(10, "bar", 0, ident("b")),
+		comment("baz"),
+		function(12, "baz", 2),
+	)
+
+	require.Equal(UASTDiffResult{
+		Language:       "go",
+		Classification: "none",
+		Added:          []NodeChange{},
+		Removed:        []NodeChange{},
+		Changed:        []NodeChange{},
+	}, diffUASTs("go", before, same))
+
+	refactor := file(
+		imp(1, "fmt"),
+		imp(2, "strings"),
+		function(4, "foo", 1, ident("c")),
+		function(8, "bar", 0, ident("b")),
+		function(10, "baz", 2),
+	)
+
+	require.Equal(UASTDiffResult{
+		Language:       "go",
+		Classification: "refactor",
+		Added:          []NodeChange{{Kind: "import", Name: "strings", Line: 2}},
+		Removed:        []NodeChange{},
+		Changed:        []NodeChange{{Kind: "function", Name: "foo", Line: 4, Change: "body"}},
+	}, diffUASTs("go", before, refactor))
+
+	api := file(
+		function(3, "foo", 2, ident("a")),
+		function(7, "bar", 0, ident("b")),
+		function(9, "qux", 2),
+	)
+
+	require.Equal(UASTDiffResult{
+		Language:       "go",
+		Classification: "api change",
+		Added:          []NodeChange{{Kind: "function", Name: "qux", Line: 9}},
+		Removed: []NodeChange{
+			{Kind: "import", Name: "fmt", Line: 1},
+			{Kind: "function", Name: "baz", Line: 9},
+		},
+		Changed: []NodeChange{{Kind: "function", Name: "foo", Line: 3, Change: "signature"}},
+	}, diffUASTs("go", before, api))
+
+	added := diffUASTs("go", nil, before)
+	require.Equal("api change", added.Classification)
+	require.Len(added.Added, 4)
+	require.Empty(added.Removed)
+}
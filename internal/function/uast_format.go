@@ -0,0 +1,369 @@
+package function
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/uastyaml"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+)
+
+// UASTToJSON returns UAST nodes as a JSON array.
+type UASTToJSON struct {
+	expression.UnaryExpression
+}
+
+// NewUASTToJSON creates a new UASTToJSON UDF.
+func NewUASTToJSON(uast sql.Expression) sql.Expression {
+	return &UASTToJSON{expression.UnaryExpression{Child: uast}}
+}
+
+// String implements the fmt.Stringer interface.
+func (f *UASTToJSON) String() string {
+	return fmt.Sprintf("uast_to_json(%s)", f.Child)
+}
+
+// Type implements the sql.Expression interface.
+func (*UASTToJSON) Type() sql.Type {
+	return sql.JSON
+}
+
+// WithChildren implements the Expression interface.
+func (f *UASTToJSON) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewUASTToJSON(children[0]), nil
+}
+
+// Eval implements the sql.Expression interface.
+func (f *UASTToJSON) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("uast_to_json: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.UASTToJSON")
+	defer span.Finish()
+
+	arr, err := evalNodes(ctx, f.Child, row)
+	if err != nil || arr == nil {
+		return nil, err
+	}
+
+	return arr.Native(), nil
+}
+
+// UASTToYAML returns UAST nodes as YAML, in the format used by bblfsh.
+type UASTToYAML struct {
+	expression.UnaryExpression
+}
+
+// NewUASTToYAML creates a new UASTToYAML UDF.
+func NewUASTToYAML(uast sql.Expression) sql.Expression {
+	return &UASTToYAML{expression.UnaryExpression{Child: uast}}
+}
+
+// String implements the fmt.Stringer interface.
+func (f *UASTToYAML) String() string {
+	return fmt.Sprintf("uast_to_yaml(%s)", f.Child)
+}
+
+// Type implements the sql.Expression interface.
+func (*UASTToYAML) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *UASTToYAML) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewUASTToYAML(children[0]), nil
+}
+
+// Eval implements the sql.Expression interface.
+func (f *UASTToYAML) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("uast_to_yaml: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.UASTToYAML")
+	defer span.Finish()
+
+	arr, err := evalNodes(ctx, f.Child, row)
+	if err != nil || arr == nil {
+		return nil, err
+	}
+
+	data, err := uastyaml.Marshal(arr)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// UASTToDot returns UAST nodes as a graph in the DOT language of Graphviz.
+type UASTToDot struct {
+	expression.UnaryExpression
+}
+
+// NewUASTToDot creates a new UASTToDot UDF.
+func NewUASTToDot(uast sql.Expression) sql.Expression {
+	return &UASTToDot{expression.UnaryExpression{Child: uast}}
+}
+
+// String implements the fmt.Stringer interface.
+func (f *UASTToDot) String() string {
+	return fmt.Sprintf("uast_to_dot(%s)", f.Child)
+}
+
+// Type implements the sql.Expression interface.
+func (*UASTToDot) Type() sql.Type {
+	return sql.Text
+}
+
+// WithChildren implements the Expression interface.
+func (f *UASTToDot) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 1)
+	}
+
+	return NewUASTToDot(children[0]), nil
+}
+
+// Eval implements the sql.Expression interface.
+func (f *UASTToDot) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("uast_to_dot: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.UASTToDot")
+	defer span.Finish()
+
+	arr, err := evalNodes(ctx, f.Child, row)
+	if err != nil || arr == nil {
+		return nil, err
+	}
+
+	return nodesToDot(arr), nil
+}
+
+// nodesToDot writes the given nodes as a directed graph. Every object is
+// a vertex labeled with its type, token, scalar properties and roles, and
+// the edges to its children are labeled with the property containing
+// them.
+func nodesToDot(arr nodes.Array) string {
+	var buf bytes.Buffer
+	buf.WriteString("digraph uast {\n")
+
+	var next int
+	var visit func(n nodes.Object) int
+	visit = func(n nodes.Object) int {
+		id := next
+		next++
+		fmt.Fprintf(&buf, "\tn%d [label=\"%s\"];\n", id, dotEscape(dotLabel(n)))
+
+		for _, k := range n.Keys() {
+			if isCommonProp(k) {
+				continue
+			}
+
+			var children nodes.Array
+			switch c := n[k].(type) {
+			case nodes.Object:
+				children = nodes.Array{c}
+			case nodes.Array:
+				children = c
+			}
+
+			for _, c := range children {
+				if obj, ok := c.(nodes.Object); ok {
+					child := visit(obj)
+					fmt.Fprintf(&buf, "\tn%d -> n%d [label=\"%s\"];\n", id, child, dotEscape(k))
+				}
+			}
+		}
+
+		return id
+	}
+
+	for _, n := range arr {
+		if obj, ok := n.(nodes.Object); ok {
+			visit(obj)
+		}
+	}
+
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+func dotLabel(n nodes.Object) string {
+	var lines []string
+	if typ := uast.TypeOf(n); typ != "" {
+		lines = append(lines, typ)
+	}
+
+	if token := uast.TokenOf(n); token != "" {
+		lines = append(lines, token)
+	}
+
+	for _, k := range n.Keys() {
+		if isCommonProp(k) {
+			continue
+		}
+
+		if v, ok := n[k].(nodes.Value); ok && v != nil {
+			lines = append(lines, fmt.Sprintf("%s: %v", k, v.Native()))
+		}
+	}
+
+	if roles := uast.RolesOf(n); len(roles) > 0 {
+		names := make([]string, len(roles))
+		for i, r := range roles {
+			names[i] = r.String()
+		}
+		lines = append(lines, strings.Join(names, ", "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+func dotEscape(s string) string {
+	return dotReplacer.Replace(s)
+}
+
+// UASTFind returns the UAST nodes at any depth having the given type or
+// role.
+type UASTFind struct {
+	expression.BinaryExpression
+}
+
+// NewUASTFind creates a new UASTFind UDF.
+func NewUASTFind(uast, roleOrType sql.Expression) sql.Expression {
+	return &UASTFind{expression.BinaryExpression{Left: uast, Right: roleOrType}}
+}
+
+// String implements the fmt.Stringer interface.
+func (f *UASTFind) String() string {
+	return fmt.Sprintf("uast_find(%s, %s)", f.Left, f.Right)
+}
+
+// Type implements the sql.Expression interface.
+func (*UASTFind) Type() sql.Type {
+	return sql.Blob
+}
+
+// WithChildren implements the Expression interface.
+func (f *UASTFind) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), 2)
+	}
+
+	return NewUASTFind(children[0], children[1]), nil
+}
+
+// Eval implements the sql.Expression interface.
+func (f *UASTFind) Eval(ctx *sql.Context, row sql.Row) (out interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("uast_find: unknown error: %s", r)
+		}
+	}()
+
+	span, ctx := ctx.Span("gitbase.UASTFind")
+	defer span.Finish()
+
+	arr, err := evalNodes(ctx, f.Left, row)
+	if err != nil || arr == nil {
+		return nil, err
+	}
+
+	right, err := f.Right.Eval(ctx, row)
+	if err != nil || right == nil {
+		return nil, err
+	}
+
+	right, err = sql.Text.Convert(right)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalNodes(findNodes(arr, right.(string)))
+}
+
+// findNodes returns the objects in the given nodes, at any depth, with the
+// given type or role. The type matches with or without the language
+// prefix, so "Identifier" matches "uast:Identifier", and roles are
+// matched ignoring the case. Positions are not taken into account.
+func findNodes(arr nodes.Array, roleOrType string) nodes.Array {
+	var found nodes.Array
+	var visit func(n nodes.Node)
+	visit = func(n nodes.Node) {
+		switch n := n.(type) {
+		case nodes.Array:
+			for _, c := range n {
+				visit(c)
+			}
+		case nodes.Object:
+			if hasTypeOrRole(n, roleOrType) {
+				found = append(found, n)
+			}
+
+			for _, k := range n.Keys() {
+				if !isCommonProp(k) {
+					visit(n[k])
+				}
+			}
+		}
+	}
+
+	visit(arr)
+	return found
+}
+
+func hasTypeOrRole(n nodes.Object, roleOrType string) bool {
+	typ := uast.TypeOf(n)
+	if typ != "" {
+		if typ == roleOrType {
+			return true
+		}
+
+		if i := strings.IndexByte(typ, ':'); i >= 0 && typ[i+1:] == roleOrType {
+			return true
+		}
+	}
+
+	for _, r := range uast.RolesOf(n) {
+		if strings.EqualFold(r.String(), roleOrType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// evalNodes evaluates the given expression as an array of UAST nodes, as
+// returned by the uast functions.
+func evalNodes(ctx *sql.Context, e sql.Expression, row sql.Row) (nodes.Array, error) {
+	v, err := e.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+
+	return getNodes(v)
+}
@@ -0,0 +1,150 @@
+package function
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bblfsh/sdk/v3/uast"
+	"github.com/bblfsh/sdk/v3/uast/nodes"
+	"github.com/bblfsh/sdk/v3/uast/role"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/expression"
+	"github.com/stretchr/testify/require"
+)
+
+func testUASTBlob(t *testing.T) []byte {
+	t.Helper()
+
+	name := nodes.Object{
+		uast.KeyType:  nodes.String("uast:Identifier"),
+		uast.KeyRoles: uast.RoleList(role.Identifier),
+		uast.KeyPos: uast.Positions{
+			uast.KeyStart: {Line: 1, Col: 5},
+		}.ToObject(),
+		"Name": nodes.String(`say "hi"`),
+	}
+
+	call := nodes.Object{
+		uast.KeyType:  nodes.String("python:Call"),
+		uast.KeyToken: nodes.String("print"),
+		uast.KeyRoles: uast.RoleList(role.Expression, role.Call),
+		"args":        nodes.Array{name},
+	}
+
+	blob, err := marshalNodes(nodes.Array{call})
+	require.NoError(t, err)
+	return blob.([]byte)
+}
+
+func TestUASTFormatNullInputs(t *testing.T) {
+	fns := []sql.Expression{
+		NewUASTToJSON(expression.NewGetField(0, sql.Blob, "", true)),
+		NewUASTToYAML(expression.NewGetField(0, sql.Blob, "", true)),
+		NewUASTToDot(expression.NewGetField(0, sql.Blob, "", true)),
+		NewUASTFind(
+			expression.NewGetField(0, sql.Blob, "", true),
+			expression.NewLiteral("Identifier", sql.Text),
+		),
+	}
+
+	ctx := sql.NewEmptyContext()
+	for _, fn := range fns {
+		for _, row := range []sql.Row{sql.NewRow(nil), sql.NewRow([]byte{})} {
+			val, err := fn.Eval(ctx, row)
+			require.NoError(t, err)
+			require.Nil(t, val, fn.String())
+		}
+	}
+}
+
+func TestUASTToJSON(t *testing.T) {
+	require := require.New(t)
+
+	fn := NewUASTToJSON(expression.NewGetField(0, sql.Blob, "", true))
+	val, err := fn.Eval(sql.NewEmptyContext(), sql.NewRow(testUASTBlob(t)))
+	require.NoError(err)
+
+	data, err := json.Marshal(val)
+	require.NoError(err)
+
+	var result []map[string]interface{}
+	require.NoError(json.Unmarshal(data, &result))
+	require.Len(result, 1)
+	require.Equal("python:Call", result[0]["@type"])
+	require.Equal("print", result[0]["@token"])
+
+	args := result[0]["args"].([]interface{})
+	require.Len(args, 1)
+	require.Equal(`say "hi"`, args[0].(map[string]interface{})["Name"])
+}
+
+func TestUASTToYAML(t *testing.T) {
+	require := require.New(t)
+
+	fn := NewUASTToYAML(expression.NewGetField(0, sql.Blob, "", true))
+	val, err := fn.Eval(sql.NewEmptyContext(), sql.NewRow(testUASTBlob(t)))
+	require.NoError(err)
+
+	yaml := val.(string)
+	require.Contains(yaml, "'@type': \"python:Call\"")
+	require.Contains(yaml, "'@token': \"print\"")
+	require.Contains(yaml, "uast:Identifier")
+}
+
+func TestUASTToDot(t *testing.T) {
+	fn := NewUASTToDot(expression.NewGetField(0, sql.Blob, "", true))
+	val, err := fn.Eval(sql.NewEmptyContext(), sql.NewRow(testUASTBlob(t)))
+	require.NoError(t, err)
+
+	expected := `digraph uast {
+	n0 [label="python:Call\nprint\nExpression, Call"];
+	n1 [label="uast:Identifier\nName: say \"hi\"\nIdentifier"];
+	n0 -> n1 [label="args"];
+}
+`
+	require.Equal(t, expected, val)
+}
+
+func TestUASTFind(t *testing.T) {
+	blob := testUASTBlob(t)
+
+	testCases := []struct {
+		roleOrType string
+		expected   []string
+	}{
+		{"python:Call", []string{"python:Call"}},
+		{"Identifier", []string{"uast:Identifier"}},
+		{"uast:Identifier", []string{"uast:Identifier"}},
+		{"call", []string{"python:Call"}},
+		{"Position", nil},
+		{"Function", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.roleOrType, func(t *testing.T) {
+			require := require.New(t)
+
+			fn := NewUASTFind(
+				expression.NewGetField(0, sql.Blob, "", true),
+				expression.NewGetField(1, sql.Text, "", true),
+			)
+
+			val, err := fn.Eval(sql.NewEmptyContext(), sql.NewRow(blob, tt.roleOrType))
+			require.NoError(err)
+
+			if tt.expected == nil {
+				require.Nil(val)
+				return
+			}
+
+			ns, err := getNodes(val)
+			require.NoError(err)
+
+			var types []string
+			for _, n := range ns {
+				types = append(types, uast.TypeOf(n))
+			}
+			require.Equal(tt.expected, types)
+		})
+	}
+}
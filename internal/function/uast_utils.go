@@ -122,6 +122,29 @@ func getUASTFromBblfsh(ctx *sql.Context,
 	return node, nil
 }
 
+// getCachedUAST returns the UAST of the given blob identified by key. The
+// blob is only sent to bblfsh if the node is not already in the UAST cache.
+// The returned boolean reports whether the node was found in the cache.
+func getCachedUAST(
+	ctx *sql.Context,
+	key string,
+	blob []byte,
+	lang string,
+	mode bblfsh.Mode,
+) (nodes.Node, bool, error) {
+	if value, ok := uastCache.Get(key); ok {
+		return value.(nodes.Node), true, nil
+	}
+
+	node, err := getUASTFromBblfsh(ctx, blob, lang, "", mode)
+	if err != nil {
+		return nil, false, err
+	}
+
+	uastCache.Add(key, node)
+	return node, false, nil
+}
+
 func applyXpath(n nodes.Node, query string) (nodes.Array, error) {
 	var filtered nodes.Array
 	it, err := tools.Filter(n, query)
@@ -0,0 +1,145 @@
+// Package minhash implements MinHash signatures, which estimate the Jaccard
+// similarity between two sets, and locality-sensitive hashing of those
+// signatures to find similar sets without comparing all of them.
+package minhash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+const (
+	// NumHashes is the number of hash functions, and thus of values, used
+	// in every signature.
+	NumHashes = 128
+	// Bands is the number of bands a signature is split into to compute
+	// its buckets.
+	Bands = 16
+	// Rows is the number of signature values in each band.
+	Rows = NumHashes / Bands
+
+	seed = 0x5eed
+)
+
+// ErrInvalidSignature is returned when a signature can't be decoded.
+var ErrInvalidSignature = errors.NewKind("invalid minhash signature of %d bytes")
+
+// coefficients of the hash functions h(x) = a*x + b, computed once from a
+// fixed seed so signatures are stable across processes.
+var coefA, coefB [NumHashes]uint64
+
+func init() {
+	state := uint64(seed)
+	for i := 0; i < NumHashes; i++ {
+		// a must be odd so the multiplication is a bijection modulo 2^64.
+		coefA[i] = splitmix64(&state) | 1
+		coefB[i] = splitmix64(&state)
+	}
+}
+
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Signature is the MinHash signature of a set.
+type Signature []uint32
+
+// New computes the signature of the given set of features. Duplicated
+// features don't change the result. It returns nil if there are no
+// features.
+func New(features []string) Signature {
+	if len(features) == 0 {
+		return nil
+	}
+
+	sig := make(Signature, NumHashes)
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+
+	h := fnv.New64a()
+	for _, f := range features {
+		h.Reset()
+		_, _ = h.Write([]byte(f))
+		x := h.Sum64()
+
+		for i := range sig {
+			v := uint32((coefA[i]*x + coefB[i]) >> 32)
+			if v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+
+	return sig
+}
+
+// Decode reads a signature encoded with Bytes.
+func Decode(data []byte) (Signature, error) {
+	if len(data) != NumHashes*4 {
+		return nil, ErrInvalidSignature.New(len(data))
+	}
+
+	sig := make(Signature, NumHashes)
+	for i := range sig {
+		sig[i] = binary.BigEndian.Uint32(data[i*4:])
+	}
+
+	return sig, nil
+}
+
+// Bytes returns the binary representation of the signature.
+func (s Signature) Bytes() []byte {
+	data := make([]byte, len(s)*4)
+	for i, v := range s {
+		binary.BigEndian.PutUint32(data[i*4:], v)
+	}
+	return data
+}
+
+// Similarity estimates the Jaccard similarity of the sets of both
+// signatures, that is, the fraction of values they have in common.
+func (s Signature) Similarity(other Signature) float64 {
+	if len(s) == 0 || len(s) != len(other) {
+		return 0
+	}
+
+	var equal int
+	for i := range s {
+		if s[i] == other[i] {
+			equal++
+		}
+	}
+
+	return float64(equal) / float64(len(s))
+}
+
+// Buckets returns the locality-sensitive hashing buckets of the signature,
+// one per band. Two signatures sharing at least one bucket are likely to
+// be similar. With the current number of bands and rows, sets with a
+// similarity of 0.7 share a bucket with a probability of roughly 0.5 and
+// sets with a similarity of 0.9 almost always do.
+func (s Signature) Buckets() []string {
+	if len(s) != NumHashes {
+		return nil
+	}
+
+	data := s.Bytes()
+	h := fnv.New64a()
+	buckets := make([]string, Bands)
+	for i := range buckets {
+		h.Reset()
+		_, _ = h.Write(data[i*Rows*4 : (i+1)*Rows*4])
+		buckets[i] = fmt.Sprintf("%02d-%016x", i, h.Sum64())
+	}
+
+	return buckets
+}
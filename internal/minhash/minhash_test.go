@@ -0,0 +1,83 @@
+package minhash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func features(from, to int) []string {
+	var result []string
+	for i := from; i < to; i++ {
+		result = append(result, fmt.Sprintf("feature-%d", i))
+	}
+	return result
+}
+
+func TestNew(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(New(nil))
+
+	a := New(features(0, 100))
+	require.Len(a, NumHashes)
+	require.Equal(a, New(append(features(0, 100), features(0, 50)...)))
+}
+
+func TestSimilarity(t *testing.T) {
+	require := require.New(t)
+
+	a := New(features(0, 100))
+	require.Equal(1.0, a.Similarity(a))
+
+	// real Jaccard similarity is 80/120
+	b := New(features(20, 120))
+	require.InDelta(0.67, a.Similarity(b), 0.15)
+
+	c := New(features(1000, 1100))
+	require.InDelta(0.0, a.Similarity(c), 0.05)
+
+	require.Equal(0.0, a.Similarity(nil))
+	require.Equal(0.0, a.Similarity(a[:10]))
+}
+
+func TestBytes(t *testing.T) {
+	require := require.New(t)
+
+	sig := New(features(0, 10))
+	decoded, err := Decode(sig.Bytes())
+	require.NoError(err)
+	require.Equal(sig, decoded)
+
+	_, err = Decode([]byte("foo"))
+	require.Error(err)
+	require.True(ErrInvalidSignature.Is(err))
+}
+
+func TestBuckets(t *testing.T) {
+	require := require.New(t)
+
+	a := New(features(0, 100))
+	require.Len(a.Buckets(), Bands)
+	require.Equal(a.Buckets(), New(features(0, 100)).Buckets())
+
+	similar := New(features(0, 99))
+	require.True(shareBucket(a.Buckets(), similar.Buckets()))
+
+	different := New(features(1000, 1100))
+	require.False(shareBucket(a.Buckets(), different.Buckets()))
+
+	require.Nil(Signature(nil).Buckets())
+}
+
+func shareBucket(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package minhash
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ShingleSize is the number of consecutive tokens in each of the features
+// returned by Shingles.
+const ShingleSize = 4
+
+// Shingles returns the set of groups of consecutive normalized tokens of
+// the given content, which are the features used to compute the signature
+// of a file.
+func Shingles(content string) []string {
+	tokens := normalizedTokens(content)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	if len(tokens) < ShingleSize {
+		return []string{strings.Join(tokens, " ")}
+	}
+
+	shingles := make([]string, 0, len(tokens)-ShingleSize+1)
+	for i := 0; i+ShingleSize <= len(tokens); i++ {
+		shingles = append(shingles, strings.Join(tokens[i:i+ShingleSize], " "))
+	}
+
+	return shingles
+}
+
+// normalizedTokens splits the content in words and punctuation, ignoring
+// whitespace. Words are lowercased and numbers are replaced by a
+// placeholder so that changes in formatting or constants don't affect
+// the similarity of two files.
+func normalizedTokens(content string) []string {
+	var tokens []string
+	var word strings.Builder
+
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+
+		w := word.String()
+		if unicode.IsDigit(rune(w[0])) {
+			w = "0"
+		}
+
+		tokens = append(tokens, strings.ToLower(w))
+		word.Reset()
+	}
+
+	for _, r := range content {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens = append(tokens, string(r))
+		}
+	}
+	flush()
+
+	return tokens
+}
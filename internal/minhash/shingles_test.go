@@ -0,0 +1,26 @@
+package minhash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShingles(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(Shingles(" \n\t"))
+	require.Equal([]string{"a = 0"}, Shingles("a = 1"))
+	require.Equal(
+		[]string{"a = 0 ;", "= 0 ; b"},
+		Shingles("A = 10;\nb"),
+	)
+}
+
+func TestNormalizedTokens(t *testing.T) {
+	require.Equal(
+		t,
+		[]string{"for", "i", ":", "=", "0", ";", "i", "<", "0", ";", "i", "+", "+", "{"},
+		normalizedTokens("for I := 0; i < 10; i++ {"),
+	)
+}
@@ -0,0 +1,53 @@
+package parquet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThriftWriter(t *testing.T) {
+	require := require.New(t)
+
+	w := newThriftWriter()
+	w.i32(1, 1)
+	w.i64(3, -2)
+	w.string(20, "ab")
+	w.bool(21, true)
+	w.beginStruct(22)
+	w.i8(1, 8)
+	w.bool(2, false)
+	w.endStruct()
+	w.list(23, thriftI32, 2)
+	w.i32Elem(0)
+	w.i32Elem(3)
+	w.list(24, thriftStruct, 15)
+	for i := 0; i < 15; i++ {
+		w.emptyStruct(0)
+	}
+
+	expected := []byte{
+		0x15, 0x02, // 1: i32 1
+		0x26, 0x03, // 3: i64 -2
+		0x08, 0x28, 0x02, 'a', 'b', // 20: long form header, string "ab"
+		0x11,             // 21: true
+		0x1c,             // 22: struct
+		0x13, 0x08, 0x12, // 1: i8 8, 2: false
+		0x00,                   // end of struct
+		0x19, 0x25, 0x00, 0x06, // 23: list of 2 i32
+		0x19, 0xfc, 0x0f, // 24: list of 15 structs
+	}
+	for i := 0; i < 15; i++ {
+		expected = append(expected, 0x00)
+	}
+	expected = append(expected, 0x00)
+
+	require.Equal(expected, w.bytes())
+}
+
+func TestThriftVarint(t *testing.T) {
+	w := newThriftWriter()
+	w.varint(300)
+	w.zigzag(-65)
+	require.Equal(t, []byte{0xac, 0x02, 0x81, 0x01}, w.buf)
+}
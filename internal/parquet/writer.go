@@ -0,0 +1,606 @@
+// Package parquet implements a minimal writer of Apache Parquet files, with
+// flat schemas of optional columns. Values are written uncompressed with
+// the plain encoding, in a single data page per column and row group.
+package parquet
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// Kind is the logical type of the values of a column.
+type Kind int
+
+// Supported kinds of columns.
+const (
+	Boolean Kind = iota
+	Int8
+	Int16
+	Int32
+	Int64
+	Uint8
+	Uint16
+	Uint32
+	Uint64
+	Float
+	Double
+	String
+	Bytes
+	JSON
+	Timestamp
+	Date
+)
+
+// Physical types of parquet.
+const (
+	typeBoolean   = 0
+	typeInt32     = 1
+	typeInt64     = 2
+	typeFloat     = 4
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Converted types of parquet, the deprecated way to set the logical
+// types, still written for the readers that don't know the new one.
+const (
+	convertedUTF8            = 0
+	convertedDate            = 6
+	convertedTimestampMicros = 10
+	convertedUint8           = 11
+	convertedUint16          = 12
+	convertedUint32          = 13
+	convertedUint64          = 14
+	convertedInt8            = 15
+	convertedInt16           = 16
+	convertedInt32           = 17
+	convertedInt64           = 18
+	convertedJSON            = 19
+)
+
+// Encodings of parquet.
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+const (
+	magic              = "PAR1"
+	createdBy          = "gitbase"
+	repetitionOptional = 1
+	pageTypeData       = 0
+	codecUncompressed  = 0
+)
+
+// DefaultRowGroupSize is the default size in bytes of the values buffered
+// before they are written as a row group.
+const DefaultRowGroupSize = 64 << 20
+
+var (
+	// ErrUnknownKind is returned when a column has an unknown kind.
+	ErrUnknownKind = errors.NewKind("unknown kind %d of column %s")
+	// ErrInvalidValue is returned when a value can't be written in a
+	// column of its kind.
+	ErrInvalidValue = errors.NewKind("invalid value %#v for column %s")
+	// ErrInvalidRow is returned when a row does not have a value for
+	// each column.
+	ErrInvalidRow = errors.NewKind("row has %d values, expecting %d")
+)
+
+// Column of a parquet file.
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+func (c Column) physicalType() int32 {
+	switch c.Kind {
+	case Boolean:
+		return typeBoolean
+	case Int8, Int16, Int32, Uint8, Uint16, Uint32, Date:
+		return typeInt32
+	case Int64, Uint64, Timestamp:
+		return typeInt64
+	case Float:
+		return typeFloat
+	case Double:
+		return typeDouble
+	default:
+		return typeByteArray
+	}
+}
+
+// writeSchema writes the schema element of the column.
+func (c Column) writeSchema(t *thriftWriter) {
+	t.beginStruct(0)
+	t.i32(1, c.physicalType())
+	t.i32(3, repetitionOptional)
+	t.string(4, c.Name)
+
+	integer := func(bits int8, signed bool) {
+		t.beginStruct(10)
+		t.beginStruct(10)
+		t.i8(1, bits)
+		t.bool(2, signed)
+		t.endStruct()
+		t.endStruct()
+	}
+
+	switch c.Kind {
+	case Int8:
+		t.i32(6, convertedInt8)
+		integer(8, true)
+	case Int16:
+		t.i32(6, convertedInt16)
+		integer(16, true)
+	case Int32:
+		t.i32(6, convertedInt32)
+		integer(32, true)
+	case Int64:
+		t.i32(6, convertedInt64)
+		integer(64, true)
+	case Uint8:
+		t.i32(6, convertedUint8)
+		integer(8, false)
+	case Uint16:
+		t.i32(6, convertedUint16)
+		integer(16, false)
+	case Uint32:
+		t.i32(6, convertedUint32)
+		integer(32, false)
+	case Uint64:
+		t.i32(6, convertedUint64)
+		integer(64, false)
+	case String:
+		t.i32(6, convertedUTF8)
+		t.beginStruct(10)
+		t.emptyStruct(1)
+		t.endStruct()
+	case JSON:
+		t.i32(6, convertedJSON)
+		t.beginStruct(10)
+		t.emptyStruct(12)
+		t.endStruct()
+	case Date:
+		t.i32(6, convertedDate)
+		t.beginStruct(10)
+		t.emptyStruct(6)
+		t.endStruct()
+	case Timestamp:
+		t.i32(6, convertedTimestampMicros)
+		t.beginStruct(10)
+		t.beginStruct(8)
+		t.bool(1, true)
+		t.beginStruct(2)
+		t.emptyStruct(2)
+		t.endStruct()
+		t.endStruct()
+		t.endStruct()
+	}
+
+	t.endStruct()
+}
+
+// columnChunk has the values of a column buffered for a row group.
+type columnChunk struct {
+	col Column
+	// levels are the definition levels of the values: 0 for nulls and 1
+	// for the rest.
+	levels []byte
+	// values has the plain encoded values, but booleans, which are stored
+	// as a byte each and packed when they are written.
+	values []byte
+}
+
+// chunkMeta is the metadata of a column chunk already written.
+type chunkMeta struct {
+	offset    int64
+	size      int64
+	numValues int64
+}
+
+type rowGroupMeta struct {
+	chunks  []chunkMeta
+	numRows int64
+}
+
+// Writer writes rows to a parquet file. Rows are buffered until they
+// reach the row group size, and the file is not valid until the writer
+// is closed.
+type Writer struct {
+	// RowGroupSize is the size in bytes of the values buffered before they
+	// are written as a row group.
+	RowGroupSize int
+
+	w      io.Writer
+	offset int64
+	err    error
+
+	chunks    []*columnChunk
+	size      int
+	rows      int64
+	rowGroups []rowGroupMeta
+}
+
+// NewWriter creates a writer of a parquet file with the given columns and
+// writes the header of the file.
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	chunks := make([]*columnChunk, len(columns))
+	for i, col := range columns {
+		if col.Kind < Boolean || col.Kind > Date {
+			return nil, ErrUnknownKind.New(col.Kind, col.Name)
+		}
+
+		chunks[i] = &columnChunk{col: col}
+	}
+
+	pw := &Writer{
+		RowGroupSize: DefaultRowGroupSize,
+		w:            w,
+		chunks:       chunks,
+	}
+
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// Write adds a row to the file. The values of the row are converted to the
+// kinds of their columns, and nil values are written as nulls.
+func (w *Writer) Write(row []interface{}) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if len(row) != len(w.chunks) {
+		return ErrInvalidRow.New(len(row), len(w.chunks))
+	}
+
+	encoded := make([][]byte, len(row))
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+
+		b, err := encodeValue(w.chunks[i].col.Kind, v)
+		if err != nil {
+			return ErrInvalidValue.New(v, w.chunks[i].col.Name)
+		}
+		encoded[i] = b
+	}
+
+	for i, b := range encoded {
+		c := w.chunks[i]
+		if b == nil {
+			c.levels = append(c.levels, 0)
+			continue
+		}
+
+		c.levels = append(c.levels, 1)
+		c.values = append(c.values, b...)
+		w.size += len(b)
+	}
+
+	w.rows++
+	if w.size >= w.RowGroupSize {
+		return w.flushRowGroup()
+	}
+
+	return nil
+}
+
+// Close writes the buffered rows and the footer of the file. It does not
+// close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.rows > 0 {
+		if err := w.flushRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	footer := w.footer()
+	size := make([]byte, 4)
+	binary.LittleEndian.PutUint32(size, uint32(len(footer)))
+
+	for _, b := range [][]byte{footer, size, []byte(magic)} {
+		if err := w.write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Writer) write(b []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+	return err
+}
+
+// flushRowGroup writes the buffered values as a row group.
+func (w *Writer) flushRowGroup() error {
+	rg := rowGroupMeta{numRows: w.rows}
+	for _, c := range w.chunks {
+		data := encodeLevels(c.levels)
+		if c.col.Kind == Boolean {
+			data = append(data, packBooleans(c.values)...)
+		} else {
+			data = append(data, c.values...)
+		}
+
+		t := newThriftWriter()
+		t.i32(1, pageTypeData)
+		t.i32(2, int32(len(data)))
+		t.i32(3, int32(len(data)))
+		t.beginStruct(5)
+		t.i32(1, int32(len(c.levels)))
+		t.i32(2, encodingPlain)
+		t.i32(3, encodingRLE)
+		t.i32(4, encodingRLE)
+		t.endStruct()
+		header := t.bytes()
+
+		meta := chunkMeta{
+			offset:    w.offset,
+			size:      int64(len(header) + len(data)),
+			numValues: int64(len(c.levels)),
+		}
+
+		if err := w.write(header); err != nil {
+			return err
+		}
+
+		if err := w.write(data); err != nil {
+			return err
+		}
+
+		rg.chunks = append(rg.chunks, meta)
+		c.levels = c.levels[:0]
+		c.values = c.values[:0]
+	}
+
+	w.rowGroups = append(w.rowGroups, rg)
+	w.rows = 0
+	w.size = 0
+	return nil
+}
+
+// footer returns the encoded metadata of the file.
+func (w *Writer) footer() []byte {
+	var numRows int64
+	for _, rg := range w.rowGroups {
+		numRows += rg.numRows
+	}
+
+	t := newThriftWriter()
+	t.i32(1, 1)
+
+	t.list(2, thriftStruct, len(w.chunks)+1)
+	t.beginStruct(0)
+	t.string(4, "schema")
+	t.i32(5, int32(len(w.chunks)))
+	t.endStruct()
+	for _, c := range w.chunks {
+		c.col.writeSchema(t)
+	}
+
+	t.i64(3, numRows)
+
+	t.list(4, thriftStruct, len(w.rowGroups))
+	for _, rg := range w.rowGroups {
+		var size int64
+		t.beginStruct(0)
+		t.list(1, thriftStruct, len(rg.chunks))
+		for i, chunk := range rg.chunks {
+			col := w.chunks[i].col
+			t.beginStruct(0)
+			t.i64(2, chunk.offset)
+			t.beginStruct(3)
+			t.i32(1, col.physicalType())
+			t.list(2, thriftI32, 2)
+			t.i32Elem(encodingPlain)
+			t.i32Elem(encodingRLE)
+			t.list(3, thriftBinary, 1)
+			t.stringElem(col.Name)
+			t.i32(4, codecUncompressed)
+			t.i64(5, chunk.numValues)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.endStruct()
+			t.endStruct()
+			size += chunk.size
+		}
+		t.i64(2, size)
+		t.i64(3, rg.numRows)
+		t.endStruct()
+	}
+
+	t.string(6, createdBy)
+	return t.bytes()
+}
+
+// encodeLevels encodes the definition levels with the RLE hybrid encoding,
+// using only RLE runs, prefixed by their length.
+func encodeLevels(levels []byte) []byte {
+	buf := make([]byte, 4, 16)
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		buf = appendUvarint(buf, uint64(j-i)<<1)
+		buf = append(buf, levels[i])
+		i = j
+	}
+
+	binary.LittleEndian.PutUint32(buf, uint32(len(buf)-4))
+	return buf
+}
+
+// packBooleans packs booleans stored as a byte each in bits, starting with
+// the least significant bit.
+func packBooleans(values []byte) []byte {
+	packed := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v != 0 {
+			packed[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return packed
+}
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+// encodeValue returns the plain encoding of a value of the given kind.
+func encodeValue(kind Kind, v interface{}) ([]byte, error) {
+	switch kind {
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("not a boolean")
+		}
+
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case Int8, Int16, Int32, Uint8, Uint16, Uint32:
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(buf, uint32(n))
+		return buf, nil
+	case Int64, Uint64:
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, uint64(n))
+		return buf, nil
+	case Float, Double:
+		var f float64
+		switch n := v.(type) {
+		case float32:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			i, err := toInt64(v)
+			if err != nil {
+				return nil, err
+			}
+			f = float64(i)
+		}
+
+		if kind == Float {
+			buf := make([]byte, 4)
+			binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(f)))
+			return buf, nil
+		}
+
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(f))
+		return buf, nil
+	case String, Bytes, JSON:
+		var b []byte
+		switch s := v.(type) {
+		case string:
+			b = []byte(s)
+		case []byte:
+			b = s
+		default:
+			if kind != JSON {
+				return nil, fmt.Errorf("not a string")
+			}
+
+			var err error
+			if b, err = json.Marshal(v); err != nil {
+				return nil, err
+			}
+		}
+
+		buf := make([]byte, 4, 4+len(b))
+		binary.LittleEndian.PutUint32(buf, uint32(len(b)))
+		return append(buf, b...), nil
+	case Timestamp, Date:
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("not a time")
+		}
+
+		if kind == Date {
+			days := t.Unix() / 86400
+			if t.Unix() < 0 && t.Unix()%86400 != 0 {
+				days--
+			}
+
+			buf := make([]byte, 4)
+			binary.LittleEndian.PutUint32(buf, uint32(int32(days)))
+			return buf, nil
+		}
+
+		buf := make([]byte, 8)
+		micros := t.Unix()*1e6 + int64(t.Nanosecond())/1e3
+		binary.LittleEndian.PutUint64(buf, uint64(micros))
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("unknown kind")
+	}
+}
+
+// toInt64 converts any integer to an int64. Unsigned integers are kept
+// with the same bits.
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint:
+		return int64(n), nil
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("not an integer")
+	}
+}
@@ -0,0 +1,124 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []Column{
+		{Name: "name", Kind: String},
+		{Name: "count", Kind: Int64},
+		{Name: "ok", Kind: Boolean},
+	})
+	require.NoError(err)
+	w.RowGroupSize = 20
+
+	require.NoError(w.Write([]interface{}{"foo", int64(1), true}))
+	require.NoError(w.Write([]interface{}{nil, uint32(2), false}))
+	require.NoError(w.Write([]interface{}{"barbaz", nil, true}))
+	require.NoError(w.Close())
+
+	require.Len(w.rowGroups, 2)
+	require.Equal(int64(2), w.rowGroups[0].numRows)
+	require.Equal(int64(1), w.rowGroups[1].numRows)
+
+	data := buf.Bytes()
+	require.Equal(magic, string(data[:4]))
+	require.Equal(magic, string(data[len(data)-4:]))
+
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	require.Equal(w.footer(), data[len(data)-8-footerSize:len(data)-8])
+
+	// the first page of the first row group starts right after the magic
+	first := w.rowGroups[0].chunks[0]
+	require.Equal(int64(4), first.offset)
+
+	var end int64
+	for _, rg := range w.rowGroups {
+		for _, chunk := range rg.chunks {
+			require.True(chunk.offset >= end)
+			end = chunk.offset + chunk.size
+		}
+	}
+	require.Equal(int64(len(data)-8-footerSize), end)
+}
+
+func TestWriterErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewWriter(new(bytes.Buffer), []Column{{Name: "foo", Kind: Kind(99)}})
+	require.True(ErrUnknownKind.Is(err))
+
+	w, err := NewWriter(new(bytes.Buffer), []Column{{Name: "foo", Kind: Int64}})
+	require.NoError(err)
+
+	err = w.Write([]interface{}{1, 2})
+	require.True(ErrInvalidRow.Is(err))
+
+	err = w.Write([]interface{}{"foo"})
+	require.True(ErrInvalidValue.Is(err))
+
+	// rows with invalid values are not written
+	require.NoError(w.Write([]interface{}{nil}))
+	require.Equal([]byte{0}, w.chunks[0].levels)
+}
+
+func TestEncodeLevels(t *testing.T) {
+	require.Equal(t,
+		[]byte{6, 0, 0, 0, 4, 1, 2, 0, 2, 1},
+		encodeLevels([]byte{1, 1, 0, 1}),
+	)
+	require.Equal(t, []byte{0, 0, 0, 0}, encodeLevels(nil))
+}
+
+func TestPackBooleans(t *testing.T) {
+	require.Equal(t,
+		[]byte{0x05, 0x01},
+		packBooleans([]byte{1, 0, 1, 0, 0, 0, 0, 0, 1}),
+	)
+}
+
+func TestEncodeValue(t *testing.T) {
+	ts := time.Date(1969, time.December, 31, 12, 0, 0, 1000, time.UTC)
+
+	testCases := []struct {
+		name     string
+		kind     Kind
+		value    interface{}
+		expected []byte
+	}{
+		{"bool", Boolean, true, []byte{1}},
+		{"int8", Int8, int8(-1), []byte{0xff, 0xff, 0xff, 0xff}},
+		{"uint32", Uint32, uint32(1 << 31), []byte{0, 0, 0, 0x80}},
+		{"int64", Int64, int64(258), []byte{2, 1, 0, 0, 0, 0, 0, 0}},
+		{"float", Float, float64(1), []byte{0, 0, 0x80, 0x3f}},
+		{"double", Double, 1, []byte{0, 0, 0, 0, 0, 0, 0xf0, 0x3f}},
+		{"string", String, "ab", []byte{2, 0, 0, 0, 'a', 'b'}},
+		{"bytes", Bytes, []byte{1}, []byte{1, 0, 0, 0, 1}},
+		{"json", JSON, []interface{}{1}, []byte{3, 0, 0, 0, '[', '1', ']'}},
+		{"date", Date, ts, []byte{0xff, 0xff, 0xff, 0xff}},
+		{"timestamp", Timestamp, ts, []byte{0x01, 0x50, 0x14, 0xf1, 0xf5, 0xff, 0xff, 0xff}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			b, err := encodeValue(tt.kind, tt.value)
+			require.NoError(err)
+			require.Equal(tt.expected, b)
+		})
+	}
+
+	_, err := encodeValue(String, 1)
+	require.Error(t, err)
+	_, err = encodeValue(Timestamp, "2019-01-01")
+	require.Error(t, err)
+}
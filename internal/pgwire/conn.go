@@ -0,0 +1,798 @@
+package pgwire
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"vitess.io/vitess/go/mysql"
+)
+
+// serverVersion is the PostgreSQL version reported to the clients, which
+// some of them use to decide the features they can use.
+const serverVersion = "9.6.0"
+
+// statement is a prepared statement created with a Parse message.
+type statement struct {
+	query string
+	oids  []int
+}
+
+// portal is a statement with its parameters bound, ready to be executed.
+type portal struct {
+	query string
+
+	// tag is set for the statements that are not run by the engine, or
+	// when the statement is completed.
+	tag    string
+	schema sql.Schema
+	iter   sql.RowIter
+	ctx    context.Context
+	finish func(error)
+	rows   int
+}
+
+// canceled reports whether the query of the portal was canceled by the
+// client.
+func (p *portal) canceled() bool {
+	return p.ctx != nil && p.ctx.Err() == context.Canceled
+}
+
+type conn struct {
+	srv    *Server
+	nc     net.Conn
+	id     uint32
+	secret int32
+
+	// nc is the plain connection, r and w use the TLS one once the client
+	// asks for it.
+	r      *bufio.Reader
+	w      *bufio.Writer
+	err    error
+	secure bool
+
+	session  sql.Session
+	txStatus byte
+
+	statements map[string]*statement
+	portals    map[string]*portal
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (c *conn) serve() error {
+	defer c.nc.Close()
+	defer c.closePortals()
+
+	c.r = bufio.NewReader(c.nc)
+	c.w = bufio.NewWriter(c.nc)
+
+	if ok, err := c.startup(); !ok || err != nil {
+		return err
+	}
+
+	c.txStatus = 'I'
+	c.statements = make(map[string]*statement)
+	c.portals = make(map[string]*portal)
+
+	// after an error in the extended query protocol, the messages are
+	// discarded until the next Sync.
+	var discard bool
+	for {
+		typ, body, err := c.read(true)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if discard && typ != msgSync && typ != msgTerminate {
+			continue
+		}
+
+		r := newReader(typ, body)
+		switch typ {
+		case msgQuery:
+			c.handleQuery(r)
+		case msgParse:
+			err = c.handleParse(r)
+		case msgBind:
+			err = c.handleBind(r)
+		case msgDescribe:
+			err = c.handleDescribe(r)
+		case msgExecute:
+			err = c.handleExecute(r)
+		case msgClose:
+			err = c.handleClose(r)
+		case msgSync:
+			discard = false
+			c.readyForQuery()
+		case msgFlush:
+			c.flush()
+		case msgTerminate:
+			return nil
+		default:
+			err = newError(codeProtocolViolation, "unsupported message type %q", typ)
+		}
+
+		if err != nil {
+			c.sendError(err)
+			discard = true
+		}
+
+		if c.err != nil {
+			return c.err
+		}
+	}
+}
+
+// startup handles the messages sent by the client before the session
+// starts. It returns false if the client was not authenticated or just
+// sent a cancel request.
+func (c *conn) startup() (bool, error) {
+	var params = make(map[string]string)
+	for {
+		_, body, err := c.read(false)
+		if err != nil {
+			return false, err
+		}
+
+		r := newReader(0, body)
+		code := r.int32()
+		switch code {
+		case sslRequestCode, gssEncRequestCode:
+			accept := code == sslRequestCode && !c.secure &&
+				c.srv.cfg.TLSConfig != nil
+			if err := c.answerEncryption(accept); err != nil {
+				return false, err
+			}
+
+			continue
+		case cancelRequestCode:
+			id, secret := r.int32(), r.int32()
+			if r.err == nil {
+				c.srv.cancel(uint32(id), secret)
+			}
+
+			return false, nil
+		case protocolVersion:
+			for {
+				key := r.string()
+				if key == "" || r.err != nil {
+					break
+				}
+
+				params[key] = r.string()
+			}
+		default:
+			c.sendError(newError(
+				codeFeatureNotSupported,
+				"unsupported frontend protocol %d.%d",
+				code>>16, code&0xffff,
+			))
+			c.flush()
+			return false, c.err
+		}
+
+		if r.err != nil {
+			return false, r.err
+		}
+
+		break
+	}
+
+	if c.srv.cfg.RequireSecureTransport && !c.secure {
+		c.sendError(newError(
+			codeInvalidAuthorization,
+			"insecure connections are not allowed, the client must use SSL",
+		))
+		c.flush()
+		return false, c.err
+	}
+
+	user := params["user"]
+	if user == "" {
+		c.sendError(newError(codeInvalidAuthorization, "no user name given"))
+		c.flush()
+		return false, c.err
+	}
+
+	ok, err := c.authenticate(user)
+	if !ok || err != nil {
+		return false, err
+	}
+
+	c.session = c.srv.builder(user, c.nc.RemoteAddr().String(), c.id)
+
+	c.send(newMessage(msgAuthentication).int32(authOK))
+	for _, p := range [][2]string{
+		{"server_version", serverVersion},
+		{"server_encoding", "UTF8"},
+		{"client_encoding", "UTF8"},
+		{"DateStyle", "ISO, MDY"},
+		{"TimeZone", "UTC"},
+		{"integer_datetimes", "on"},
+		{"standard_conforming_strings", "on"},
+		{"application_name", params["application_name"]},
+	} {
+		c.send(newMessage(msgParameterStatus).string(p[0]).string(p[1]))
+	}
+	c.send(newMessage(msgBackendKeyData).int32(int(c.id)).int32(int(c.secret)))
+	c.txStatus = 'I'
+	c.readyForQuery()
+
+	return c.err == nil, c.err
+}
+
+// answerEncryption answers a request of the client to encrypt the
+// connection. When it's accepted, the TLS handshake is done and the rest of
+// the messages are read and written through the TLS connection.
+func (c *conn) answerEncryption(accept bool) error {
+	answer := byte('N')
+	if accept {
+		answer = 'S'
+	}
+
+	if err := c.w.WriteByte(answer); err != nil {
+		return err
+	}
+
+	if err := c.w.Flush(); err != nil {
+		return err
+	}
+
+	if !accept {
+		return nil
+	}
+
+	tc := tls.Server(c.nc, c.srv.cfg.TLSConfig)
+	if err := tc.Handshake(); err != nil {
+		return err
+	}
+
+	c.r = bufio.NewReader(tc)
+	c.w = bufio.NewWriter(tc)
+	c.secure = true
+	return nil
+}
+
+// authenticate asks the client for the password of the user in clear
+// text and checks it. Users without password are not asked for it. The
+// password is only encrypted if the connection uses TLS.
+func (c *conn) authenticate(user string) (bool, error) {
+	authServer := c.srv.engine.Auth.Mysql()
+
+	// the check of the empty password is not audited, so it's only logged
+	// once, when the user is authenticated.
+	plain := authServer
+	if a, ok := authServer.(*auth.MysqlAudit); ok {
+		plain = a.AuthServer
+	}
+
+	if c.checkPassword(plain, user, "") {
+		return c.checkPassword(authServer, user, ""), nil
+	}
+
+	c.send(newMessage(msgAuthentication).int32(authCleartextPassword))
+	c.flush()
+	if c.err != nil {
+		return false, c.err
+	}
+
+	typ, body, err := c.read(true)
+	if err != nil {
+		return false, err
+	}
+
+	if typ != msgPassword {
+		c.sendError(newError(codeProtocolViolation, "expected password message, got %q", typ))
+		c.flush()
+		return false, c.err
+	}
+
+	r := newReader(typ, body)
+	password := r.string()
+	if r.err != nil || !c.checkPassword(authServer, user, password) {
+		c.sendError(newError(codeInvalidPassword, "password authentication failed for user %q", user))
+		c.flush()
+		return false, c.err
+	}
+
+	return true, nil
+}
+
+func (c *conn) checkPassword(authServer mysql.AuthServer, user, password string) bool {
+	salt, err := mysql.NewSalt()
+	if err != nil {
+		return false
+	}
+
+	scrambled := mysql.ScramblePassword(salt, []byte(password))
+	_, err = authServer.ValidateHash(salt, user, scrambled, c.nc.RemoteAddr())
+	return err == nil
+}
+
+// handleQuery runs the statements of a Query message, using the simple
+// query protocol.
+func (c *conn) handleQuery(r *reader) {
+	query := r.string()
+	if r.err != nil {
+		c.sendError(r.err)
+		c.readyForQuery()
+		return
+	}
+
+	stmts := splitStatements(query)
+	if len(stmts) == 0 {
+		c.send(newMessage(msgEmptyQueryResponse))
+	}
+
+	for _, stmt := range stmts {
+		p, err := c.open(stmt)
+		if err == nil {
+			if len(p.schema) > 0 {
+				c.sendRowDescription(p.schema)
+			}
+			_, err = c.execute(p, 0)
+		}
+
+		if err != nil {
+			c.sendError(err)
+			break
+		}
+	}
+
+	c.readyForQuery()
+}
+
+func (c *conn) handleParse(r *reader) error {
+	name, query := r.string(), r.string()
+	n := int(r.int16())
+	oids := make([]int, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		oids = append(oids, int(r.int32()))
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	if _, ok := c.statements[name]; ok && name != "" {
+		return newError(codeDuplicateStatement, "prepared statement %q already exists", name)
+	}
+
+	stmts := splitStatements(query)
+	if len(stmts) > 1 {
+		return ErrMultipleStatements.New()
+	}
+
+	if len(stmts) == 1 {
+		query = stmts[0]
+	}
+
+	for i := len(oids); i < countParams(query); i++ {
+		oids = append(oids, oidText)
+	}
+
+	for i, oid := range oids {
+		if oid == 0 {
+			oids[i] = oidText
+		}
+	}
+
+	c.statements[name] = &statement{query: query, oids: oids}
+	c.send(newMessage(msgParseComplete))
+	return nil
+}
+
+func (c *conn) handleBind(r *reader) error {
+	portalName, stmtName := r.string(), r.string()
+
+	n := int(r.int16())
+	for i := 0; i < n && r.err == nil; i++ {
+		if r.int16() != 0 {
+			return ErrBinaryFormat.New()
+		}
+	}
+
+	n = int(r.int16())
+	values := make([][]byte, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		values = append(values, r.bytes())
+	}
+
+	n = int(r.int16())
+	for i := 0; i < n && r.err == nil; i++ {
+		if r.int16() != 0 {
+			return ErrBinaryFormat.New()
+		}
+	}
+
+	if r.err != nil {
+		return r.err
+	}
+
+	stmt, ok := c.statements[stmtName]
+	if !ok {
+		return newError(codeUndefinedStatement, "prepared statement %q does not exist", stmtName)
+	}
+
+	query, err := bindParams(stmt.query, values, stmt.oids)
+	if err != nil {
+		return err
+	}
+
+	c.closePortal(portalName)
+	c.portals[portalName] = &portal{query: query}
+	c.send(newMessage(msgBindComplete))
+	return nil
+}
+
+func (c *conn) handleDescribe(r *reader) error {
+	kind, name := r.byte(), r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	switch kind {
+	case 'S':
+		stmt, ok := c.statements[name]
+		if !ok {
+			return newError(codeUndefinedStatement, "prepared statement %q does not exist", name)
+		}
+
+		schema, err := c.describe(stmt)
+		if err != nil {
+			return err
+		}
+
+		m := newMessage(msgParameterDescription).int16(len(stmt.oids))
+		for _, oid := range stmt.oids {
+			m.int32(oid)
+		}
+		c.send(m)
+
+		if len(schema) > 0 {
+			c.sendRowDescription(schema)
+		} else {
+			c.send(newMessage(msgNoData))
+		}
+	case 'P':
+		p, err := c.portal(name)
+		if err != nil {
+			return err
+		}
+
+		if len(p.schema) > 0 {
+			c.sendRowDescription(p.schema)
+		} else {
+			c.send(newMessage(msgNoData))
+		}
+	default:
+		return ErrMalformedMessage.New(string(msgDescribe))
+	}
+
+	return nil
+}
+
+// describe returns the schema of the rows of a prepared statement. The
+// statement is analyzed with all its parameters as NULL, and only if it's
+// one of the statements that return rows, as analyzing the rest could
+// execute them.
+func (c *conn) describe(stmt *statement) (sql.Schema, error) {
+	if !returnsRows(stmt.query) {
+		return nil, nil
+	}
+
+	query, err := bindParams(stmt.query, make([][]byte, len(stmt.oids)), stmt.oids)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.open(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p.close(nil)
+	return p.schema, nil
+}
+
+func (c *conn) handleExecute(r *reader) error {
+	name, maxRows := r.string(), int(r.int32())
+	if r.err != nil {
+		return r.err
+	}
+
+	p, err := c.portal(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.execute(p, maxRows)
+	return err
+}
+
+func (c *conn) handleClose(r *reader) error {
+	kind, name := r.byte(), r.string()
+	if r.err != nil {
+		return r.err
+	}
+
+	switch kind {
+	case 'S':
+		delete(c.statements, name)
+	case 'P':
+		c.closePortal(name)
+	default:
+		return ErrMalformedMessage.New(string(msgClose))
+	}
+
+	c.send(newMessage(msgCloseComplete))
+	return nil
+}
+
+// portal returns the portal with the given name, running its query if it
+// was not run yet.
+func (c *conn) portal(name string) (*portal, error) {
+	p, ok := c.portals[name]
+	if !ok {
+		return nil, newError(codeUndefinedCursor, "portal %q does not exist", name)
+	}
+
+	if p.tag != "" || p.iter != nil || p.finish != nil {
+		return p, nil
+	}
+
+	opened, err := c.open(p.query)
+	if err != nil {
+		delete(c.portals, name)
+		return nil, err
+	}
+
+	c.portals[name] = opened
+	return opened, nil
+}
+
+// open runs a query with the engine and returns a portal to read its rows.
+// Transaction control statements are not run, and errors of SET
+// statements are sent as notices, as clients usually set some PostgreSQL
+// variables that gitbase does not have.
+func (c *conn) open(query string) (*portal, error) {
+	p := &portal{query: query}
+	if p.tag = transactionTag(query); p.tag != "" {
+		switch p.tag {
+		case "BEGIN":
+			c.txStatus = 'T'
+		default:
+			c.txStatus = 'I'
+		}
+		return p, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	sctx := sql.NewContext(ctx,
+		sql.WithSession(c.session),
+		sql.WithPid(pidOffset+atomic.AddUint64(&c.srv.pid, 1)),
+		sql.WithQuery(query),
+	)
+
+	start := time.Now()
+	p.ctx = ctx
+	p.finish = func(err error) {
+		cancel()
+		if a, ok := c.srv.engine.Auth.(*auth.Audit); ok {
+			a.Query(sctx, time.Since(start), err)
+		}
+	}
+
+	schema, iter, err := c.srv.engine.Query(sctx, query)
+	if err != nil {
+		canceled := p.canceled()
+		p.finish(err)
+		if canceled {
+			return nil, errCanceled
+		}
+
+		if isSet(query) {
+			c.send(newMessage(msgNoticeResponse).
+				byte('S').string("WARNING").
+				byte('C').string(errorCode(err)).
+				byte('M').string(err.Error()).
+				byte(0))
+			p.tag = "SET"
+			return p, nil
+		}
+
+		return nil, err
+	}
+
+	p.schema, p.iter = schema, iter
+	return p, nil
+}
+
+// execute sends the rows of a portal, up to maxRows if it's greater than
+// zero. It returns whether the portal was suspended before reading all
+// the rows.
+func (c *conn) execute(p *portal, maxRows int) (bool, error) {
+	if p.iter == nil {
+		if p.tag == "" {
+			c.send(newMessage(msgEmptyQueryResponse))
+		} else {
+			c.send(newMessage(msgCommandComplete).string(p.tag))
+		}
+		return false, nil
+	}
+
+	for n := 0; maxRows <= 0 || n < maxRows; n++ {
+		row, err := p.iter.Next()
+		if err == io.EOF {
+			p.close(nil)
+			p.tag = commandTag(p.query, p.schema, p.rows)
+			c.send(newMessage(msgCommandComplete).string(p.tag))
+			return false, nil
+		}
+
+		if err == nil {
+			err = c.sendDataRow(p.schema, row)
+		}
+
+		if err != nil {
+			canceled := p.canceled()
+			p.close(err)
+			if canceled {
+				return false, errCanceled
+			}
+			return false, err
+		}
+
+		p.rows++
+		if c.err != nil {
+			p.close(c.err)
+			return false, nil
+		}
+	}
+
+	c.send(newMessage(msgPortalSuspended))
+	return true, nil
+}
+
+// close closes the iterator of the portal, if any.
+func (p *portal) close(err error) {
+	if p.iter != nil {
+		if cerr := p.iter.Close(); err == nil {
+			err = cerr
+		}
+		p.iter = nil
+	}
+
+	if p.finish != nil {
+		p.finish(err)
+		p.finish = nil
+	}
+}
+
+func (c *conn) closePortal(name string) {
+	if p, ok := c.portals[name]; ok {
+		p.close(nil)
+		delete(c.portals, name)
+	}
+}
+
+func (c *conn) closePortals() {
+	for name := range c.portals {
+		c.closePortal(name)
+	}
+}
+
+// cancelQuery cancels the running query of the connection, if any.
+func (c *conn) cancelQuery() {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (c *conn) sendRowDescription(schema sql.Schema) {
+	m := newMessage(msgRowDescription).int16(len(schema))
+	for _, col := range schema {
+		t := typeOf(col.Type)
+		m.string(col.Name).
+			int32(0).      // table oid
+			int16(0).      // column number
+			int32(t.oid).  // type oid
+			int16(t.size). // type size
+			int32(-1).     // type modifier
+			int16(0)       // text format
+	}
+	c.send(m)
+}
+
+func (c *conn) sendDataRow(schema sql.Schema, row sql.Row) error {
+	m := newMessage(msgDataRow).int16(len(row))
+	for i, v := range row {
+		var t sql.Type = sql.Text
+		if i < len(schema) {
+			t = schema[i].Type
+		}
+
+		val, err := encodeText(t, v)
+		if err != nil {
+			return err
+		}
+
+		m.bytes(val)
+	}
+
+	c.send(m)
+	return nil
+}
+
+func (c *conn) sendError(err error) {
+	c.send(newMessage(msgErrorResponse).
+		byte('S').string("ERROR").
+		byte('V').string("ERROR").
+		byte('C').string(errorCode(err)).
+		byte('M').string(err.Error()).
+		byte(0))
+}
+
+func (c *conn) readyForQuery() {
+	c.send(newMessage(msgReadyForQuery).byte(c.txStatus))
+	c.flush()
+}
+
+func (c *conn) read(typed bool) (byte, []byte, error) {
+	if c.srv.cfg.ConnReadTimeout > 0 {
+		deadline := time.Now().Add(c.srv.cfg.ConnReadTimeout)
+		if err := c.nc.SetReadDeadline(deadline); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return readMessage(c.r, typed)
+}
+
+// send writes a message to the client. Once a write fails, the rest of
+// the messages are ignored and the connection is closed.
+func (c *conn) send(m *message) {
+	if c.err != nil {
+		return
+	}
+
+	c.setWriteDeadline()
+	c.err = m.writeTo(c.w)
+}
+
+func (c *conn) flush() {
+	if c.err != nil {
+		return
+	}
+
+	c.setWriteDeadline()
+	c.err = c.w.Flush()
+}
+
+func (c *conn) setWriteDeadline() {
+	if c.srv.cfg.ConnWriteTimeout > 0 {
+		deadline := time.Now().Add(c.srv.cfg.ConnWriteTimeout)
+		if err := c.nc.SetWriteDeadline(deadline); err != nil {
+			c.err = err
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package pgwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// Codes of the messages sent by the frontend before the startup, instead
+// of a protocol version.
+const (
+	protocolVersion   = 196608 // 3.0
+	sslRequestCode    = 80877103
+	gssEncRequestCode = 80877104
+	cancelRequestCode = 80877102
+)
+
+// Types of the messages sent by the frontend.
+const (
+	msgBind      = 'B'
+	msgClose     = 'C'
+	msgDescribe  = 'D'
+	msgExecute   = 'E'
+	msgFlush     = 'H'
+	msgParse     = 'P'
+	msgPassword  = 'p'
+	msgQuery     = 'Q'
+	msgSync      = 'S'
+	msgTerminate = 'X'
+)
+
+// Types of the messages sent by the backend.
+const (
+	msgAuthentication       = 'R'
+	msgBackendKeyData       = 'K'
+	msgBindComplete         = '2'
+	msgCloseComplete        = '3'
+	msgCommandComplete      = 'C'
+	msgDataRow              = 'D'
+	msgEmptyQueryResponse   = 'I'
+	msgErrorResponse        = 'E'
+	msgNoData               = 'n'
+	msgNoticeResponse       = 'N'
+	msgParameterDescription = 't'
+	msgParameterStatus      = 'S'
+	msgParseComplete        = '1'
+	msgPortalSuspended      = 's'
+	msgReadyForQuery        = 'Z'
+	msgRowDescription       = 'T'
+)
+
+// Authentication request codes.
+const (
+	authOK                = 0
+	authCleartextPassword = 3
+)
+
+// maxMessageSize is the maximum size of a message sent by the frontend.
+const maxMessageSize = 64 << 20
+
+var (
+	// ErrMessageTooLarge is returned when the frontend sends a message
+	// larger than the maximum size.
+	ErrMessageTooLarge = errors.NewKind("message of %d bytes is too large")
+	// ErrMalformedMessage is returned when the body of a message sent by
+	// the frontend does not have the expected fields.
+	ErrMalformedMessage = errors.NewKind("malformed message %q")
+)
+
+// readMessage reads a message with the given header size, which is 5 bytes
+// for regular messages (type and length) and 4 for the startup messages,
+// which have no type.
+func readMessage(r io.Reader, typed bool) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if !typed {
+		header = header[1:]
+	}
+
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	var typ byte
+	if typed {
+		typ, header = header[0], header[1:]
+	}
+
+	size := int(binary.BigEndian.Uint32(header)) - 4
+	if size < 0 || size > maxMessageSize {
+		return 0, nil, ErrMessageTooLarge.New(size)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return typ, body, nil
+}
+
+// reader reads the fields of the body of a message.
+type reader struct {
+	typ byte
+	buf []byte
+	err error
+}
+
+func newReader(typ byte, body []byte) *reader {
+	return &reader{typ: typ, buf: body}
+}
+
+func (r *reader) fail() {
+	if r.err == nil {
+		r.err = ErrMalformedMessage.New(string(r.typ))
+	}
+	r.buf = nil
+}
+
+func (r *reader) int16() int16 {
+	if len(r.buf) < 2 {
+		r.fail()
+		return 0
+	}
+
+	v := int16(binary.BigEndian.Uint16(r.buf))
+	r.buf = r.buf[2:]
+	return v
+}
+
+func (r *reader) int32() int32 {
+	if len(r.buf) < 4 {
+		r.fail()
+		return 0
+	}
+
+	v := int32(binary.BigEndian.Uint32(r.buf))
+	r.buf = r.buf[4:]
+	return v
+}
+
+func (r *reader) byte() byte {
+	if len(r.buf) < 1 {
+		r.fail()
+		return 0
+	}
+
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+	return v
+}
+
+// string reads a null terminated string.
+func (r *reader) string() string {
+	i := bytes.IndexByte(r.buf, 0)
+	if i < 0 {
+		r.fail()
+		return ""
+	}
+
+	s := string(r.buf[:i])
+	r.buf = r.buf[i+1:]
+	return s
+}
+
+// bytes reads a value prefixed by its length, where a length of -1 means
+// a NULL value, returned as nil.
+func (r *reader) bytes() []byte {
+	n := r.int32()
+	if n < 0 || r.err != nil {
+		return nil
+	}
+
+	if len(r.buf) < int(n) {
+		r.fail()
+		return nil
+	}
+
+	v := r.buf[:n:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+// message is a message sent by the backend.
+type message struct {
+	typ byte
+	buf []byte
+}
+
+func newMessage(typ byte) *message {
+	return &message{typ: typ}
+}
+
+func (m *message) int16(v int) *message {
+	m.buf = append(m.buf, byte(v>>8), byte(v))
+	return m
+}
+
+func (m *message) int32(v int) *message {
+	m.buf = append(m.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	return m
+}
+
+func (m *message) byte(v byte) *message {
+	m.buf = append(m.buf, v)
+	return m
+}
+
+// string writes a null terminated string.
+func (m *message) string(s string) *message {
+	m.buf = append(m.buf, s...)
+	m.buf = append(m.buf, 0)
+	return m
+}
+
+// bytes writes a value prefixed by its length, using -1 for NULL values.
+func (m *message) bytes(v []byte) *message {
+	if v == nil {
+		return m.int32(-1)
+	}
+
+	m.int32(len(v))
+	m.buf = append(m.buf, v...)
+	return m
+}
+
+// writeTo writes the message with its type and length.
+func (m *message) writeTo(w io.Writer) error {
+	header := []byte{m.typ, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], uint32(len(m.buf)+4))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(m.buf)
+	return err
+}
@@ -0,0 +1,301 @@
+package pgwire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/analyzer"
+	"github.com/src-d/go-mysql-server/sql/parse"
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// SQLSTATE codes of the errors sent to the clients.
+const (
+	codeInternalError         = "XX000"
+	codeProtocolViolation     = "08P01"
+	codeFeatureNotSupported   = "0A000"
+	codeInvalidAuthorization  = "28000"
+	codeInvalidPassword       = "28P01"
+	codeSyntaxError           = "42601"
+	codeInsufficientPrivilege = "42501"
+	codeUndefinedTable        = "42P01"
+	codeUndefinedColumn       = "42703"
+	codeUndefinedFunction     = "42883"
+	codeDuplicateStatement    = "42P05"
+	codeUndefinedStatement    = "26000"
+	codeUndefinedCursor       = "34000"
+	codeUndefinedParameter    = "42P02"
+	codeQueryCanceled         = "57014"
+)
+
+var (
+	// ErrMultipleStatements is returned when a prepared statement has more
+	// than one statement.
+	ErrMultipleStatements = errors.NewKind("cannot insert multiple commands into a prepared statement")
+	// ErrParameterNotFound is returned when a query refers to a parameter
+	// that was not bound.
+	ErrParameterNotFound = errors.NewKind("there is no parameter $%d")
+	// ErrBinaryFormat is returned when a client asks for parameters or
+	// results in binary format.
+	ErrBinaryFormat = errors.NewKind("binary format is not supported")
+)
+
+// errCanceled is returned when a query is canceled by the client.
+var errCanceled = newError(codeQueryCanceled, "canceling statement due to user request")
+
+// pgError is an error with the SQLSTATE code sent to the client.
+type pgError struct {
+	code    string
+	message string
+}
+
+func (e *pgError) Error() string {
+	return e.message
+}
+
+func newError(code, format string, args ...interface{}) *pgError {
+	return &pgError{code, fmt.Sprintf(format, args...)}
+}
+
+// errorCode returns the SQLSTATE code of an error returned by the engine.
+func errorCode(err error) string {
+	switch e := err.(type) {
+	case *pgError:
+		return e.code
+	}
+
+	switch {
+	case auth.ErrNotAuthorized.Is(err):
+		return codeInsufficientPrivilege
+	case sql.ErrTableNotFound.Is(err):
+		return codeUndefinedTable
+	case analyzer.ErrColumnNotFound.Is(err):
+		return codeUndefinedColumn
+	case sql.ErrFunctionNotFound.Is(err):
+		return codeUndefinedFunction
+	case ErrParameterNotFound.Is(err):
+		return codeUndefinedParameter
+	case ErrMultipleStatements.Is(err):
+		return codeSyntaxError
+	case ErrBinaryFormat.Is(err),
+		parse.ErrUnsupportedSyntax.Is(err),
+		parse.ErrUnsupportedFeature.Is(err):
+		return codeFeatureNotSupported
+	case strings.Contains(err.Error(), "syntax error"):
+		return codeSyntaxError
+	default:
+		return codeInternalError
+	}
+}
+
+// splitStatements splits a query with several statements separated by
+// semicolons. Semicolons inside quoted strings and identifiers are
+// ignored. Empty statements are removed.
+func splitStatements(query string) []string {
+	var stmts []string
+	var quote byte
+	var start int
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0 && c == '\\' && quote != '`':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			stmts = appendStatement(stmts, query[start:i])
+			start = i + 1
+		}
+	}
+
+	return appendStatement(stmts, query[start:])
+}
+
+func appendStatement(stmts []string, stmt string) []string {
+	if stmt = strings.TrimSpace(stmt); stmt != "" {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+// scanParams calls fn with the position, length and number of each $n
+// parameter of the query outside quoted strings and identifiers.
+func scanParams(query string, fn func(pos, length, n int)) {
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0 && c == '\\' && quote != '`':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+
+			if j > i+1 {
+				n, err := strconv.Atoi(query[i+1 : j])
+				if err == nil {
+					fn(i, j-i, n)
+				}
+				i = j - 1
+			}
+		}
+	}
+}
+
+// countParams returns the highest number of the parameters of the query.
+func countParams(query string) int {
+	var max int
+	scanParams(query, func(_, _, n int) {
+		if n > max {
+			max = n
+		}
+	})
+	return max
+}
+
+// bindParams replaces the $n parameters of the query with the given
+// values as SQL literals. Values of numeric types are written as they
+// are, the rest are quoted, and nil values are written as NULL.
+func bindParams(query string, values [][]byte, oids []int) (string, error) {
+	var buf strings.Builder
+	var last int
+	var err error
+
+	scanParams(query, func(pos, length, n int) {
+		if err != nil {
+			return
+		}
+
+		if n < 1 || n > len(values) {
+			err = ErrParameterNotFound.New(n)
+			return
+		}
+
+		buf.WriteString(query[last:pos])
+		last = pos + length
+
+		var oid int
+		if n <= len(oids) {
+			oid = oids[n-1]
+		}
+
+		v := values[n-1]
+		switch {
+		case v == nil:
+			buf.WriteString("NULL")
+		case isNumeric(oid) && isNumber(string(v)):
+			buf.Write(v)
+		default:
+			buf.WriteString(quote(string(v)))
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	buf.WriteString(query[last:])
+	return buf.String(), nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+var quoteReplacer = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quote returns the given string as a quoted SQL string.
+func quote(s string) string {
+	return "'" + quoteReplacer.Replace(s) + "'"
+}
+
+// keywords returns the first n words of the query in upper case.
+func keywords(query string, n int) []string {
+	fields := strings.Fields(strings.ToUpper(query))
+	if len(fields) > n {
+		fields = fields[:n]
+	}
+	return fields
+}
+
+// transactionTag returns the command tag of the transaction control
+// statements, which are accepted but ignored, as gitbase has no
+// transactions. It returns an empty string for any other statement.
+func transactionTag(query string) string {
+	kw := keywords(strings.TrimSuffix(query, ";"), 2)
+	if len(kw) == 0 {
+		return ""
+	}
+
+	switch kw[0] {
+	case "BEGIN":
+		return "BEGIN"
+	case "START":
+		if len(kw) > 1 && kw[1] == "TRANSACTION" {
+			return "BEGIN"
+		}
+	case "COMMIT", "END":
+		return "COMMIT"
+	case "ROLLBACK", "ABORT":
+		return "ROLLBACK"
+	}
+
+	return ""
+}
+
+// isSet reports whether the query is a SET statement.
+func isSet(query string) bool {
+	kw := keywords(query, 1)
+	return len(kw) == 1 && kw[0] == "SET"
+}
+
+// returnsRows reports whether the query is one of the statements that
+// return rows, which are the only ones that can be described before
+// they are executed.
+func returnsRows(query string) bool {
+	kw := keywords(strings.TrimLeft(query, "("), 1)
+	if len(kw) == 0 {
+		return false
+	}
+
+	switch kw[0] {
+	case "SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN":
+		return true
+	default:
+		return false
+	}
+}
+
+// commandTag returns the tag sent to the client when a statement is
+// completed.
+func commandTag(query string, schema sql.Schema, rows int) string {
+	if len(schema) > 0 {
+		return fmt.Sprintf("SELECT %d", rows)
+	}
+
+	kw := keywords(query, 2)
+	switch {
+	case len(kw) == 0:
+		return ""
+	case len(kw) == 2 && (kw[0] == "CREATE" || kw[0] == "DROP"):
+		return kw[0] + " " + kw[1]
+	default:
+		return kw[0]
+	}
+}
@@ -0,0 +1,119 @@
+package pgwire
+
+import (
+	"testing"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{" ; ;", nil},
+		{`SELECT ';', "a;b", ` + "`c;d`" + `; SELECT 2`, []string{`SELECT ';', "a;b", ` + "`c;d`", "SELECT 2"}},
+		{`SELECT 'it\'s;'; SELECT 2`, []string{`SELECT 'it\'s;'`, "SELECT 2"}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require.Equal(t, tt.expected, splitStatements(tt.query))
+		})
+	}
+}
+
+func TestBindParams(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		values   [][]byte
+		oids     []int
+		expected string
+		err      bool
+	}{
+		{
+			"text",
+			"SELECT * FROM refs WHERE ref_name = $1",
+			[][]byte{[]byte(`it's \o/`)},
+			[]int{oidText},
+			`SELECT * FROM refs WHERE ref_name = 'it\'s \\o/'`,
+			false,
+		},
+		{
+			"numbers and nulls",
+			"SELECT $2, $1, $10",
+			[][]byte{[]byte("1"), nil, nil, nil, nil, nil, nil, nil, nil, []byte("1.5")},
+			[]int{oidInt8, oidInt8, 0, 0, 0, 0, 0, 0, 0, oidFloat8},
+			"SELECT NULL, 1, 1.5",
+			false,
+		},
+		{
+			"invalid number",
+			"SELECT $1",
+			[][]byte{[]byte("1; DROP")},
+			[]int{oidInt4},
+			"SELECT '1; DROP'",
+			false,
+		},
+		{
+			"quoted",
+			"SELECT '$1', `$1`, $1",
+			[][]byte{[]byte("a")},
+			nil,
+			"SELECT '$1', `$1`, 'a'",
+			false,
+		},
+		{
+			"missing",
+			"SELECT $2",
+			[][]byte{[]byte("a")},
+			nil,
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			query, err := bindParams(tt.query, tt.values, tt.oids)
+			if tt.err {
+				require.Error(err)
+				require.True(ErrParameterNotFound.Is(err))
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tt.expected, query)
+		})
+	}
+}
+
+func TestCountParams(t *testing.T) {
+	require.Equal(t, 0, countParams("SELECT '$1'"))
+	require.Equal(t, 3, countParams("SELECT $3, $1"))
+}
+
+func TestCommandTags(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("BEGIN", transactionTag("begin"))
+	require.Equal("BEGIN", transactionTag("START TRANSACTION"))
+	require.Equal("COMMIT", transactionTag("end;"))
+	require.Equal("ROLLBACK", transactionTag("ROLLBACK"))
+	require.Equal("", transactionTag("SELECT 1"))
+
+	schema := sql.Schema{{Name: "a", Type: sql.Int64}}
+	require.Equal("SELECT 2", commandTag("SHOW TABLES", schema, 2))
+	require.Equal("CREATE INDEX", commandTag("create index foo ON refs USING pilosa (ref_name)", nil, 0))
+	require.Equal("SET", commandTag("SET foo = 1", nil, 0))
+
+	require.True(returnsRows("(SELECT 1)"))
+	require.True(returnsRows("describe refs"))
+	require.False(returnsRows("CREATE INDEX foo ON refs USING pilosa (ref_name)"))
+	require.True(isSet("set foo = 1"))
+}
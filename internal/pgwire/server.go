@@ -0,0 +1,167 @@
+package pgwire
+
+import (
+	"crypto/tls"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/sql"
+)
+
+// pidOffset is added to the pids of the queries received through the
+// PostgreSQL protocol, so they don't collide with the ones of the MySQL
+// connections and the HTTP API.
+const pidOffset = 2 << 32
+
+// SessionBuilder creates the session of a new connection of the given user
+// and client address.
+type SessionBuilder func(user, client string, connID uint32) sql.Session
+
+// Config for the PostgreSQL server.
+type Config struct {
+	// Address where the server listens.
+	Address string
+	// ConnReadTimeout is the maximum time to wait for a message of the
+	// client. Zero means no timeout.
+	ConnReadTimeout time.Duration
+	// ConnWriteTimeout is the maximum time to wait to write a message to
+	// the client. Zero means no timeout.
+	ConnWriteTimeout time.Duration
+	// TLSConfig enables TLS for the clients that ask for it. Without TLS,
+	// passwords are sent in clear text.
+	TLSConfig *tls.Config
+	// RequireSecureTransport rejects the clients that don't use TLS.
+	RequireSecureTransport bool
+}
+
+// Server accepts connections of PostgreSQL clients and runs their queries
+// with an engine. Clients are authenticated against the auth.Auth of the
+// engine with their passwords in clear text, so unless TLS is enabled the
+// server is meant to be used in trusted networks.
+type Server struct {
+	cfg      Config
+	engine   *sqle.Engine
+	builder  SessionBuilder
+	listener net.Listener
+
+	mu     sync.Mutex
+	conns  map[uint32]*conn
+	closed bool
+
+	connID uint32
+	pid    uint64
+}
+
+// NewServer creates a server listening on the address of the config.
+func NewServer(cfg Config, e *sqle.Engine, sb SessionBuilder) (*Server, error) {
+	l, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		cfg:      cfg,
+		engine:   e,
+		builder:  sb,
+		listener: l,
+		conns:    make(map[uint32]*conn),
+	}, nil
+}
+
+// Addr returns the address where the server listens.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Start accepts connections until the server is closed.
+func (s *Server) Start() error {
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		c := &conn{
+			srv:    s,
+			nc:     nc,
+			id:     atomic.AddUint32(&s.connID, 1),
+			secret: rand.Int31(),
+		}
+
+		go s.serve(c)
+	}
+}
+
+func (s *Server) serve(c *conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = c.nc.Close()
+		return
+	}
+	s.conns[c.id] = c
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c.id)
+		s.mu.Unlock()
+	}()
+
+	if err := c.serve(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"conn":  c.id,
+			"error": err,
+		}).Debug("postgres connection closed with error")
+	}
+}
+
+// Connections returns the number of open connections.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// cancel cancels the running query of the connection with the given id
+// and secret key.
+func (s *Server) cancel(id uint32, secret int32) {
+	s.mu.Lock()
+	c, ok := s.conns[id]
+	s.mu.Unlock()
+
+	if ok && c.secret == secret {
+		c.cancelQuery()
+	}
+}
+
+// Close stops accepting connections and closes the open ones.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	conns := make([]*conn, 0, len(s.conns))
+	for _, c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	for _, c := range conns {
+		c.cancelQuery()
+		_ = c.nc.Close()
+	}
+
+	return err
+}
@@ -0,0 +1,538 @@
+package pgwire
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	sqle "github.com/src-d/go-mysql-server"
+	"github.com/src-d/go-mysql-server/auth"
+	"github.com/src-d/go-mysql-server/mem"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/src-d/go-mysql-server/sql/analyzer"
+	"github.com/stretchr/testify/require"
+)
+
+func setupServer(t *testing.T, userAuth auth.Auth) (*Server, func()) {
+	t.Helper()
+	return setupServerWithConfig(t, userAuth, Config{Address: "localhost:0"})
+}
+
+func setupServerWithConfig(
+	t *testing.T,
+	userAuth auth.Auth,
+	cfg Config,
+) (*Server, func()) {
+	t.Helper()
+	require := require.New(t)
+
+	table := mem.NewTable("repos", sql.Schema{
+		{Name: "name", Type: sql.Text, Source: "repos"},
+		{Name: "stars", Type: sql.Int64, Source: "repos"},
+		{Name: "created", Type: sql.Timestamp, Source: "repos"},
+		{Name: "archived", Type: sql.Boolean, Source: "repos"},
+		{Name: "head", Type: sql.Blob, Source: "repos", Nullable: true},
+	})
+
+	ctx := sql.NewEmptyContext()
+	created := time.Date(2019, time.July, 1, 12, 30, 0, 0, time.UTC)
+	require.NoError(table.Insert(ctx, sql.NewRow("gitbase", int64(1500), created, false, []byte{0xca, 0xfe})))
+	require.NoError(table.Insert(ctx, sql.NewRow("go-git", int64(3000), created, true, nil)))
+	require.NoError(table.Insert(ctx, sql.NewRow("it's", int64(1), created, false, nil)))
+
+	db := mem.NewDatabase("gitbase")
+	db.AddTable("repos", table)
+
+	catalog := sql.NewCatalog()
+	catalog.AddDatabase(db)
+	engine := sqle.New(catalog, analyzer.NewDefault(catalog), &sqle.Config{Auth: userAuth})
+
+	s, err := NewServer(cfg, engine,
+		func(user, client string, connID uint32) sql.Session {
+			return sql.NewSession("localhost", client, user, connID)
+		},
+	)
+	require.NoError(err)
+
+	go func() {
+		require.NoError(s.Start())
+	}()
+
+	return s, func() {
+		require.NoError(s.Close())
+	}
+}
+
+// client is a minimal PostgreSQL client that returns the messages of the
+// server as strings, so they can be easily compared.
+type client struct {
+	t  *testing.T
+	nc net.Conn
+	r  *bufio.Reader
+}
+
+func newClient(t *testing.T, s *Server) *client {
+	nc, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+
+	return &client{t, nc, bufio.NewReader(nc)}
+}
+
+func (c *client) sendUntyped(m *message) {
+	buf := make([]byte, 4, len(m.buf)+4)
+	binary.BigEndian.PutUint32(buf, uint32(len(m.buf)+4))
+	_, err := c.nc.Write(append(buf, m.buf...))
+	require.NoError(c.t, err)
+}
+
+func (c *client) send(msgs ...*message) {
+	for _, m := range msgs {
+		require.NoError(c.t, m.writeTo(c.nc))
+	}
+}
+
+func (c *client) startup(user string) []string {
+	c.sendUntyped(newMessage(0).int32(protocolVersion).
+		string("user").string(user).
+		string("database").string("gitbase").
+		byte(0))
+	return c.recv()
+}
+
+// recv reads messages until the server is ready for a query or it closes
+// the connection.
+func (c *client) recv() []string {
+	var msgs []string
+	for {
+		typ, body, err := readMessage(c.r, true)
+		if err != nil {
+			return msgs
+		}
+
+		msgs = append(msgs, formatMessage(typ, body))
+		if typ == msgReadyForQuery || typ == msgAuthentication && len(body) == 4 && body[3] == authCleartextPassword {
+			return msgs
+		}
+	}
+}
+
+func formatMessage(typ byte, body []byte) string {
+	r := newReader(typ, body)
+	fields := []string{string(typ)}
+	switch typ {
+	case msgRowDescription:
+		for n := r.int16(); n > 0; n-- {
+			name := r.string()
+			r.int32()
+			r.int16()
+			oid := r.int32()
+			r.int16()
+			r.int32()
+			r.int16()
+			fields = append(fields, fmt.Sprintf("%s:%d", name, oid))
+		}
+	case msgDataRow:
+		for n := r.int16(); n > 0; n-- {
+			v := r.bytes()
+			if v == nil {
+				fields = append(fields, "NULL")
+			} else {
+				fields = append(fields, string(v))
+			}
+		}
+	case msgErrorResponse, msgNoticeResponse:
+		for code := r.byte(); code != 0 && r.err == nil; code = r.byte() {
+			v := r.string()
+			if code == 'C' || code == 'M' {
+				fields = append(fields, v)
+			}
+		}
+	case msgParameterDescription:
+		for n := r.int16(); n > 0; n-- {
+			fields = append(fields, fmt.Sprint(r.int32()))
+		}
+	case msgAuthentication:
+		fields = append(fields, fmt.Sprint(r.int32()))
+	case msgCommandComplete:
+		fields = append(fields, r.string())
+	case msgReadyForQuery:
+		fields = append(fields, string(r.byte()))
+	case msgParameterStatus:
+		fields = append(fields, r.string()+"="+r.string())
+	case msgBackendKeyData:
+		return "K"
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func (c *client) query(q string) []string {
+	c.send(newMessage(msgQuery).string(q))
+	return c.recv()
+}
+
+func (c *client) close() {
+	// the server may have closed the connection already
+	_ = newMessage(msgTerminate).writeTo(c.nc)
+	require.NoError(c.t, c.nc.Close())
+}
+
+var startupMessages = []string{
+	"R 0",
+	"S server_version=9.6.0",
+	"S server_encoding=UTF8",
+	"S client_encoding=UTF8",
+	"S DateStyle=ISO, MDY",
+	"S TimeZone=UTC",
+	"S integer_datetimes=on",
+	"S standard_conforming_strings=on",
+	"S application_name=",
+	"K",
+	"Z I",
+}
+
+func TestSimpleQuery(t *testing.T) {
+	s, cleanup := setupServer(t, new(auth.None))
+	defer cleanup()
+
+	c := newClient(t, s)
+	defer c.close()
+
+	require.Equal(t, startupMessages, c.startup("root"))
+
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{
+			"SELECT name, stars, created, archived, head FROM repos ORDER BY stars",
+			[]string{
+				"T name:25 stars:20 created:1184 archived:16 head:17",
+				"D it's 1 2019-07-01 12:30:00+00 f NULL",
+				"D gitbase 1500 2019-07-01 12:30:00+00 f \\xcafe",
+				"D go-git 3000 2019-07-01 12:30:00+00 t NULL",
+				"C SELECT 3",
+				"Z I",
+			},
+		},
+		{
+			"SELECT 1 AS a; SELECT 'b;' AS b;",
+			[]string{
+				"T a:20", "D 1", "C SELECT 1",
+				"T b:25", "D b;", "C SELECT 1",
+				"Z I",
+			},
+		},
+		{
+			" ; ",
+			[]string{"I", "Z I"},
+		},
+		{
+			"SELECT * FROM nope; SELECT 1",
+			[]string{"E 42P01 table not found: nope", "Z I"},
+		},
+		{
+			"SELEC 1",
+			[]string{"E 42601 syntax error at position 6 near 'SELEC'", "Z I"},
+		},
+		{
+			"BEGIN",
+			[]string{"C BEGIN", "Z T"},
+		},
+		{
+			"COMMIT",
+			[]string{"C COMMIT", "Z I"},
+		},
+		{
+			"SET TIME ZONE 'UTC'",
+			[]string{"N 42601 syntax error at position 14 near 'ZONE'", "C SET", "Z I"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.query, func(t *testing.T) {
+			require.Equal(t, tt.expected, c.query(tt.query))
+		})
+	}
+}
+
+func TestExtendedQuery(t *testing.T) {
+	require := require.New(t)
+	s, cleanup := setupServer(t, new(auth.None))
+	defer cleanup()
+
+	c := newClient(t, s)
+	defer c.close()
+
+	require.Equal(startupMessages, c.startup("root"))
+
+	bind := func(portal, stmt string, values ...[]byte) *message {
+		m := newMessage(msgBind).string(portal).string(stmt).int16(0).int16(len(values))
+		for _, v := range values {
+			m.bytes(v)
+		}
+		return m.int16(0)
+	}
+
+	c.send(
+		newMessage(msgParse).string("q").
+			string("SELECT name FROM repos WHERE stars > $1 OR name = $2 ORDER BY name").
+			int16(1).int32(oidInt8),
+		newMessage(msgDescribe).byte('S').string("q"),
+		bind("", "q", []byte("1000"), []byte("it's")),
+		newMessage(msgExecute).string("").int32(2),
+		newMessage(msgExecute).string("").int32(2),
+		newMessage(msgSync),
+	)
+	require.Equal([]string{
+		"1",
+		"t 20 25",
+		"T name:25",
+		"2",
+		"D gitbase",
+		"D go-git",
+		"s",
+		"D it's",
+		"C SELECT 3",
+		"Z I",
+	}, c.recv())
+
+	c.send(
+		bind("p", "q", nil, []byte("gitbase")),
+		newMessage(msgDescribe).byte('P').string("p"),
+		newMessage(msgExecute).string("p").int32(0),
+		newMessage(msgClose).byte('P').string("p"),
+		newMessage(msgClose).byte('S').string("q"),
+		newMessage(msgSync),
+	)
+	require.Equal([]string{
+		"2",
+		"T name:25",
+		"D gitbase",
+		"C SELECT 1",
+		"3",
+		"3",
+		"Z I",
+	}, c.recv())
+
+	c.send(
+		newMessage(msgParse).string("").string("SELECT 1; SELECT 2").int16(0),
+		bind("", ""),
+		newMessage(msgExecute).string("").int32(0),
+		newMessage(msgSync),
+	)
+	require.Equal([]string{
+		"E 42601 cannot insert multiple commands into a prepared statement",
+		"Z I",
+	}, c.recv())
+
+	c.send(bind("", "q"), newMessage(msgSync))
+	require.Equal([]string{
+		`E 26000 prepared statement "q" does not exist`,
+		"Z I",
+	}, c.recv())
+
+	c.send(
+		newMessage(msgParse).string("").string("SELECT $1").int16(0),
+		newMessage(msgBind).string("").string("").
+			int16(0).
+			int16(1).bytes([]byte("x")).
+			int16(1).int16(1),
+		newMessage(msgSync),
+	)
+	require.Equal([]string{
+		"1",
+		"E 0A000 binary format is not supported",
+		"Z I",
+	}, c.recv())
+}
+
+func TestAuthentication(t *testing.T) {
+	s, cleanup := setupServer(t, auth.NewNativeSingle("user", "pass", auth.ReadPerm))
+	defer cleanup()
+
+	t.Run("valid password", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		require.Equal([]string{"R 3"}, c.startup("user"))
+		c.send(newMessage(msgPassword).string("pass"))
+		require.Equal(startupMessages, c.recv())
+
+		require.Equal([]string{
+			"E 42501 not authorized: user does not have permission: write",
+			"Z I",
+		}, c.query("DROP INDEX foo ON repos"))
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		require.Equal([]string{"R 3"}, c.startup("user"))
+		c.send(newMessage(msgPassword).string("nope"))
+		require.Equal([]string{
+			`E 28P01 password authentication failed for user "user"`,
+		}, c.recv())
+	})
+}
+
+func TestSSLRequest(t *testing.T) {
+	require := require.New(t)
+	s, cleanup := setupServer(t, new(auth.None))
+	defer cleanup()
+
+	c := newClient(t, s)
+	defer c.close()
+
+	c.sendUntyped(newMessage(0).int32(sslRequestCode))
+	b, err := c.r.ReadByte()
+	require.NoError(err)
+	require.Equal(byte('N'), b)
+
+	require.Equal(startupMessages, c.startup("root"))
+}
+
+// newTestTLSConfig returns a TLS config with a self-signed certificate for
+// localhost.
+func newTestTLSConfig(t *testing.T) *tls.Config {
+	require := require.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(err)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
+
+// requestSSL asks the server to use TLS and returns its answer. The TLS
+// handshake is done if the server accepts it.
+func (c *client) requestSSL() byte {
+	c.sendUntyped(newMessage(0).int32(sslRequestCode))
+	b, err := c.r.ReadByte()
+	require.NoError(c.t, err)
+
+	if b == 'S' {
+		tc := tls.Client(c.nc, &tls.Config{InsecureSkipVerify: true})
+		require.NoError(c.t, tc.Handshake())
+		c.nc = tc
+		c.r = bufio.NewReader(tc)
+	}
+
+	return b
+}
+
+func TestTLS(t *testing.T) {
+	s, cleanup := setupServerWithConfig(
+		t,
+		auth.NewNativeSingle("user", "pass", auth.ReadPerm),
+		Config{Address: "localhost:0", TLSConfig: newTestTLSConfig(t)},
+	)
+	defer cleanup()
+
+	t.Run("ssl request", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		require.Equal(byte('S'), c.requestSSL())
+		require.Equal([]string{"R 3"}, c.startup("user"))
+		c.send(newMessage(msgPassword).string("pass"))
+		require.Equal(startupMessages, c.recv())
+		require.Equal([]string{"T stars:20", "D 3000", "C SELECT 1", "Z I"},
+			c.query("SELECT stars FROM repos WHERE name = 'go-git'"))
+	})
+
+	t.Run("gssenc request", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		c.sendUntyped(newMessage(0).int32(gssEncRequestCode))
+		b, err := c.r.ReadByte()
+		require.NoError(err)
+		require.Equal(byte('N'), b)
+	})
+
+	t.Run("without ssl", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		require.Equal([]string{"R 3"}, c.startup("user"))
+	})
+}
+
+func TestRequireSecureTransport(t *testing.T) {
+	s, cleanup := setupServerWithConfig(t, new(auth.None), Config{
+		Address:                "localhost:0",
+		TLSConfig:              newTestTLSConfig(t),
+		RequireSecureTransport: true,
+	})
+	defer cleanup()
+
+	t.Run("ssl", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		require.Equal(byte('S'), c.requestSSL())
+		require.Equal(startupMessages, c.startup("root"))
+	})
+
+	t.Run("insecure", func(t *testing.T) {
+		require := require.New(t)
+		c := newClient(t, s)
+		defer c.close()
+
+		require.Equal([]string{
+			"E 28000 insecure connections are not allowed, the client must use SSL",
+		}, c.startup("root"))
+	})
+}
+
+func TestConnections(t *testing.T) {
+	require := require.New(t)
+	s, cleanup := setupServer(t, new(auth.None))
+	defer cleanup()
+
+	require.Equal(0, s.Connections())
+
+	c := newClient(t, s)
+	require.Equal(startupMessages, c.startup("root"))
+	require.Equal(1, s.Connections())
+
+	c.close()
+	deadline := time.Now().Add(time.Second)
+	for s.Connections() > 0 {
+		require.True(time.Now().Before(deadline), "connection not closed")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
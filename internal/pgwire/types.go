@@ -0,0 +1,114 @@
+package pgwire
+
+import (
+	"encoding/hex"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"vitess.io/vitess/go/sqltypes"
+)
+
+// OIDs of the PostgreSQL types the gitbase types are mapped to.
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTimestamptz = 1184
+	oidNumeric     = 1700
+	oidJSONB       = 3802
+)
+
+// pgType is the PostgreSQL type a column is described with.
+type pgType struct {
+	oid  int
+	size int
+}
+
+// typeOf returns the PostgreSQL type of the given gitbase type. Types
+// without an exact equivalent, such as the unsigned integers, are mapped
+// to a wider type so all their values fit.
+func typeOf(t sql.Type) pgType {
+	switch t.Type() {
+	case sqltypes.Bit:
+		return pgType{oidBool, 1}
+	case sqltypes.Int8, sqltypes.Uint8, sqltypes.Int16:
+		return pgType{oidInt2, 2}
+	case sqltypes.Uint16, sqltypes.Int24, sqltypes.Uint24, sqltypes.Int32:
+		return pgType{oidInt4, 4}
+	case sqltypes.Uint32, sqltypes.Int64:
+		return pgType{oidInt8, 8}
+	case sqltypes.Uint64:
+		return pgType{oidNumeric, -1}
+	case sqltypes.Float32:
+		return pgType{oidFloat4, 4}
+	case sqltypes.Float64:
+		return pgType{oidFloat8, 8}
+	case sqltypes.Timestamp, sqltypes.Datetime:
+		return pgType{oidTimestamptz, 8}
+	case sqltypes.Date:
+		return pgType{oidDate, 4}
+	case sqltypes.Blob:
+		return pgType{oidBytea, -1}
+	case sqltypes.TypeJSON:
+		return pgType{oidJSONB, -1}
+	case sqltypes.VarChar:
+		return pgType{oidVarchar, -1}
+	default:
+		return pgType{oidText, -1}
+	}
+}
+
+// isNumeric reports whether the values of the type with the given OID are
+// written as numbers in SQL.
+func isNumeric(oid int) bool {
+	switch oid {
+	case oidInt2, oidInt4, oidInt8, oidFloat4, oidFloat8, oidNumeric:
+		return true
+	default:
+		return false
+	}
+}
+
+// encodeText returns the text format of a value of the given type, or nil
+// if the value is NULL.
+func encodeText(t sql.Type, v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	val, err := t.SQL(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if val.IsNull() {
+		return nil, nil
+	}
+
+	raw := val.Raw()
+	switch typeOf(t).oid {
+	case oidBool:
+		if string(raw) == "1" {
+			return []byte("t"), nil
+		}
+		return []byte("f"), nil
+	case oidTimestamptz:
+		return append(raw[:len(raw):len(raw)], "+00"...), nil
+	case oidBytea:
+		buf := make([]byte, 2+hex.EncodedLen(len(raw)))
+		copy(buf, `\x`)
+		hex.Encode(buf[2:], raw)
+		return buf, nil
+	default:
+		if raw == nil {
+			raw = []byte{}
+		}
+		return raw, nil
+	}
+}
@@ -0,0 +1,69 @@
+package pgwire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeOf(t *testing.T) {
+	testCases := []struct {
+		typ      sql.Type
+		expected pgType
+	}{
+		{sql.Boolean, pgType{oidBool, 1}},
+		{sql.Int8, pgType{oidInt2, 2}},
+		{sql.Int32, pgType{oidInt4, 4}},
+		{sql.Uint32, pgType{oidInt8, 8}},
+		{sql.Int64, pgType{oidInt8, 8}},
+		{sql.Uint64, pgType{oidNumeric, -1}},
+		{sql.Float64, pgType{oidFloat8, 8}},
+		{sql.Timestamp, pgType{oidTimestamptz, 8}},
+		{sql.Date, pgType{oidDate, 4}},
+		{sql.Blob, pgType{oidBytea, -1}},
+		{sql.JSON, pgType{oidJSONB, -1}},
+		{sql.Array(sql.Text), pgType{oidJSONB, -1}},
+		{sql.Text, pgType{oidText, -1}},
+		{sql.VarChar(40), pgType{oidVarchar, -1}},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.typ.String(), func(t *testing.T) {
+			require.Equal(t, tt.expected, typeOf(tt.typ))
+		})
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	ts := time.Date(2019, time.July, 1, 12, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		typ      sql.Type
+		value    interface{}
+		expected []byte
+	}{
+		{"null", sql.Text, nil, nil},
+		{"empty text", sql.Text, "", []byte{}},
+		{"true", sql.Boolean, true, []byte("t")},
+		{"false", sql.Boolean, false, []byte("f")},
+		{"int", sql.Int64, int64(42), []byte("42")},
+		{"float", sql.Float64, 1.5, []byte("1.5")},
+		{"timestamp", sql.Timestamp, ts, []byte("2019-07-01 12:30:00+00")},
+		{"date", sql.Date, ts, []byte("2019-07-01")},
+		{"blob", sql.Blob, []byte("gb"), []byte(`\x6762`)},
+		{"json", sql.JSON, map[string]interface{}{"a": 1}, []byte(`{"a":1}`)},
+		{"array", sql.Array(sql.Text), []interface{}{"a", "b"}, []byte(`["a","b"]`)},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			v, err := encodeText(tt.typ, tt.value)
+			require.NoError(err)
+			require.Equal(tt.expected, v)
+		})
+	}
+}
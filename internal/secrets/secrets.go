@@ -0,0 +1,234 @@
+// Package secrets finds secrets, such as credentials or private keys, in
+// the content of files using a set of rules.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/src-d/enry/v2"
+	errors "gopkg.in/src-d/go-errors.v1"
+	"gopkg.in/src-d/go-git.v4/utils/binary"
+)
+
+// RulesFileKey is the environment variable with the path of the JSON file
+// containing the rules used by the default scanner.
+const RulesFileKey = "GITBASE_SECRET_RULES_FILE"
+
+var (
+	// ErrInvalidRule is returned when a rule can't be used to find secrets.
+	ErrInvalidRule = errors.NewKind("invalid secret rule %q: %s")
+
+	// ErrRulesFile is returned when the rules file can't be loaded.
+	ErrRulesFile = errors.NewKind("unable to load secret rules file %s: %s")
+)
+
+// Rule describes how to find a kind of secret.
+type Rule struct {
+	// ID identifies the rule in the findings.
+	ID string `json:"id"`
+	// Regex is the regular expression matching the secret. If it has
+	// capturing groups, the first one is the secret itself and the rest of
+	// the match is just context. Otherwise, the whole match is the secret.
+	Regex string `json:"regex"`
+	// Entropy is the minimum Shannon entropy, in bits per character, the
+	// secret must have to be reported. It's used to discard placeholders
+	// and other values that are not random enough. 0 disables the check.
+	Entropy float64 `json:"entropy"`
+}
+
+// DefaultRules are the rules used when no rules file is configured.
+var DefaultRules = []Rule{
+	{ID: "aws-access-key-id", Regex: `\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`},
+	{
+		ID:      "aws-secret-access-key",
+		Regex:   `(?i)aws.{0,20}?(?:secret|key).{0,20}?['"]([0-9a-zA-Z/+]{40})['"]`,
+		Entropy: 3.5,
+	},
+	{ID: "github-token", Regex: `\b(gh[pousr]_[0-9a-zA-Z]{36})\b`},
+	{ID: "slack-token", Regex: `\b(xox[abposr]-[0-9a-zA-Z-]{10,72})\b`},
+	{ID: "google-api-key", Regex: `\b(AIza[0-9A-Za-z_-]{35})\b`},
+	{
+		ID:    "private-key",
+		Regex: `-----BEGIN (?:RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`,
+	},
+	{
+		ID:      "generic-secret",
+		Regex:   `(?i)(?:api_?key|secret|token|passw(?:or)?d)["']?\s*[:=]\s*["']([^"'\s]{12,})["']`,
+		Entropy: 3.5,
+	},
+}
+
+// Finding is a secret found in a file.
+type Finding struct {
+	// RuleID is the ID of the rule that found the secret.
+	RuleID string `json:"RuleID"`
+	// Line is the line, starting at 1, where the secret is.
+	Line int `json:"Line"`
+	// Match is the text matched by the rule with the secret redacted.
+	Match string `json:"Match"`
+}
+
+type rule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// Scanner finds secrets in files.
+type Scanner struct {
+	rules []rule
+}
+
+// NewScanner creates a new scanner with the given rules.
+func NewScanner(rules []Rule) (*Scanner, error) {
+	s := &Scanner{rules: make([]rule, len(rules))}
+	for i, r := range rules {
+		if r.ID == "" {
+			return nil, ErrInvalidRule.New(r.Regex, "empty id")
+		}
+
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, ErrInvalidRule.New(r.ID, err)
+		}
+
+		s.rules[i] = rule{r, re}
+	}
+
+	return s, nil
+}
+
+// LoadRules reads a JSON file containing an array of rules.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, ErrRulesFile.New(path, err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, ErrRulesFile.New(path, err)
+	}
+
+	return rules, nil
+}
+
+var (
+	defaultScanner     *Scanner
+	defaultScannerErr  error
+	defaultScannerOnce sync.Once
+)
+
+// DefaultScanner returns a scanner with the rules of the file set in the
+// GITBASE_SECRET_RULES_FILE environment variable or, if it's not set, with
+// DefaultRules. The rules are only loaded once.
+func DefaultScanner() (*Scanner, error) {
+	defaultScannerOnce.Do(func() {
+		rules := DefaultRules
+		if path := os.Getenv(RulesFileKey); path != "" {
+			rules, defaultScannerErr = LoadRules(path)
+			if defaultScannerErr != nil {
+				return
+			}
+		}
+
+		defaultScanner, defaultScannerErr = NewScanner(rules)
+	})
+
+	return defaultScanner, defaultScannerErr
+}
+
+// Scan returns the secrets found in the given file. Vendored and binary
+// files are ignored.
+func (s *Scanner) Scan(path string, content []byte) ([]Finding, error) {
+	if len(content) == 0 || enry.IsVendor(path) {
+		return nil, nil
+	}
+
+	bin, err := binary.IsBinary(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if bin {
+		return nil, nil
+	}
+
+	var findings []Finding
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+
+	var line int
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		for _, r := range s.rules {
+			for _, m := range r.re.FindAllStringSubmatchIndex(text, -1) {
+				if f, ok := r.finding(text, m); ok {
+					f.Line = line
+					findings = append(findings, f)
+				}
+			}
+		}
+	}
+
+	return findings, scanner.Err()
+}
+
+func (r rule) finding(text string, m []int) (Finding, bool) {
+	start, end := m[0], m[1]
+	secretStart, secretEnd := start, end
+	if len(m) >= 4 && m[2] >= 0 {
+		secretStart, secretEnd = m[2], m[3]
+	}
+
+	secret := text[secretStart:secretEnd]
+	if r.Entropy > 0 && entropy(secret) < r.Entropy {
+		return Finding{}, false
+	}
+
+	return Finding{
+		RuleID: r.ID,
+		Match:  text[start:secretStart] + redact(secret) + text[secretEnd:end],
+	}, true
+}
+
+// redact keeps the first characters of the secret, which usually identify
+// its kind, and masks the rest.
+func redact(secret string) string {
+	const visible = 4
+	if len(secret) <= visible*2 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return secret[:visible] + strings.Repeat("*", len(secret)-visible)
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	if len(s) == 0 {
+		return 0
+	}
+
+	freqs := make(map[rune]float64)
+	var total float64
+	for _, r := range s {
+		freqs[r]++
+		total++
+	}
+
+	var e float64
+	for _, f := range freqs {
+		p := f / total
+		e -= p * math.Log2(p)
+	}
+
+	return e
+}
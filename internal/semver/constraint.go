@@ -0,0 +1,237 @@
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidConstraint is returned when a version constraint can't be parsed.
+var ErrInvalidConstraint = errors.NewKind("invalid version constraint %q: %s")
+
+var (
+	termRegex = regexp.MustCompile(`^(!=|>=|<=|=|>|<|~|\^)?\s*` +
+		`[vV]?(\*|[xX]|[0-9]+)(?:\.(\*|[xX]|[0-9]+))?(?:\.(\*|[xX]|[0-9]+))?` +
+		`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?` +
+		`(?:\+[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*)?`)
+	separatorRegex = regexp.MustCompile(`^[\s,]*`)
+)
+
+// Constraint is a set of conditions a version can satisfy. Conditions are
+// made of an operator followed by a version, which may be partial, such as
+// "1.2", or have wildcards, such as "1.x" or "1.2.*":
+//
+//   - "=" or no operator: equal to the version.
+//   - "!=": not equal to the version.
+//   - ">", ">=", "<", "<=": greater or lower than the version.
+//   - "~": same major and minor version, or the same major version if the
+//     minor version is not given.
+//   - "^": same leftmost non-zero part of the version.
+//
+// Conditions separated by commas or spaces must all be satisfied and
+// groups of them can be separated with "||" when any of them can be.
+//
+// Pre-release versions only satisfy a group of conditions if any of them
+// uses a pre-release of the same major, minor and patch version.
+type Constraint struct {
+	groups [][]condition
+}
+
+// condition is a range of versions, which may be unbounded.
+type condition struct {
+	min, max         *Version
+	minIncl, maxIncl bool
+	negate           bool
+	// prerelease is the version of the condition if it was a pre-release.
+	prerelease *Version
+}
+
+// ParseConstraint parses a version constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	var c Constraint
+	for _, group := range strings.Split(s, "||") {
+		var conds []condition
+		rest := strings.TrimSpace(group)
+		for rest != "" {
+			m := termRegex.FindStringSubmatch(rest)
+			if m == nil {
+				return nil, ErrInvalidConstraint.New(s, "unexpected "+strconv.Quote(rest))
+			}
+
+			cond, err := parseCondition(m[1], m[2:5], m[5])
+			if err != nil {
+				return nil, ErrInvalidConstraint.New(s, err.Error())
+			}
+
+			conds = append(conds, cond)
+			rest = rest[len(m[0]):]
+
+			sep := separatorRegex.FindString(rest)
+			if sep == "" && rest != "" {
+				return nil, ErrInvalidConstraint.New(s, "unexpected "+strconv.Quote(rest))
+			}
+			rest = rest[len(sep):]
+		}
+
+		if len(conds) == 0 {
+			return nil, ErrInvalidConstraint.New(s, "empty condition")
+		}
+
+		c.groups = append(c.groups, conds)
+	}
+
+	return &c, nil
+}
+
+func parseCondition(op string, parts []string, prerelease string) (condition, error) {
+	var nums []uint64
+	for _, p := range parts {
+		if p == "" || p == "*" || p == "x" || p == "X" {
+			break
+		}
+
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return condition{}, err
+		}
+		nums = append(nums, n)
+	}
+
+	v := &Version{}
+	for i, n := range nums {
+		switch i {
+		case 0:
+			v.Major = n
+		case 1:
+			v.Minor = n
+		case 2:
+			v.Patch = n
+		}
+	}
+
+	full := len(nums) == 3
+	if prerelease != "" {
+		if !full {
+			return condition{}, fmt.Errorf("pre-release of partial version %s", v)
+		}
+		v.Prerelease = prerelease
+	}
+
+	var cond condition
+	if prerelease != "" {
+		cond.prerelease = v
+	}
+
+	if len(nums) == 0 {
+		switch op {
+		case "", "=", ">=", "<=", "~", "^":
+			return cond, nil
+		default:
+			return condition{}, fmt.Errorf("operator %s with wildcard version", op)
+		}
+	}
+
+	// next is the lowest version greater than all the versions matching
+	// a partial version.
+	next := bump(v, len(nums)-1)
+	switch op {
+	case "", "=", "!=":
+		cond.min, cond.minIncl = v, true
+		if full {
+			cond.max, cond.maxIncl = v, true
+		} else {
+			cond.max = next
+		}
+		cond.negate = op == "!="
+	case ">":
+		if full {
+			cond.min = v
+		} else {
+			cond.min, cond.minIncl = next, true
+		}
+	case ">=":
+		cond.min, cond.minIncl = v, true
+	case "<":
+		cond.max = v
+	case "<=":
+		if full {
+			cond.max, cond.maxIncl = v, true
+		} else {
+			cond.max = next
+		}
+	case "~":
+		cond.min, cond.minIncl = v, true
+		if len(nums) == 1 {
+			cond.max = bump(v, 0)
+		} else {
+			cond.max = bump(v, 1)
+		}
+	case "^":
+		cond.min, cond.minIncl = v, true
+		i := 0
+		for i < len(nums)-1 && nums[i] == 0 {
+			i++
+		}
+		cond.max = bump(v, i)
+	}
+
+	return cond, nil
+}
+
+// bump returns the version resulting from incrementing the given part of
+// the version, 0 being the major version, and resetting the following ones.
+func bump(v *Version, part int) *Version {
+	switch part {
+	case 0:
+		return &Version{Major: v.Major + 1}
+	case 1:
+		return &Version{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
+
+// Check returns whether the version satisfies the constraint.
+func (c *Constraint) Check(v *Version) bool {
+	for _, conds := range c.groups {
+		if checkGroup(conds, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkGroup(conds []condition, v *Version) bool {
+	allowPrerelease := v.Prerelease == ""
+	for _, cond := range conds {
+		if !cond.check(v) {
+			return false
+		}
+
+		if p := cond.prerelease; p != nil &&
+			p.Major == v.Major && p.Minor == v.Minor && p.Patch == v.Patch {
+			allowPrerelease = true
+		}
+	}
+
+	return allowPrerelease
+}
+
+func (c condition) check(v *Version) bool {
+	ok := true
+	if c.min != nil {
+		cmp := v.Compare(c.min)
+		ok = cmp > 0 || (cmp == 0 && c.minIncl)
+	}
+
+	if ok && c.max != nil {
+		cmp := v.Compare(c.max)
+		ok = cmp < 0 || (cmp == 0 && c.maxIncl)
+	}
+
+	return ok != c.negate
+}
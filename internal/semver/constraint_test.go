@@ -0,0 +1,80 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintCheck(t *testing.T) {
+	testCases := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{"1.2.3", []string{"1.2.3", "v1.2.3+build"}, []string{"1.2.4", "1.2.3-rc.1"}},
+		{"=1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"1.x", []string{"1.0.0", "1.9.9"}, []string{"2.0.0", "0.9.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		{"!=1.2.3", []string{"1.2.2", "1.2.4"}, []string{"1.2.3"}},
+		{"!= 1.2", []string{"1.1.9", "1.3.0"}, []string{"1.2.0", "1.2.5"}},
+		{">1.2.3", []string{"1.2.4", "2.0.0"}, []string{"1.2.3", "1.0.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">=1.2", []string{"1.2.0", "3.0.0"}, []string{"1.1.9"}},
+		{"<1.2.3", []string{"1.2.2", "0.1.0"}, []string{"1.2.3", "1.2.3-rc.1"}},
+		{"<=1.2", []string{"1.2.9", "1.0.0"}, []string{"1.3.0"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"2.0.0", "1.2.2"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0", []string{"0.0.1", "0.9.0"}, []string{"1.0.0"}},
+		{">=1.0.0, <2.0.0", []string{"1.0.0", "1.5.0"}, []string{"2.0.0", "0.9.0"}},
+		{">= 1.0 < 2", []string{"1.5.0"}, []string{"2.0.0"}},
+		{"<1.0.0 || >=3.0.0", []string{"0.5.0", "3.1.0"}, []string{"1.0.0", "2.9.9"}},
+		{
+			">=1.0.0-beta.2",
+			[]string{"1.0.0-beta.2", "1.0.0-rc.1", "1.0.0", "1.1.0"},
+			[]string{"1.0.0-beta.1", "1.1.0-rc.1"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.constraint, func(t *testing.T) {
+			require := require.New(t)
+			c, err := ParseConstraint(tt.constraint)
+			require.NoError(err)
+
+			for _, s := range tt.matches {
+				v, err := Parse(s)
+				require.NoError(err)
+				require.True(c.Check(v), "%s should match", s)
+			}
+
+			for _, s := range tt.rejects {
+				v, err := Parse(s)
+				require.NoError(err)
+				require.False(c.Check(v), "%s should not match", s)
+			}
+		})
+	}
+}
+
+func TestParseConstraintErrors(t *testing.T) {
+	constraints := []string{
+		"",
+		"foo",
+		">=1.0 ||",
+		"1.2.3abc",
+		"1.2.3 - 2.0.0",
+		">*",
+		"1.2-rc.1",
+	}
+
+	for _, c := range constraints {
+		t.Run(c, func(t *testing.T) {
+			_, err := ParseConstraint(c)
+			require.True(t, ErrInvalidConstraint.Is(err))
+		})
+	}
+}
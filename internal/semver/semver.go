@@ -0,0 +1,147 @@
+// Package semver parses and compares semantic versions, as described in
+// https://semver.org, and checks them against version constraints.
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	errors "gopkg.in/src-d/go-errors.v1"
+)
+
+// ErrInvalidVersion is returned when a string is not a semantic version.
+var ErrInvalidVersion = errors.NewKind("invalid semantic version: %q")
+
+const tagPrefix = "refs/tags/"
+
+var versionRegex = regexp.MustCompile(`^[vV]?` +
+	`(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+	`(?:-((?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*)` +
+	`(?:\.(?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Version is a semantic version.
+type Version struct {
+	Major      uint64 `json:"Major"`
+	Minor      uint64 `json:"Minor"`
+	Patch      uint64 `json:"Patch"`
+	Prerelease string `json:"Prerelease"`
+	Build      string `json:"Build"`
+}
+
+// Parse parses a semantic version. The version may have a "v" prefix and
+// be a tag reference name, such as "refs/tags/v1.2.3".
+func Parse(s string) (*Version, error) {
+	m := versionRegex.FindStringSubmatch(strings.TrimPrefix(s, tagPrefix))
+	if m == nil {
+		return nil, ErrInvalidVersion.New(s)
+	}
+
+	var parts [3]uint64
+	for i := range parts {
+		n, err := strconv.ParseUint(m[i+1], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidVersion.New(s)
+		}
+		parts[i] = n
+	}
+
+	return &Version{
+		Major:      parts[0],
+		Minor:      parts[1],
+		Patch:      parts[2],
+		Prerelease: m[4],
+		Build:      m[5],
+	}, nil
+}
+
+// IsValid returns whether the given string is a semantic version
+// accepted by Parse.
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+// String returns the canonical representation of the version, without
+// the "v" prefix.
+func (v *Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+
+	return s
+}
+
+// Compare returns -1, 0 or 1 if the version has lower, equal or higher
+// precedence than the other one. Build metadata is ignored.
+func (v *Version) Compare(other *Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease compares two pre-release versions. A version without
+// pre-release has higher precedence than any pre-release of it, and the
+// identifiers are compared one by one, numerically if both are numbers.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(as)), uint64(len(bs)))
+}
+
+func compareIdentifier(a, b string) int {
+	an, aerr := strconv.ParseUint(a, 10, 64)
+	bn, berr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aerr == nil && berr == nil:
+		return compareUint(an, bn)
+	case aerr == nil:
+		return -1
+	case berr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
@@ -0,0 +1,89 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected *Version
+	}{
+		{"1.2.3", &Version{Major: 1, Minor: 2, Patch: 3}},
+		{"v0.0.1", &Version{Patch: 1}},
+		{"refs/tags/v1.2.3-rc.1", &Version{1, 2, 3, "rc.1", ""}},
+		{"1.0.0-alpha+001", &Version{1, 0, 0, "alpha", "001"}},
+		{"1.0.0+20130313144700", &Version{1, 0, 0, "", "20130313144700"}},
+		{"1.0.0-x-y-z.--", &Version{1, 0, 0, "x-y-z.--", ""}},
+		{"1.2", nil},
+		{"1.2.3.4", nil},
+		{"01.2.3", nil},
+		{"1.2.3-01", nil},
+		{"1.2.3-", nil},
+		{"refs/heads/v1.2.3", nil},
+		{"99999999999999999999.0.0", nil},
+		{"", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.input, func(t *testing.T) {
+			require := require.New(t)
+			v, err := Parse(tt.input)
+			if tt.expected == nil {
+				require.True(ErrInvalidVersion.Is(err))
+				require.False(IsValid(tt.input))
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tt.expected, v)
+			require.True(IsValid(tt.input))
+		})
+	}
+}
+
+func TestVersionString(t *testing.T) {
+	v, err := Parse("v1.0.0-rc.1+build.5")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0-rc.1+build.5", v.String())
+}
+
+func TestCompare(t *testing.T) {
+	// sorted by precedence, as in the semver specification
+	versions := []string{
+		"0.9.9",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+		"10.0.0",
+	}
+
+	require := require.New(t)
+	for i := range versions {
+		for j := range versions {
+			a, err := Parse(versions[i])
+			require.NoError(err)
+			b, err := Parse(versions[j])
+			require.NoError(err)
+
+			expected := compareUint(uint64(i), uint64(j))
+			require.Equal(expected, a.Compare(b), "%s <=> %s", versions[i], versions[j])
+		}
+	}
+
+	a, err := Parse("1.0.0+a")
+	require.NoError(err)
+	b, err := Parse("1.0.0+b")
+	require.NoError(err)
+	require.Equal(0, a.Compare(b))
+}
@@ -0,0 +1,447 @@
+// Package tokenizer splits source code in lexical tokens and identifiers
+// without parsing it, using a lexer for each supported language. Comments
+// and string literals are discarded.
+package tokenizer
+
+import (
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is the kind of a token.
+type Kind int
+
+const (
+	// Identifier is the name of a variable, function, type, etc.
+	Identifier Kind = iota
+	// Keyword is a reserved word of the language.
+	Keyword
+	// Number is a numeric literal.
+	Number
+	// Operator is an operator or a punctuation mark.
+	Operator
+)
+
+// Token is a lexical token of source code.
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// Supported returns whether the language, as returned by the language
+// function, can be tokenized. Names are case insensitive.
+func Supported(lang string) bool {
+	_, ok := lexers[strings.ToLower(lang)]
+	return ok
+}
+
+// Tokens returns the tokens of the content in the given language, without
+// comments and string literals. If the language is not supported, the
+// returned boolean is false.
+func Tokens(lang string, content []byte) ([]Token, bool) {
+	lex, ok := lexers[strings.ToLower(lang)]
+	if !ok {
+		return nil, false
+	}
+
+	return lex(content), true
+}
+
+// Identifiers returns the identifiers of the content in the given language,
+// split in lowercase words by SplitIdentifier. Keywords are not identifiers.
+// If the language is not supported, the returned boolean is false.
+func Identifiers(lang string, content []byte) ([]string, bool) {
+	tokens, ok := Tokens(lang, content)
+	if !ok {
+		return nil, false
+	}
+
+	var words []string
+	for _, t := range tokens {
+		if t.Kind == Identifier {
+			words = append(words, SplitIdentifier(t.Text)...)
+		}
+	}
+
+	return words, true
+}
+
+// SplitIdentifier splits an identifier in lowercase words, using the
+// underscores and other symbols, and the case changes of camelCase and
+// PascalCase names, as separators. A sequence of uppercase letters is an
+// acronym, so "parseHTTPRequest" is split in "parse", "http" and
+// "request". Digits are part of the preceding word.
+func SplitIdentifier(name string) []string {
+	var words []string
+	fields := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, f := range fields {
+		runes := []rune(f)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			if !unicode.IsUpper(runes[i]) {
+				continue
+			}
+
+			prev := runes[i-1]
+			acronymEnd := unicode.IsUpper(prev) &&
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || acronymEnd {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+
+		words = append(words, strings.ToLower(string(runes[start:])))
+	}
+
+	return words
+}
+
+// NGrams returns the sequences of n consecutive texts, joined by a space.
+func NGrams(texts []string, n int) []string {
+	if n <= 1 {
+		return texts
+	}
+
+	var grams []string
+	for i := 0; i+n <= len(texts); i++ {
+		grams = append(grams, strings.Join(texts[i:i+n], " "))
+	}
+
+	return grams
+}
+
+var lexers = map[string]func([]byte) []Token{
+	"go":         goTokens,
+	"python":     pythonLexer.tokens,
+	"java":       javaLexer.tokens,
+	"javascript": javascriptLexer.tokens,
+}
+
+// goTokens uses the scanner of the standard library, which skips the
+// comments and reports the semicolons automatically inserted at the end
+// of the lines with a newline as literal.
+func goTokens(content []byte) []Token {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(content))
+
+	var s scanner.Scanner
+	s.Init(file, content, nil, 0)
+
+	var tokens []Token
+	for {
+		_, tok, lit := s.Scan()
+		switch {
+		case tok == token.EOF:
+			return tokens
+		case tok == token.IDENT:
+			tokens = append(tokens, Token{Identifier, lit})
+		case tok.IsKeyword():
+			tokens = append(tokens, Token{Keyword, lit})
+		case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+			tokens = append(tokens, Token{Number, lit})
+		case tok == token.SEMICOLON && lit == "\n":
+		case tok.IsOperator():
+			tokens = append(tokens, Token{Operator, tok.String()})
+		}
+	}
+}
+
+// lexer is a lexer for languages with a C-like or Python-like syntax.
+type lexer struct {
+	// lineComment starts a comment until the end of the line.
+	lineComment string
+	// blockComments are enabled for /* */ comments.
+	blockComments bool
+	// quotes are the characters delimiting string literals. A backslash
+	// escapes the next character and a newline ends the literal, unless
+	// the quote is a multiline one.
+	quotes string
+	// multilineQuotes are the quotes of literals spanning several lines.
+	multilineQuotes string
+	// tripleQuotes are the quotes that can be tripled to delimit literals
+	// spanning several lines.
+	tripleQuotes string
+	// stringPrefixes are the identifiers that can prefix string literals.
+	stringPrefixes map[string]bool
+	// identRunes are the characters other than letters, digits and the
+	// underscore that can be part of identifiers.
+	identRunes string
+	// regexLiterals are enabled for /regex/ literals. They are only
+	// recognized where an expression can start.
+	regexLiterals bool
+	keywords      map[string]bool
+	// operators are sorted by descending length, so the longest one is
+	// matched first.
+	operators []string
+}
+
+func newLexer(l lexer, keywords, operators string) *lexer {
+	l.keywords = make(map[string]bool)
+	for _, k := range strings.Fields(keywords) {
+		l.keywords[k] = true
+	}
+
+	l.operators = strings.Fields(operators)
+	sort.SliceStable(l.operators, func(i, j int) bool {
+		return len(l.operators[i]) > len(l.operators[j])
+	})
+
+	return &l
+}
+
+var pythonLexer = newLexer(
+	lexer{
+		lineComment:  "#",
+		quotes:       `"'`,
+		tripleQuotes: `"'`,
+		stringPrefixes: map[string]bool{
+			"r": true, "u": true, "b": true, "f": true,
+			"br": true, "rb": true, "fr": true, "rf": true,
+		},
+	},
+	`False None True and as assert async await break class continue def del
+	elif else except finally for from global if import in is lambda nonlocal
+	not or pass raise return try while with yield`,
+	`**= //= >>= <<= ... -> := ** // << >> <= >= == != += -= *= /= %= &= |=
+	^= @=`,
+)
+
+var javaLexer = newLexer(
+	lexer{
+		lineComment:   "//",
+		blockComments: true,
+		quotes:        `"'`,
+		tripleQuotes:  `"`,
+		identRunes:    "$",
+	},
+	`abstract assert boolean break byte case catch char class const continue
+	default do double else enum extends final finally float for goto if
+	implements import instanceof int interface long native new package
+	private protected public return short static strictfp super switch
+	synchronized this throw throws transient try void volatile while true
+	false null`,
+	`>>>= <<= >>= >>> ... -> :: ++ -- && || == != <= >= += -= *= /= %= &= |=
+	^= << >>`,
+)
+
+var javascriptLexer = newLexer(
+	lexer{
+		lineComment:     "//",
+		blockComments:   true,
+		quotes:          `"'`,
+		multilineQuotes: "`",
+		identRunes:      "$",
+		regexLiterals:   true,
+	},
+	`await break case catch class const continue debugger default delete do
+	else enum export extends false finally for function if import in
+	instanceof let new null return super switch this throw true try typeof
+	var void while with yield`,
+	`>>>= === !== **= <<= >>= >>> ... &&= ||= ??= => ** ++ -- && || ?? ?.
+	== != <= >= += -= *= /= %= &= |= ^= << >>`,
+)
+
+// regexPrecedingKeywords are the keywords after which a slash starts a
+// regular expression instead of being a division.
+var regexPrecedingKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+func (l *lexer) tokens(content []byte) []Token {
+	src := string(content)
+	var tokens []Token
+	// regexAllowed is true where an expression can start, that is, at
+	// the beginning and after operators and some keywords.
+	regexAllowed := true
+
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		rest := src[i:]
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case l.lineComment != "" && strings.HasPrefix(rest, l.lineComment):
+			i += lineEnd(rest)
+			continue
+		case l.blockComments && strings.HasPrefix(rest, "/*"):
+			if end := strings.Index(rest[2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(src)
+			}
+			continue
+		case strings.ContainsRune(l.quotes+l.multilineQuotes, r):
+			i += l.stringEnd(rest)
+			regexAllowed = false
+			continue
+		case l.regexLiterals && r == '/' && regexAllowed:
+			i += regexEnd(rest)
+			regexAllowed = false
+			continue
+		case l.isIdentStart(r):
+			end := l.identEnd(rest)
+			word := rest[:end]
+			if end < len(rest) && strings.ContainsRune(l.quotes, rune(rest[end])) &&
+				l.stringPrefixes[strings.ToLower(word)] {
+				i += end + l.stringEnd(rest[end:])
+				regexAllowed = false
+				continue
+			}
+
+			if l.keywords[word] {
+				tokens = append(tokens, Token{Keyword, word})
+				regexAllowed = regexPrecedingKeywords[word]
+			} else {
+				tokens = append(tokens, Token{Identifier, word})
+				regexAllowed = false
+			}
+			i += end
+		case unicode.IsDigit(r) || r == '.' && len(rest) > 1 && isDigit(rest[1]):
+			end := numberEnd(rest)
+			tokens = append(tokens, Token{Number, rest[:end]})
+			regexAllowed = false
+			i += end
+		default:
+			// the bytes are used instead of the rune so invalid UTF-8 sequences
+			// are kept as they are.
+			op := src[i : i+size]
+			for _, o := range l.operators {
+				if strings.HasPrefix(rest, o) {
+					op = o
+					break
+				}
+			}
+
+			tokens = append(tokens, Token{Operator, op})
+			regexAllowed = op != ")" && op != "]" && op != "}" &&
+				op != "++" && op != "--"
+			i += len(op)
+		}
+	}
+
+	return tokens
+}
+
+func (l *lexer) isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_' || strings.ContainsRune(l.identRunes, r)
+}
+
+func (l *lexer) identEnd(s string) int {
+	for i, r := range s {
+		if !l.isIdentStart(r) && !unicode.IsDigit(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// stringEnd returns the length of the string literal at the start of s.
+// Unterminated literals end at the end of the line, or at the end of s if
+// they can span several lines.
+func (l *lexer) stringEnd(s string) int {
+	quote := s[:1]
+	if strings.Contains(l.tripleQuotes, quote) && strings.HasPrefix(s, quote+quote+quote) {
+		delim := quote + quote + quote
+		for i := 3; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(s[i:], delim) {
+				return i + 3
+			}
+		}
+		return len(s)
+	}
+
+	multiline := strings.Contains(l.multilineQuotes, quote)
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == quote[0]:
+			return i + 1
+		case s[i] == '\n' && !multiline:
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+// regexEnd returns the length of the regular expression literal, with its
+// flags, at the start of s. A slash inside a character class does not end
+// the literal.
+func regexEnd(s string) int {
+	var class bool
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '\n':
+			return i
+		case '/':
+			if !class {
+				i++
+				for i < len(s) && isLetter(s[i]) {
+					i++
+				}
+				return i
+			}
+		}
+	}
+
+	return len(s)
+}
+
+// numberEnd returns the length of the numeric literal at the start of s.
+// Signs are only part of the literal after the exponent.
+func numberEnd(s string) int {
+	hex := len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isDigit(c) || isLetter(c) || c == '_' || c == '.':
+		case (c == '+' || c == '-') && isExponent(s[i-1], hex):
+		default:
+			return i
+		}
+	}
+
+	return len(s)
+}
+
+func isExponent(c byte, hex bool) bool {
+	if hex {
+		return c == 'p' || c == 'P'
+	}
+	return c == 'e' || c == 'E'
+}
+
+func lineEnd(s string) int {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return i
+	}
+	return len(s)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
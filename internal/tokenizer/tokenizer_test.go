@@ -0,0 +1,147 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func texts(tokens []Token) []string {
+	result := make([]string, len(tokens))
+	for i, t := range tokens {
+		result[i] = t.Text
+	}
+	return result
+}
+
+func TestTokens(t *testing.T) {
+	testCases := []struct {
+		lang     string
+		content  string
+		expected []string
+	}{
+		{
+			"Go",
+			"package main\n\n// Main does nothing.\nfunc main() {\n\tx := `raw\nstring` + \"s\" + 'c'\n\ty := 0x1P-2 /* comment */ + 1e+3\n}\n",
+			[]string{
+				"package", "main", "func", "main", "(", ")", "{",
+				"x", ":=", "+", "+", "y", ":=", "0x1P-2", "+", "1e+3", "}",
+			},
+		},
+		{
+			"python",
+			"# comment\ndef f(a, b=1.5e-3):\n    '''doc\n    string'''\n    return rb'x' + f\"{a}\" ** b  # done\n",
+			[]string{
+				"def", "f", "(", "a", ",", "b", "=", "1.5e-3", ")", ":",
+				"return", "+", "**", "b",
+			},
+		},
+		{
+			"Java",
+			"/** Doc. */\npublic class A$B {\n  String s = \"\"\"\n  text\n  \"\"\"; // comment\n  int x = y >>>= 2;\n}\n",
+			[]string{
+				"public", "class", "A$B", "{", "String", "s", "=", ";",
+				"int", "x", "=", "y", ">>>=", "2", ";", "}",
+			},
+		},
+		{
+			"JavaScript",
+			"const re = /[/'\"]+/gi; // comment\nlet t = `a\n${b}` / 2;\nif (a === b) x = c / d / e;\nreturn /x/.test(s);\n",
+			[]string{
+				"const", "re", "=", ";", "let", "t", "=", "/", "2", ";",
+				"if", "(", "a", "===", "b", ")", "x", "=", "c", "/", "d", "/", "e", ";",
+				"return", ".", "test", "(", "s", ")", ";",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.lang, func(t *testing.T) {
+			tokens, ok := Tokens(tt.lang, []byte(tt.content))
+			require.True(t, ok)
+			require.Equal(t, tt.expected, texts(tokens))
+		})
+	}
+}
+
+func TestTokensKinds(t *testing.T) {
+	tokens, ok := Tokens("Python", []byte("if x1 >= 2: pass"))
+	require.True(t, ok)
+	require.Equal(t, []Token{
+		{Keyword, "if"},
+		{Identifier, "x1"},
+		{Operator, ">="},
+		{Number, "2"},
+		{Operator, ":"},
+		{Keyword, "pass"},
+	}, tokens)
+}
+
+func TestTokensInvalidUTF8(t *testing.T) {
+	tokens, ok := Tokens("Python", []byte("a \xff\xfe b"))
+	require.True(t, ok)
+	require.Equal(t, []Token{
+		{Identifier, "a"},
+		{Operator, "\xff"},
+		{Operator, "\xfe"},
+		{Identifier, "b"},
+	}, tokens)
+}
+
+func TestTokensUnsupported(t *testing.T) {
+	_, ok := Tokens("Ruby", []byte("puts 1"))
+	require.False(t, ok)
+	require.False(t, Supported("Ruby"))
+	require.True(t, Supported("javascript"))
+}
+
+func TestIdentifiers(t *testing.T) {
+	ids, ok := Identifiers("Go", []byte(`package foo
+
+import "net/http"
+
+// parseRequest parses an HTTP request.
+func parseHTTPRequest(r *http.Request) (base64Value string, err error) {
+	return "", nil
+}
+`))
+	require.True(t, ok)
+	require.Equal(t, []string{
+		"foo", "parse", "http", "request", "r", "http", "request",
+		"base64", "value", "string", "err", "error", "nil",
+	}, ids)
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected []string
+	}{
+		{"foo", []string{"foo"}},
+		{"fooBar", []string{"foo", "bar"}},
+		{"FooBar", []string{"foo", "bar"}},
+		{"foo_bar", []string{"foo", "bar"}},
+		{"__init__", []string{"init"}},
+		{"MAX_VALUE", []string{"max", "value"}},
+		{"parseHTTPRequest", []string{"parse", "http", "request"}},
+		{"HTTPServer", []string{"http", "server"}},
+		{"utf8String", []string{"utf8", "string"}},
+		{"$scope", []string{"scope"}},
+		{"_", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, SplitIdentifier(tt.name))
+		})
+	}
+}
+
+func TestNGrams(t *testing.T) {
+	require := require.New(t)
+	texts := []string{"a", "b", "c"}
+	require.Equal(texts, NGrams(texts, 1))
+	require.Equal([]string{"a b", "b c"}, NGrams(texts, 2))
+	require.Equal([]string{"a b c"}, NGrams(texts, 3))
+	require.Nil(NGrams(texts, 4))
+}
@@ -0,0 +1,351 @@
+package gitbase
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/src-d/enry/v2"
+	"github.com/src-d/gitbase/internal/secrets"
+	"github.com/src-d/go-mysql-server/sql"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
+)
+
+type leakedSecretsTable struct {
+	checksumable
+	partitioned
+	filters []sql.Expression
+}
+
+// LeakedSecretsSchema is the schema for the leaked secrets table.
+var LeakedSecretsSchema = sql.Schema{
+	{Name: "repository_id", Type: sql.Text, Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "commit_hash", Type: sql.VarChar(40), Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "commit_author_when", Type: sql.Timestamp, Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "file_path", Type: sql.Text, Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "blob_hash", Type: sql.VarChar(40), Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "rule_id", Type: sql.Text, Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "line", Type: sql.Int64, Nullable: false, Source: LeakedSecretsTableName},
+	{Name: "match", Type: sql.Text, Nullable: false, Source: LeakedSecretsTableName},
+}
+
+func newLeakedSecretsTable(pool *RepositoryPool) *leakedSecretsTable {
+	return &leakedSecretsTable{checksumable: checksumable{pool}}
+}
+
+var _ Table = (*leakedSecretsTable)(nil)
+
+func (leakedSecretsTable) isGitbaseTable() {}
+
+func (leakedSecretsTable) Name() string {
+	return LeakedSecretsTableName
+}
+
+func (leakedSecretsTable) Schema() sql.Schema {
+	return LeakedSecretsSchema
+}
+
+func (t leakedSecretsTable) String() string {
+	return printTable(
+		LeakedSecretsTableName,
+		LeakedSecretsSchema,
+		nil,
+		t.filters,
+		nil,
+	)
+}
+
+func (t *leakedSecretsTable) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *leakedSecretsTable) Filters() []sql.Expression { return t.filters }
+
+func (t *leakedSecretsTable) PartitionRows(
+	ctx *sql.Context,
+	p sql.Partition,
+) (sql.RowIter, error) {
+	repo, err := getPartitionRepo(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ctx := ctx.Span("gitbase.LeakedSecretsTable")
+	iter, err := rowIterWithSelectors(
+		ctx, LeakedSecretsSchema, LeakedSecretsTableName,
+		t.filters,
+		t.handledColumns(),
+		func(selectors selectors) (sql.RowIter, error) {
+			var repos []string
+			repos, err = selectors.textValues("repository_id")
+			if err != nil {
+				return nil, err
+			}
+
+			if len(repos) > 0 && !stringContains(repos, repo.ID()) {
+				return noRows, nil
+			}
+
+			scanner, err := secrets.DefaultScanner()
+			if err != nil {
+				return nil, err
+			}
+
+			return &leakedSecretsRowIter{
+				repo:          repo,
+				scanner:       scanner,
+				skipGitErrors: shouldSkipErrors(ctx),
+			}, nil
+		},
+	)
+
+	if err != nil {
+		span.Finish()
+		return nil, errorWithRepo(repo, err)
+	}
+
+	return sql.NewSpanIter(span, newRepoRowIter(repo, iter)), nil
+}
+
+func (leakedSecretsTable) HandledFilters(filters []sql.Expression) []sql.Expression {
+	return handledFilters(LeakedSecretsTableName, LeakedSecretsSchema, filters)
+}
+
+func (leakedSecretsTable) handledColumns() []string { return []string{"repository_id"} }
+
+// leakedSecretsRowIter scans the blobs of a repository in the order they
+// were added, from the oldest commit to the newest, so the secrets found
+// in each blob are reported along with the first commit containing it.
+type leakedSecretsRowIter struct {
+	repo          *Repository
+	scanner       *secrets.Scanner
+	skipGitErrors bool
+
+	iter    *commitIter
+	commits []commitDate
+	seen    map[plumbing.Hash]struct{}
+	rows    []sql.Row
+}
+
+// commitDate is the hash of a commit and its author date, which is all
+// that is kept of every commit until it's scanned.
+type commitDate struct {
+	hash plumbing.Hash
+	when time.Time
+}
+
+func (i *leakedSecretsRowIter) init() error {
+	var err error
+	i.iter, err = newCommitIter(i.repo, i.skipGitErrors)
+	if err != nil {
+		return err
+	}
+
+	for {
+		c, err := i.iter.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		i.commits = append(i.commits, commitDate{c.Hash, c.Author.When})
+	}
+
+	sort.SliceStable(i.commits, func(a, b int) bool {
+		return i.commits[a].when.Before(i.commits[b].when)
+	})
+
+	i.seen = make(map[plumbing.Hash]struct{})
+	return nil
+}
+
+func (i *leakedSecretsRowIter) Next() (sql.Row, error) {
+	if i.seen == nil {
+		if err := i.init(); err != nil {
+			if i.skipGitErrors {
+				return nil, io.EOF
+			}
+
+			return nil, err
+		}
+	}
+
+	for len(i.rows) == 0 {
+		if len(i.commits) == 0 {
+			return nil, io.EOF
+		}
+
+		hash := i.commits[0].hash
+		i.commits = i.commits[1:]
+
+		commit, err := i.repo.CommitObject(hash)
+		if err != nil {
+			if i.skipGitErrors {
+				continue
+			}
+
+			return nil, err
+		}
+
+		rows, err := i.commitSecrets(commit)
+		if err != nil {
+			if i.skipGitErrors {
+				continue
+			}
+
+			return nil, err
+		}
+
+		i.rows = rows
+	}
+
+	row := i.rows[0]
+	i.rows = i.rows[1:]
+	return row, nil
+}
+
+// commitSecrets returns the rows of the secrets in the files added or
+// modified by the given commit whose blobs were not seen before.
+func (i *leakedSecretsRowIter) commitSecrets(c *object.Commit) ([]sql.Row, error) {
+	files, err := addedFiles(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sql.Row
+	for _, f := range files {
+		if _, ok := i.seen[f.Hash]; ok || !f.Mode.IsFile() || enry.IsVendor(f.Name) {
+			continue
+		}
+		i.seen[f.Hash] = struct{}{}
+
+		findings, err := i.blobSecrets(f.Name, f.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range findings {
+			rows = append(rows, sql.NewRow(
+				i.repo.ID(),
+				c.Hash.String(),
+				c.Author.When,
+				f.Name,
+				f.Hash.String(),
+				s.RuleID,
+				int64(s.Line),
+				s.Match,
+			))
+		}
+	}
+
+	return rows, nil
+}
+
+func (i *leakedSecretsRowIter) blobSecrets(
+	path string,
+	hash plumbing.Hash,
+) ([]secrets.Finding, error) {
+	blob, err := i.repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if blob.Size > int64(blobsMaxSize) {
+		return nil, nil
+	}
+
+	bin, err := isBinary(blob)
+	if err != nil || bin {
+		return nil, err
+	}
+
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.scanner.Scan(path, content)
+}
+
+func (i *leakedSecretsRowIter) Close() error {
+	if i.iter != nil {
+		i.iter.Close()
+	}
+
+	if i.repo != nil {
+		i.repo.Close()
+	}
+
+	return nil
+}
+
+// addedFiles returns the entries of the files added or modified by a
+// commit with respect to its first parent, or all of them if it has no
+// parents.
+func addedFiles(c *object.Commit) ([]object.TreeEntry, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []object.TreeEntry
+	if c.NumParents() == 0 {
+		err = tree.Files().ForEach(func(f *object.File) error {
+			result = append(result, object.TreeEntry{
+				Name: f.Name,
+				Mode: f.Mode,
+				Hash: f.Hash,
+			})
+			return nil
+		})
+		return result, err
+	}
+
+	parent, err := c.Parent(0)
+	if err != nil {
+		return nil, err
+	}
+
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ch := range changes {
+		action, err := ch.Action()
+		if err != nil {
+			return nil, err
+		}
+
+		if action == merkletrie.Delete {
+			continue
+		}
+
+		result = append(result, object.TreeEntry{
+			Name: ch.To.Name,
+			Mode: ch.To.TreeEntry.Mode,
+			Hash: ch.To.TreeEntry.Hash,
+		})
+	}
+
+	return result, nil
+}
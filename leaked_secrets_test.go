@@ -0,0 +1,61 @@
+package gitbase
+
+import (
+	"testing"
+
+	"github.com/src-d/gitbase/internal/secrets"
+	"github.com/src-d/go-mysql-server/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeakedSecretsRowIter(t *testing.T) {
+	require := require.New(t)
+	ctx, path, cleanup := setup(t)
+	defer cleanup()
+
+	scanner, err := secrets.NewScanner([]secrets.Rule{
+		{ID: "go-package", Regex: `^package (\w+)$`},
+	})
+	require.NoError(err)
+
+	session, err := getSession(ctx)
+	require.NoError(err)
+
+	repo, err := session.Pool.GetRepo(path)
+	require.NoError(err)
+
+	rows, err := sql.RowIterToRows(&leakedSecretsRowIter{
+		repo:    repo,
+		scanner: scanner,
+	})
+	require.NoError(err)
+
+	for i, row := range rows {
+		// remove repository id and commit time
+		rows[i] = append(sql.Row{row[1]}, row[3:]...)
+	}
+
+	// vendor/foo.go is ignored because it's vendored
+	expected := []sql.Row{
+		{
+			"918c48b83bd081e863dbe1b80f8998f058cd8294",
+			"go/example.go",
+			"880cd14280f4b9b6ed3986d6671f907d7cc2a198",
+			"go-package",
+			int64(1),
+			"package harv******",
+		},
+	}
+	require.Equal(expected, rows)
+}
+
+func TestLeakedSecretsTable(t *testing.T) {
+	require := require.New(t)
+	ctx, _, cleanup := setup(t)
+	defer cleanup()
+
+	table := getTable(t, LeakedSecretsTableName, ctx)
+	rows, err := tableToRows(ctx, table)
+	require.NoError(err)
+	require.Len(rows, 0)
+}
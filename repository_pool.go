@@ -3,6 +3,7 @@ package gitbase
 import (
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/src-d/go-borges"
 	billy "gopkg.in/src-d/go-billy.v4"
@@ -73,6 +74,9 @@ func (r *Repository) Close() error {
 type RepositoryPool struct {
 	cache   cache.Object
 	library borges.Library
+
+	labelsMu sync.RWMutex
+	labels   map[string]map[string]string
 }
 
 // NewRepositoryPool holds a repository library and a shared object cache.
@@ -106,6 +110,33 @@ func (p *RepositoryPool) GetRepo(id string) (*Repository, error) {
 	return r, nil
 }
 
+// SetLabels sets the labels of the repository with the given id, replacing
+// the previous ones.
+func (p *RepositoryPool) SetLabels(id string, labels map[string]string) {
+	p.labelsMu.Lock()
+	defer p.labelsMu.Unlock()
+
+	if p.labels == nil {
+		p.labels = make(map[string]map[string]string)
+	}
+
+	if len(labels) == 0 {
+		delete(p.labels, id)
+		return
+	}
+
+	p.labels[id] = labels
+}
+
+// Labels returns the labels of the repository with the given id. The
+// returned map must not be modified.
+func (p *RepositoryPool) Labels(id string) map[string]string {
+	p.labelsMu.RLock()
+	defer p.labelsMu.RUnlock()
+
+	return p.labels[id]
+}
+
 // RepoIter creates a new Repository iterator
 func (p *RepositoryPool) RepoIter() (*RepositoryIter, error) {
 	it, err := p.library.Repositories(borges.ReadOnlyMode)
@@ -57,6 +57,24 @@ func TestRepositoryPoolBasic(t *testing.T) {
 	require.Equal(io.EOF, err)
 }
 
+func TestRepositoryPoolLabels(t *testing.T) {
+	require := require.New(t)
+
+	lib, err := newMultiLibrary()
+	require.NoError(err)
+
+	pool := NewRepositoryPool(cache.NewObjectLRUDefault(), lib)
+	require.Nil(pool.Labels("foo"))
+
+	pool.SetLabels("foo", map[string]string{"team": "core"})
+	pool.SetLabels("bar", map[string]string{"team": "infra"})
+	require.Equal(map[string]string{"team": "core"}, pool.Labels("foo"))
+	require.Equal(map[string]string{"team": "infra"}, pool.Labels("bar"))
+
+	pool.SetLabels("foo", nil)
+	require.Nil(pool.Labels("foo"))
+}
+
 func TestRepositoryPoolGit(t *testing.T) {
 	require := require.New(t)
 